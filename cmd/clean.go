@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func newCleanCmd() *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove ralph's state, notes, and stale lock from .ralph/",
+		Long: `Remove ralph's bookkeeping from .ralph/: state.json, notes.md,
+notes-archive.md, specs backups, and a stale lock. PROMPT.md,
+CONVENTIONS.md, and the specs file are never touched.
+
+Refuses to run while another run holds the lock.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := ralph.Clean(all)
+			for _, path := range removed {
+				cmd.Printf("Removed %s\n", path)
+			}
+			if err != nil {
+				return err
+			}
+			if len(removed) == 0 {
+				cmd.Println("Nothing to clean")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "Also remove .ralph/config.json")
+	return cmd
+}
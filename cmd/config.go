@@ -10,9 +10,21 @@ import (
 
 func newConfigCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "config",
+		Use:   "config [set KEY VALUE | unset KEY | edit | schema | reset]",
 		Short: "View or modify configuration",
-		Args:  cobra.ArbitraryArgs,
+		Long: `View or modify .ralph/config.json.
+
+With no arguments, prints the current configuration.
+
+  config set KEY VALUE  Set a configuration value
+  config unset KEY      Restore a single key to its default
+  config edit           Edit the config in $EDITOR, validating before saving
+  config schema         Print a JSON Schema for .ralph/config.json
+  config reset          Reset configuration to defaults
+
+RALPH_<KEY> environment variables (e.g. RALPH_MAX_ITERATIONS) override the
+file for a single run without changing it; flags override both.`,
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				out, err := ralph.ConfigView()
@@ -33,6 +45,24 @@ func newConfigCmd() *cobra.Command {
 				}
 				cmd.Printf("Set %s = %s\n", args[1], args[2])
 				return nil
+			case "unset":
+				if len(args) < 2 {
+					return fmt.Errorf("usage: config unset KEY")
+				}
+				if err := ralph.ConfigUnset(args[1]); err != nil {
+					return err
+				}
+				cmd.Printf("Unset %s (restored default)\n", args[1])
+				return nil
+			case "edit":
+				return ralph.ConfigEdit(cmd.OutOrStdout())
+			case "schema":
+				out, err := ralph.ConfigSchema()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), out)
+				return nil
 			case "reset":
 				if err := ralph.ConfigReset(); err != nil {
 					return err
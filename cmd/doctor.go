@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "doctor",
+		Short:        "Check opencode, config, context files, and the run lock",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ralph.Doctor(cmd.OutOrStdout())
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func newHistoryCmd() *cobra.Command {
+	var (
+		limit  int
+		asJSON bool
+	)
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List past iterations recorded in .ralph/notes.md",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := ralph.History(limit, asJSON)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), out)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 0, "Show only the last N iterations (0 = all)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print iterations as JSON")
+	return cmd
+}
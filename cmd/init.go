@@ -7,12 +7,15 @@ import (
 )
 
 func newInitCmd() *cobra.Command {
-	return &cobra.Command{
+	var opts ralph.InitOptions
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Create PROMPT.md, CONVENTIONS.md, and stub SPECS.md",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return ralph.Init()
+			return ralph.InitWithOptions(opts)
 		},
 	}
+	cmd.Flags().StringVar(&opts.SpecsFrom, "specs-from", "", "Seed the specs file from a plaintext/markdown URL instead of the stub")
+	return cmd
 }
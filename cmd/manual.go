@@ -14,9 +14,16 @@ func newManualCmd(cfg ralph.Config) *cobra.Command {
 		Args:         cobra.NoArgs,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return ralph.RunWithOptions(*opts, cfg.MaxIterations, cfg.MaxPerHour, cfg.MaxPerDay)
+			if err := applyConfigDefaults(cmd, opts); err != nil {
+				return err
+			}
+			// One iteration, whatever max_iterations the config sets.
+			opts.MaxIterations = 1
+			return startRun(cmd, *opts)
 		},
 	}
 	bindRunFlags(cmd, cfg, opts)
+	cmd.Flags().BoolVar(&opts.EditSpecs, "edit", false, "Open the specs file in $EDITOR before running the iteration")
+	cmd.Flags().BoolVar(&opts.EditIfTTY, "edit-if-tty", false, "With --edit, run without editing instead of failing when not in a terminal")
 	return cmd
 }
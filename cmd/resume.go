@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func newResumeCmd(cfg ralph.Config) *cobra.Command {
+	opts := &ralph.RunOptions{ResumeLast: true}
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Continue the previous run's session without resetting counters",
+		Long: `Resume runs like run, but continues the opencode session of the previous
+run (the recorded session ID, or opencode --continue when none was captured)
+and keeps counting iterations from .ralph/state.json. It fails when no run
+has been recorded yet.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithFlags(cmd, opts)
+		},
+	}
+	bindRunFlags(cmd, cfg, opts)
+	return cmd
+}
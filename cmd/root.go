@@ -1,19 +1,58 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/spf13/cobra"
 
 	"opencode-ralph/internal/ralph"
 )
 
-// Execute runs the root command.
+// Execute runs the root command. SIGINT and SIGTERM cancel its context, so
+// a run stops opencode, records its outcome, and releases the lock; a second
+// signal kills the process as usual.
 func Execute() error {
-	return newRootCmd().Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	return newRootCmd().ExecuteContext(ctx)
+}
+
+// Exit codes returned by main.
+const (
+	exitFailure          = 1
+	exitDryRun           = 3
+	exitOpencodeNotFound = 127
+	exitInterrupted      = 130
+)
+
+// ExitCode maps an error from Execute to the process exit code. A missing
+// opencode binary gets its own code, matching the shell's "command not found",
+// as does a --dry-run under --strict. A run stopped by a signal exits like a
+// shell command interrupted with Ctrl-C.
+func ExitCode(err error) int {
+	switch {
+	case errors.Is(err, ralph.ErrOpencodeNotFound):
+		return exitOpencodeNotFound
+	case errors.Is(err, ralph.ErrDryRun):
+		return exitDryRun
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	}
+	return exitFailure
 }
 
 func newRootCmd() *cobra.Command {
-	cfg := ralph.LoadConfig()
+	// Only flag defaults come from here; a bad RALPH_* value is reported when
+	// the command reloads the config.
+	cfg, _ := ralph.LoadConfig()
 	opts := &ralph.RunOptions{}
+	var cwd, projectDir string
 
 	rootCmd := &cobra.Command{
 		Use:           "opencode-ralph",
@@ -21,12 +60,24 @@ func newRootCmd() *cobra.Command {
 		Args:          cobra.NoArgs,
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if projectDir != "" {
+				if cwd != "" {
+					return fmt.Errorf("--cwd and --project-dir are the same option; give only one")
+				}
+				return changeDir(projectDir, "--project-dir")
+			}
+			return changeDir(cwd, "--cwd")
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Default behavior: same as `opencode-ralph run ...`
-			return ralph.RunWithOptions(*opts, cfg.MaxIterations, cfg.MaxPerHour, cfg.MaxPerDay)
+			return runWithFlags(cmd, opts)
 		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&cwd, "cwd", "", "Run as if started in DIR")
+	rootCmd.PersistentFlags().StringVarP(&projectDir, "project-dir", "C", "", "Same as --cwd")
+
 	bindRunFlags(rootCmd, cfg, opts)
 
 	legacyHelp := `opencode-ralph - Iterative AI development orchestrator
@@ -38,14 +89,27 @@ Commands:
   init      Create PROMPT.md, CONVENTIONS.md, and stub SPECS.md
   manual    Run exactly one iteration
   run       Run multiple iterations until complete (default)
+  resume    Continue the previous run's session, keeping iteration counts
   config    View or modify configuration
+  status    Show iteration history and rate-limit headroom
+  stats     Show all-time run statistics (--json for machine output)
+  history   List past iterations from notes (--limit N, --json)
+  sessions  List opencode sessions (IDs, titles, last update) for --session
+  clean     Remove .ralph state, notes, and stale lock (--all also removes config)
+  selftest  Verify the install using a built-in echo runner (no model needed)
+  doctor    Check opencode, config, context files, and the run lock
   help      Show this help message
 
+Global Options:
+  --cwd DIR             Run as if started in DIR (config, state, notes, specs, and opencode)
+  -C, --project-dir DIR Same as --cwd
+
 Run Options:
   --max-iterations N    Maximum iterations (default: from config or 50)
   --max-per-hour N      Maximum iterations per hour (default: from config or 0)
   --max-per-day N       Maximum iterations per day (default: from config or 0)
-  --prompt FILE         Override prompt file path
+  --max-per-minute N    Maximum iterations per minute (default: from config or 0)
+  --prompt FILE         Override prompt file path (- reads the prompt from stdin once)
   --conventions FILE    Override conventions file path
   --specs FILE          Override specs file path
   --agent AGENT         Agent to use (passed to opencode run --agent)
@@ -61,33 +125,131 @@ Run Options:
   --model MODEL         Model to use (e.g., ollama/qwen3-coder:30b)
   --verbose             Stream opencode output in real-time
   --dry-run             Show constructed prompt without executing
+  --dry-run-all         Show the prompt for every iteration up to --max-iterations
   --delay SECONDS       Delay between iterations (default: 2s)
+  --delay-jitter SECONDS
+                        Add a random 0..SECONDS to each delay so parallel runs spread out
+  --summary-log FILE    Append a one-line JSON record per run to FILE
+  --prompt-arg-style S  Pass the prompt to opencode as positional|flag (default: from config or positional)
+  --max-notes-per-iteration N
+                        Truncate each iteration's notes to N characters (0 = unlimited)
+  --retry-on-truncation Retry an iteration once when opencode output looks truncated
+  --prefer-config-model Let the config file's model beat RALPH_MODEL unless --model is given
+  --output-dir DIR      Save each iteration's prompt and output as <run-id>/iteration-<n>.prompt/.log
+  --color MODE          Colorize status output: always|auto|never (default: auto)
+  --output-filter CMD   Pipe opencode output through CMD before extracting notes/status
+  --max-runtime DUR     Stop the run after this much wall time, including delays (e.g. 2h; 0 = unlimited)
+  --iterations-file F   JSON or YAML list of per-iteration model/agent/variant/specs overlays
+  --model-escalation S  Model schedule, e.g. ollama/small:3,anthropic/big (3 iterations on small, then big)
+  --allow-unsafe-cwd    Allow running in /, $HOME, or a blocked_dirs directory
+  --nice N              Run opencode with lower CPU priority (0-19)
+  --ionice CLASS        Run opencode with lower IO priority: best-effort|idle
+  --escalation-hints    Add increasingly urgent guidance as the iteration budget runs out
+  --require-notes       Remind the agent in the next prompt when an iteration produces no notes,
+                        and count it toward --max-consecutive-failures
+  --strict-notes        Fail the run when an iteration produces no notes
+  --delay-ratio R       Sleep R times the last opencode call's duration between iterations (overrides --delay)
+  --delay-min DUR       Lower bound for --delay-ratio pauses (e.g. 5s)
+  --delay-max DUR       Upper bound for --delay-ratio pauses (0 = unbounded)
+  --include-last-output Include the previous iteration's output in a <previous_output> prompt section
+  --last-output-chars N Keep only the last N characters of --include-last-output (0 = unlimited)
+  --resume              Continue the last session if it ran within resume_window, otherwise start fresh
+  --pretty-json-logs    Indent --format json output in --output-dir logs
+  --max-prompt-chars N  Shrink notes so the prompt stays within N characters (0 = unlimited)
+  --summarize-notes     With --max-prompt-chars, summarize notes through opencode instead of dropping older ones
+  --strict-format       Fail the iteration when --format json output is not valid JSON
+  --prompt-via-file     Pass the prompt to opencode as an attached temporary file (for huge prompts)
+  --auto-extend N       On reaching max iterations without completion, extend the run by N iterations
+  --auto-extend-cap N   Absolute iteration limit for --auto-extend (required with it)
+  --on-max-iterations-cmd CMD
+                        Shell command to run when the run stops at max iterations
+  --strict              With --dry-run, exit with code 3 instead of 0
+  --meta KEY=VALUE      Metadata recorded in the summary, summary log, and notes header (repeatable)
+  --dedupe-notes        Record "(same as iteration N)" instead of repeating an identical note
+  --prompt-order LIST   Order of prompt sections, e.g. prompt,specs,conventions,notes
+  --backup-specs        Copy the specs file to .ralph/specs-backup/iteration-<n>.md before each opencode call
+  --backup-specs-keep K Number of specs backups to keep (default: 20; 0 = all)
+  --quiet-summary       Print only the final status line (e.g. complete) to stdout
+  --token-budget N      Stop once the run has used more than N tokens (requires --format json)
+  --warn-on-slow-iteration DURATION
+                        Warn when an opencode call takes longer than this, e.g. 10m
+  --timeout SECONDS     Kill an opencode call, and its children, after SECONDS (0 = no limit)
+  --phases LIST         Run to completion against each specs file in turn, e.g. PHASE1.md,PHASE2.md
+  --commit              Commit the working tree with git after each successful iteration
+  --retries N           Retry a failed opencode call up to N times within the same iteration
+  --retry-backoff SECONDS
+                        Wait before the first retry, doubled for each further retry (default: 5)
+  --max-consecutive-failures N
+                        Stop with status failed after N failed opencode calls in a row
+  --drain-notes-to FILE
+                        Copy .ralph/notes.md to FILE when the run finishes
+  --move                With --drain-notes-to, move the notes instead of copying
+  --merge-streams MODE  ordered (stdout and stderr kept in order on stdout) or separate (default)
+  --plan                Print what each iteration would target (model, agent, specs), then exit
+  --log-format FORMAT   text (default) or json: one JSON object per iteration and a final summary
+  --wait-on-limit       Wait for rate-limit capacity instead of stopping as rate_limited
+  --notes-tail N        Only include the last N iterations of notes in the prompt
+  --check-command "CMD" Run CMD before each iteration and add its output and exit code to the prompt
+  --check-output-bytes N
+                        Keep only the last N bytes of --check-command output (default: 10000)
+  --on-complete CMD     Run CMD when the run completes (RALPH_STATUS is set)
+  --on-failure CMD      Run CMD when the run fails or stops at max iterations
+  --webhook URL         POST a JSON summary to URL when the run finishes
+  --session-file FILE   Reuse the session ID saved in FILE and update it after each iteration (implies --format json)
+  --opencode-bin PATH   Run PATH instead of opencode from PATH (e.g. a wrapper script)
+  --track-specs         Print each iteration's specs diff and checklist progress (e.g. 7/12 tasks complete)
+  --stop-when-specs-complete
+                        Finish as complete once every - [ ] task in the specs is checked
+  --prompt-out FILE     Write the constructed prompt to FILE and exit (no opencode needed)
+  --no-lock             Skip the .ralph/lock file (only for isolated workspaces such as CI)
 
 
 Config Commands:
   config                Show current configuration
   config set KEY VALUE  Set a configuration value
+  config unset KEY      Restore a single key to its default
+  config edit           Edit the config in $EDITOR, validating before saving
+  config schema         Print a JSON Schema for .ralph/config.json
   config reset          Reset configuration to defaults
 
 Config Keys:
   prompt_file, conventions_file, specs_file,
-  max_iterations, max_per_hour, max_per_day, model
+  max_iterations, max_per_hour, max_per_day, max_per_minute, model,
+  prompt_arg_style, opencode_subcommand, blocked_dirs (comma-separated),
+  escalation_hints (JSON array), resume_window (duration), format,
+  prompt_template (text/template file), max_notes_bytes,
+  notes_rotation (rotate|truncate), on_complete, on_failure,
+  webhook_url, min_opencode_version, opencode_bin, notes_tag,
+  status_tag
+
+  Each key can also be set with a RALPH_<KEY> environment variable, e.g.
+  RALPH_MAX_ITERATIONS=10 or RALPH_MODEL=ollama/qwen3-coder:30b.
+  Precedence: defaults < .ralph/config.json < environment < flags
 
 Examples:
   opencode-ralph init
+  opencode-ralph init --specs-from https://example.com/raw/issue.md
   opencode-ralph manual --verbose
   opencode-ralph run --max-iterations 10
   opencode-ralph config set specs_file TASKS.md
   opencode-ralph --specs TASKS.md --max-per-hour 5
 `
 
-	rootCmd.SetHelpTemplate(legacyHelp)
-
-	// Override cobra's default help/usage rendering to keep legacy output.
+	// Keep the curated legacy help for the root command only; subcommands
+	// fall through to cobra's rendering so they list their own flags.
+	defaultHelp := rootCmd.HelpFunc()
+	defaultUsage := rootCmd.UsageFunc()
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if cmd != rootCmd {
+			defaultHelp(cmd, args)
+			return
+		}
 		cmd.Println(legacyHelp)
 	})
 	rootCmd.SetUsageFunc(func(cmd *cobra.Command) error {
+		if cmd != rootCmd {
+			return defaultUsage(cmd)
+		}
 		cmd.Println(legacyHelp)
 		return nil
 	})
@@ -95,16 +257,87 @@ Examples:
 	rootCmd.AddCommand(newInitCmd())
 	rootCmd.AddCommand(newManualCmd(cfg))
 	rootCmd.AddCommand(newRunCmd(cfg))
+	rootCmd.AddCommand(newResumeCmd(cfg))
 	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newSelftestCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newHistoryCmd())
+	rootCmd.AddCommand(newCleanCmd())
+	rootCmd.AddCommand(newSessionsCmd())
 
 	return rootCmd
 }
 
+// runWithFlags fills the options not given as flags from the config, then
+// starts a run.
+func runWithFlags(cmd *cobra.Command, opts *ralph.RunOptions) error {
+	if err := applyConfigDefaults(cmd, opts); err != nil {
+		return err
+	}
+	return startRun(cmd, *opts)
+}
+
+// applyConfigDefaults fills the limits not given on the command line from
+// the config. It reloads the config, since --cwd may have moved to another
+// project after the flag defaults were computed.
+func applyConfigDefaults(cmd *cobra.Command, opts *ralph.RunOptions) error {
+	cfg, err := ralph.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("max-iterations") {
+		opts.MaxIterations = cfg.MaxIterations
+	}
+	if !cmd.Flags().Changed("max-per-hour") {
+		opts.MaxPerHour = cfg.MaxPerHour
+	}
+	if !cmd.Flags().Changed("max-per-day") {
+		opts.MaxPerDay = cfg.MaxPerDay
+	}
+	if !cmd.Flags().Changed("max-per-minute") {
+		opts.MaxPerMinute = cfg.MaxPerMinute
+	}
+	opts.ModelInherited = !cmd.Flags().Changed("model")
+	opts.FormatInherited = !cmd.Flags().Changed("format")
+	return nil
+}
+
+// startRun runs the loop, reporting Ctrl-C as an interruption.
+func startRun(cmd *cobra.Command, opts ralph.RunOptions) error {
+	_, err := ralph.Run(cmd.Context(), opts)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
+}
+
+// changeDir switches the process to dir for --cwd or --project-dir, named by
+// flag in errors; empty means stay put.
+func changeDir(dir, flag string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", flag, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid %s: %s is not a directory", flag, dir)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("changing to %s: %w", dir, err)
+	}
+	return nil
+}
+
 func bindRunFlags(cmd *cobra.Command, cfg ralph.Config, opts *ralph.RunOptions) {
 	cmd.Flags().IntVar(&opts.MaxIterations, "max-iterations", cfg.MaxIterations, "Maximum iterations")
 	cmd.Flags().IntVar(&opts.MaxPerHour, "max-per-hour", cfg.MaxPerHour, "Maximum iterations per hour (0 = unlimited)")
 	cmd.Flags().IntVar(&opts.MaxPerDay, "max-per-day", cfg.MaxPerDay, "Maximum iterations per day (0 = unlimited)")
-	cmd.Flags().StringVar(&opts.Prompt, "prompt", "", "Override prompt file path")
+	cmd.Flags().IntVar(&opts.MaxPerMinute, "max-per-minute", cfg.MaxPerMinute, "Maximum iterations per minute (0 = unlimited)")
+	cmd.Flags().StringVar(&opts.Prompt, "prompt", "", "Override prompt file path (- reads the prompt from stdin once)")
 	cmd.Flags().StringVar(&opts.Conventions, "conventions", "", "Override conventions file path")
 	cmd.Flags().StringVar(&opts.Specs, "specs", "", "Override specs file path")
 	cmd.Flags().StringVar(&opts.Agent, "agent", "", "Agent to use (passed to opencode run --agent)")
@@ -117,8 +350,76 @@ func bindRunFlags(cmd *cobra.Command, cfg ralph.Config, opts *ralph.RunOptions)
 	cmd.Flags().StringVar(&opts.Attach, "attach", "", "Remote attach target (passed to opencode run --attach)")
 	cmd.Flags().IntVar(&opts.Port, "port", 0, "Remote attach port (passed to opencode run --port)")
 	cmd.Flags().BoolVar(&opts.Quiet, "quiet", false, "Hide opencode-ralph banner/status output")
-	cmd.Flags().StringVar(&opts.Model, "model", "", "Model to use (e.g., ollama/qwen3-coder:30b)")
+	// RALPH_MODEL is inherited as the --model default so that
+	// --prefer-config-model can tell it apart from an explicit --model.
+	cmd.Flags().StringVar(&opts.Model, "model", os.Getenv("RALPH_MODEL"), "Model to use (e.g., ollama/qwen3-coder:30b)")
 	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "Stream opencode output in real-time")
 	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show constructed prompt without executing")
+	cmd.Flags().BoolVar(&opts.DryRunAll, "dry-run-all", false, "Show the constructed prompt for every iteration up to --max-iterations without executing")
 	cmd.Flags().Float64Var(&opts.Delay, "delay", 2.0, "Delay between iterations in seconds")
+	cmd.Flags().Float64Var(&opts.DelayJitter, "delay-jitter", 0, "Add a uniform random 0..SECONDS to each delay between iterations")
+	cmd.Flags().StringVar(&opts.SummaryLog, "summary-log", "", "Append a one-line JSON record per run to FILE")
+	cmd.Flags().StringVar(&opts.PromptArgStyle, "prompt-arg-style", "", "Pass the prompt to opencode as positional|flag (default: from config)")
+	cmd.Flags().IntVar(&opts.MaxNotesChars, "max-notes-per-iteration", 0, "Truncate each iteration's notes to N characters (0 = unlimited)")
+	cmd.Flags().BoolVar(&opts.RetryOnTruncation, "retry-on-truncation", false, "Retry an iteration once when opencode output looks truncated")
+	cmd.Flags().BoolVar(&opts.PreferConfigModel, "prefer-config-model", false, "Let the config file's model beat RALPH_MODEL unless --model is given explicitly")
+	cmd.Flags().StringVar(&opts.OutputDir, "output-dir", "", "Save each iteration's prompt and output as <run-id>/iteration-<n>.prompt/.log in DIR")
+	cmd.Flags().StringVar(&opts.Color, "color", "auto", "Colorize status output: always|auto|never")
+	cmd.Flags().StringVar(&opts.OutputFilter, "output-filter", "", "Pipe opencode output through CMD before extracting notes/status")
+	cmd.Flags().DurationVar(&opts.MaxRuntime, "max-runtime", 0, "Stop the run after this much wall time, including delays (0 = unlimited)")
+	cmd.Flags().StringVar(&opts.IterationsFile, "iterations-file", "", "JSON or YAML list of per-iteration model/agent/variant/specs overlays")
+	cmd.Flags().StringVar(&opts.ModelEscalation, "model-escalation", "", "Switch models as the run goes on, e.g. ollama/small:3,anthropic/big (small for 3 iterations, then big)")
+	cmd.Flags().BoolVar(&opts.AllowUnsafeCWD, "allow-unsafe-cwd", false, "Allow running in /, $HOME, or a blocked_dirs directory")
+	cmd.Flags().IntVar(&opts.Nice, "nice", 0, "Run opencode with lower CPU priority (0-19)")
+	cmd.Flags().StringVar(&opts.IOClass, "ionice", "", "Run opencode with lower IO priority: best-effort|idle")
+	cmd.Flags().BoolVar(&opts.EscalationHints, "escalation-hints", false, "Add increasingly urgent guidance to the prompt as the iteration budget runs out")
+	cmd.Flags().BoolVar(&opts.RequireNotes, "require-notes", false, "Remind the agent to report notes when an iteration produces none")
+	cmd.Flags().BoolVar(&opts.StrictNotes, "strict-notes", false, "Fail the run when an iteration produces no notes")
+	cmd.Flags().Float64Var(&opts.DelayRatio, "delay-ratio", 0, "Delay between iterations as a multiple of the last opencode call's duration (overrides --delay)")
+	cmd.Flags().DurationVar(&opts.DelayMin, "delay-min", 0, "Lower bound for --delay-ratio pauses")
+	cmd.Flags().DurationVar(&opts.DelayMax, "delay-max", 0, "Upper bound for --delay-ratio pauses (0 = unbounded)")
+	cmd.Flags().BoolVar(&opts.IncludeLastOutput, "include-last-output", false, "Include the previous iteration's output in the prompt")
+	cmd.Flags().IntVar(&opts.LastOutputChars, "last-output-chars", 0, "Keep only the last N characters of --include-last-output (0 = unlimited)")
+	cmd.Flags().BoolVar(&opts.Resume, "resume", false, "Continue the last session if it ran within resume_window, otherwise start fresh")
+	cmd.Flags().BoolVar(&opts.PrettyJSONLogs, "pretty-json-logs", false, "Indent --format json output in --output-dir logs")
+	cmd.Flags().IntVar(&opts.MaxPromptChars, "max-prompt-chars", 0, "Shrink notes so the prompt stays within N characters (0 = unlimited)")
+	cmd.Flags().BoolVar(&opts.SummarizeNotes, "summarize-notes", false, "With --max-prompt-chars, summarize notes through opencode instead of dropping older ones")
+	cmd.Flags().BoolVar(&opts.StrictFormat, "strict-format", false, "Fail the iteration when --format json output is not valid JSON")
+	cmd.Flags().BoolVar(&opts.PromptViaFile, "prompt-via-file", false, "Pass the prompt to opencode as an attached temporary file")
+	cmd.Flags().IntVar(&opts.AutoExtend, "auto-extend", 0, "On reaching max iterations without completion, extend the run by N iterations")
+	cmd.Flags().IntVar(&opts.AutoExtendCap, "auto-extend-cap", 0, "Absolute iteration limit for --auto-extend")
+	cmd.Flags().StringVar(&opts.OnMaxIterationsCmd, "on-max-iterations-cmd", "", "Shell command to run when the run stops at max iterations")
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "With --dry-run, exit with code 3 instead of 0")
+	cmd.Flags().StringToStringVar(&opts.Meta, "meta", nil, "Metadata key=value recorded in the summary and notes (repeatable)")
+	cmd.Flags().BoolVar(&opts.DedupeNotes, "dedupe-notes", false, "Record a short marker instead of repeating a note identical to the previous iteration's")
+	cmd.Flags().StringSliceVar(&opts.PromptOrder, "prompt-order", nil, "Order of prompt sections, e.g. prompt,specs,conventions,notes")
+	cmd.Flags().BoolVar(&opts.BackupSpecs, "backup-specs", false, "Copy the specs file to .ralph/specs-backup/iteration-<n>.md before each opencode call")
+	cmd.Flags().IntVar(&opts.BackupSpecsKeep, "backup-specs-keep", 20, "Number of specs backups to keep (0 = all)")
+	cmd.Flags().BoolVar(&opts.QuietSummary, "quiet-summary", false, "Print only the final status line (e.g. complete) to stdout")
+	cmd.Flags().IntVar(&opts.TokenBudget, "token-budget", 0, "Stop once the run has used more than N tokens (requires --format json; 0 = unlimited)")
+	cmd.Flags().DurationVar(&opts.SlowIterationWarning, "warn-on-slow-iteration", 0, "Warn when an opencode call takes longer than this, e.g. 10m (0 = never)")
+	cmd.Flags().IntVar(&opts.Timeout, "timeout", 0, "Kill an opencode call, and its children, after this many seconds (0 = no limit)")
+	cmd.Flags().StringSliceVar(&opts.Phases, "phases", nil, "Run to completion against each specs file in turn, e.g. PHASE1.md,PHASE2.md")
+	cmd.Flags().BoolVar(&opts.Commit, "commit", false, "Commit the working tree with git after each successful iteration")
+	cmd.Flags().IntVar(&opts.Retries, "retries", 0, "Retry a failed opencode call up to N times within the same iteration")
+	cmd.Flags().Float64Var(&opts.RetryBackoff, "retry-backoff", 5, "Seconds to wait before the first retry, doubled for each further retry")
+	cmd.Flags().IntVar(&opts.MaxConsecutiveFailures, "max-consecutive-failures", 0, "Stop with status failed after N failed opencode calls in a row (0 = never)")
+	cmd.Flags().StringVar(&opts.DrainNotesTo, "drain-notes-to", "", "Copy .ralph/notes.md to FILE when the run finishes (not when rate limited)")
+	cmd.Flags().BoolVar(&opts.DrainNotesMove, "move", false, "With --drain-notes-to, move the notes instead of copying them")
+	cmd.Flags().StringVar(&opts.MergeStreams, "merge-streams", "separate", "How streamed opencode stdout/stderr are combined: ordered (one synchronized stream on stdout) or separate")
+	cmd.Flags().BoolVar(&opts.Plan, "plan", false, "Print the model, agent, variant, and specs each iteration would use, then exit")
+	cmd.Flags().StringVar(&opts.LogFormat, "log-format", "text", "Status output format: text (human-readable) or json (one object per iteration plus a summary)")
+	cmd.Flags().BoolVar(&opts.WaitOnLimit, "wait-on-limit", false, "When a rate limit is reached, wait for capacity instead of stopping")
+	cmd.Flags().IntVar(&opts.NotesTail, "notes-tail", 0, "Only include the last N iterations of notes in the prompt (0 = all)")
+	cmd.Flags().StringVar(&opts.CheckCommand, "check-command", "", "Command run (via sh -c) before each iteration; its output and exit code are added to the prompt")
+	cmd.Flags().IntVar(&opts.CheckOutputBytes, "check-output-bytes", 10000, "Keep only the last N bytes of --check-command output (0 = unlimited)")
+	cmd.Flags().StringVar(&opts.OnComplete, "on-complete", "", "Shell command to run when the run completes (default: on_complete from config)")
+	cmd.Flags().StringVar(&opts.OnFailure, "on-failure", "", "Shell command to run when the run fails or stops at max iterations (default: on_failure from config)")
+	cmd.Flags().StringVar(&opts.Webhook, "webhook", "", "URL to POST a JSON summary to when the run finishes (default: webhook_url from config)")
+	cmd.Flags().StringVar(&opts.SessionFile, "session-file", "", "Read the first iteration's session ID from FILE and save the latest one back to it (implies --format json)")
+	cmd.Flags().StringVar(&opts.OpencodeBin, "opencode-bin", "", "opencode binary or wrapper script to run (default: opencode_bin from config, then opencode on PATH)")
+	cmd.Flags().BoolVar(&opts.TrackSpecs, "track-specs", false, "Print how each iteration changed the specs and the checklist progress")
+	cmd.Flags().BoolVar(&opts.StopWhenSpecsComplete, "stop-when-specs-complete", false, "Finish as complete once every checkbox task in the specs is checked")
+	cmd.Flags().StringVar(&opts.PromptOut, "prompt-out", "", "Write the constructed prompt to FILE and exit without running opencode")
+	cmd.Flags().BoolVar(&opts.NoLock, "no-lock", false, "Skip the .ralph/lock file, for isolated workspaces such as CI jobs (concurrent runs can corrupt state)")
 }
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func executeHelp(t *testing.T, args ...string) string {
+	t.Helper()
+
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestRootHelpKeepsLegacyText(t *testing.T) {
+	out := executeHelp(t, "--help")
+	if !strings.Contains(out, "Run Options:") || !strings.Contains(out, "Config Keys:") {
+		t.Fatalf("expected curated root help, got %q", out)
+	}
+}
+
+func TestSubcommandHelpListsOwnFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"run", "--help"}, want: []string{"opencode-ralph run [flags]", "--max-iterations", "--dry-run", "--model"}},
+		{args: []string{"manual", "--help"}, want: []string{"opencode-ralph manual [flags]", "--verbose", "--specs"}},
+		{args: []string{"resume", "--help"}, want: []string{"opencode-ralph resume [flags]", "--max-iterations"}},
+		{args: []string{"help", "run"}, want: []string{"opencode-ralph run [flags]", "--max-iterations"}},
+		{args: []string{"config", "--help"}, want: []string{"config set KEY VALUE", "config unset KEY", "config reset"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out := executeHelp(t, tt.args...)
+			if strings.Contains(out, "Run Options:") {
+				t.Fatalf("subcommand help should not render the root help, got %q", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Fatalf("expected %q in help, got %q", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestCwdFlagCreatesArtifactsInTarget(t *testing.T) {
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Chdir(orig) // restores the working directory after --cwd moves it
+
+	target := t.TempDir()
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs([]string{"--cwd", target, "init"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("init --cwd: %v", err)
+	}
+
+	for _, name := range []string{"PROMPT.md", "CONVENTIONS.md", "SPECS.md", filepath.Join(".ralph", "config.json")} {
+		if _, err := os.Stat(filepath.Join(target, name)); err != nil {
+			t.Fatalf("expected %s in target directory: %v", name, err)
+		}
+	}
+}
+
+func TestCwdFlagRejectsMissingDirectory(t *testing.T) {
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Chdir(orig)
+
+	root := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"--cwd", filepath.Join(t.TempDir(), "missing"), "status"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "invalid --cwd") {
+		t.Fatalf("expected invalid --cwd error, got %v", err)
+	}
+}
+
+func TestProjectDirFlag(t *testing.T) {
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Chdir(orig)
+
+	target := t.TempDir()
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "long form", args: []string{"--project-dir", target, "init"}},
+		{name: "short form", args: []string{"-C", target, "init"}},
+		{name: "missing directory", args: []string{"-C", filepath.Join(target, "missing"), "status"}, wantErr: "invalid --project-dir"},
+		{name: "with --cwd", args: []string{"--cwd", target, "-C", target, "status"}, wantErr: "give only one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(orig)
+			root := newRootCmd()
+			root.SetOut(&bytes.Buffer{})
+			root.SetErr(&bytes.Buffer{})
+			root.SetArgs(tt.args)
+			err := root.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(target, ".ralph", "config.json")); err != nil {
+				t.Fatalf("expected config in project directory: %v", err)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: errors.New("boom"), want: exitFailure},
+		{err: ralph.ErrOpencodeNotFound, want: exitOpencodeNotFound},
+		{err: fmt.Errorf("wrapped: %w", ralph.ErrDryRun), want: exitDryRun},
+		{err: fmt.Errorf("interrupted: %w", context.Canceled), want: exitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Fatalf("ExitCode(%v): got %d want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestEnvOverridesSitBetweenConfigAndFlags(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("RALPH_MAX_ITERATIONS", "7")
+
+	root := newRootCmd()
+	run, _, err := root.Find([]string{"run"})
+	if err != nil {
+		t.Fatalf("find run: %v", err)
+	}
+	if got := run.Flags().Lookup("max-iterations").DefValue; got != "7" {
+		t.Fatalf("--max-iterations default: got %s want 7", got)
+	}
+	if err := run.Flags().Parse([]string{"--max-iterations", "2"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := run.Flags().Lookup("max-iterations").Value.String(); got != "2" {
+		t.Fatalf("explicit flag should beat env: got %s", got)
+	}
+}
+
+func TestInvalidEnvOverrideFailsCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("RALPH_MAX_PER_DAY", "lots")
+
+	root := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"status"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "RALPH_MAX_PER_DAY") {
+		t.Fatalf("expected RALPH_MAX_PER_DAY error, got %v", err)
+	}
+}
+
+func TestConfigSchemaWritesToStdout(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	root := newRootCmd()
+	var stdout, stderr bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"config", "schema"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("config schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &schema); err != nil {
+		t.Fatalf("expected a JSON schema on stdout: %v\n%s", err, stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestStatsJSONWritesToStdout(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	root := newRootCmd()
+	var stdout, stderr bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"stats", "--json"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("stats --json: %v", err)
+	}
+	var stats ralph.RunStats
+	if err := json.Unmarshal(stdout.Bytes(), &stats); err != nil {
+		t.Fatalf("expected stats JSON on stdout: %v\n%s", err, stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestHistoryJSONWritesToStdout(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	root := newRootCmd()
+	var stdout, stderr bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"history", "--json"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("history --json: %v", err)
+	}
+	var entries []ralph.NotesEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("expected history JSON on stdout: %v\n%s", err, stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestSessionsJSONWritesToStdout(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	fake := filepath.Join(dir, "fake-opencode")
+	script := "#!/bin/sh\necho '[{\"id\":\"ses_1\",\"title\":\"t\"}]'\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RALPH_OPENCODE_BIN", fake)
+
+	root := newRootCmd()
+	var stdout, stderr bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"sessions", "--json"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("sessions --json: %v", err)
+	}
+	var sessions []ralph.SessionInfo
+	if err := json.Unmarshal(stdout.Bytes(), &sessions); err != nil || len(sessions) != 1 || sessions[0].ID != "ses_1" {
+		t.Fatalf("expected sessions JSON on stdout, got %v: %q", err, stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestManualRunsOneIterationDespiteConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	calls := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "fake-opencode")
+	script := "#!/bin/sh\necho call >> " + calls + "\necho '<ralph_notes>n</ralph_notes>'\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RALPH_OPENCODE_BIN", fake)
+	t.Setenv("RALPH_MAX_ITERATIONS", "5")
+
+	for _, args := range [][]string{{"init"}, {"manual", "--quiet", "--delay", "0"}} {
+		root := newRootCmd()
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(args)
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	if n := strings.Count(string(data), "call"); n != 1 {
+		t.Fatalf("expected exactly one opencode call, got %d", n)
+	}
+}
+
+func TestPreferConfigModelPrecedence(t *testing.T) {
+	tests := []struct {
+		name        string
+		configModel string
+		args        []string
+		want        string
+	}{
+		{name: "inherited RALPH_MODEL beats config", configModel: "cfg/m", want: "env/m"},
+		{name: "prefer config over RALPH_MODEL", configModel: "cfg/m", args: []string{"--prefer-config-model"}, want: "cfg/m"},
+		{name: "explicit model beats preferred config", configModel: "cfg/m", args: []string{"--prefer-config-model", "--model", "flag/m"}, want: "flag/m"},
+		{name: "prefer config with config model unset", args: []string{"--prefer-config-model"}, want: "env/m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			argsFile := filepath.Join(dir, "args")
+			fake := filepath.Join(dir, "fake-opencode")
+			script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\necho '<ralph_notes>n</ralph_notes>'\n"
+			if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("RALPH_OPENCODE_BIN", fake)
+
+			setup := [][]string{{"init"}}
+			if tt.configModel != "" {
+				setup = append(setup, []string{"config", "set", "model", tt.configModel})
+			}
+			for _, args := range setup {
+				root := newRootCmd()
+				root.SetOut(&bytes.Buffer{})
+				root.SetErr(&bytes.Buffer{})
+				root.SetArgs(args)
+				if err := root.Execute(); err != nil {
+					t.Fatalf("%v: %v", args, err)
+				}
+			}
+
+			// The environment applies from here on, as it would for a wrapper.
+			t.Setenv("RALPH_MODEL", "env/m")
+			root := newRootCmd()
+			root.SetOut(&bytes.Buffer{})
+			root.SetErr(&bytes.Buffer{})
+			root.SetArgs(append([]string{"run", "--quiet", "--max-iterations", "1", "--delay", "0"}, tt.args...))
+			if err := root.Execute(); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatalf("read opencode args: %v", err)
+			}
+			if !strings.Contains(string(data), "-m "+tt.want+" ") {
+				t.Fatalf("expected opencode to get model %s, got args %q", tt.want, data)
+			}
+		})
+	}
+}
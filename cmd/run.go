@@ -14,7 +14,7 @@ func newRunCmd(cfg ralph.Config) *cobra.Command {
 		Args:         cobra.NoArgs,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return ralph.RunWithOptions(*opts, cfg.MaxIterations, cfg.MaxPerHour, cfg.MaxPerDay)
+			return runWithFlags(cmd, opts)
 		},
 	}
 	bindRunFlags(cmd, cfg, opts)
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"opencode-ralph/internal/ralph"
+)
+
+func newSelftestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "selftest",
+		Short:        "Run the loop in a scratch directory with a built-in echo runner",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ralph.SelfTest()
+		},
+	}
+}
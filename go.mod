@@ -2,8 +2,13 @@ module opencode-ralph
 
 go 1.25.5
 
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/spf13/cobra v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.8.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 )
@@ -0,0 +1,66 @@
+package ralph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// iterationArtifactPath returns where a run's iteration artifact is saved:
+// <dir>/<run-id>/iteration-<n>.<ext>, so runs sharing dir never overwrite
+// each other and each can be matched to its summary.
+func iterationArtifactPath(dir, runID string, iteration int, ext string) string {
+	return filepath.Join(dir, runID, fmt.Sprintf("iteration-%d.%s", iteration, ext))
+}
+
+// writeIterationArtifacts saves the exact prompt sent and the raw output
+// received for an iteration as iteration-<n>.prompt and iteration-<n>.log in
+// the run's directory under dir.
+func writeIterationArtifacts(dir, runID string, iteration int, prompt, output string) error {
+	runDir := filepath.Join(dir, runID)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory %s: %w", runDir, err)
+	}
+	promptPath := iterationArtifactPath(dir, runID, iteration, "prompt")
+	if err := os.WriteFile(promptPath, []byte(prompt), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", promptPath, err)
+	}
+	logPath := iterationArtifactPath(dir, runID, iteration, "log")
+	if err := os.WriteFile(logPath, []byte(output), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", logPath, err)
+	}
+	return nil
+}
+
+// prettyJSON indents each JSON value in output (a single document or one
+// event per line), separating values with a blank line.
+func prettyJSON(output string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(output))
+	var b bytes.Buffer
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing JSON output: %w", err)
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if err := json.Indent(&b, raw, "", "  "); err != nil {
+			return "", fmt.Errorf("indenting JSON output: %w", err)
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return "", errors.New("no JSON in output")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,95 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputDirWritesPromptAndLogPerIteration(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return fmt.Sprintf("output %d", len(prompts)), nil
+		},
+	}
+
+	outDir := filepath.Join("out", "logs")
+	opts := RunOptions{MaxIterations: 2, Quiet: true, OutputDir: outDir}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	for i, sent := range prompts {
+		iteration := i + 1
+		prompt, err := os.ReadFile(iterationArtifactPath(outDir, result.RunID, iteration, "prompt"))
+		if err != nil {
+			t.Fatalf("read prompt %d: %v", iteration, err)
+		}
+		if string(prompt) != sent {
+			t.Fatalf("prompt %d does not match what was sent", iteration)
+		}
+		log, err := os.ReadFile(iterationArtifactPath(outDir, result.RunID, iteration, "log"))
+		if err != nil {
+			t.Fatalf("read log %d: %v", iteration, err)
+		}
+		if want := fmt.Sprintf("output %d", iteration); string(log) != want {
+			t.Fatalf("log %d: got %q want %q", iteration, log, want)
+		}
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("runner calls: got %d want %d", len(prompts), 2)
+	}
+}
+
+func TestPrettyJSONLogs(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "ndjson",
+			output: "{\"type\":\"text\",\"part\":{\"text\":\"hi\"}}\n{\"type\":\"step_finish\"}\n",
+			want:   "{\n  \"type\": \"text\",\n  \"part\": {\n    \"text\": \"hi\"\n  }\n}\n\n{\n  \"type\": \"step_finish\"\n}\n",
+		},
+		{name: "invalid", output: "not json {", want: "not json {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					return tt.output, nil
+				},
+			}
+
+			opts := RunOptions{MaxIterations: 1, Quiet: true, Format: "json", OutputDir: "out", PrettyJSONLogs: true}
+			result, err := runIterationsWithRunner(cfg, opts, runner)
+			if err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+
+			log, err := os.ReadFile(iterationArtifactPath("out", result.RunID, 1, "log"))
+			if err != nil {
+				t.Fatalf("read log: %v", err)
+			}
+			if string(log) != tt.want {
+				t.Fatalf("log: got %q want %q", log, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package ralph
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Clock hooks, replaced in tests so sleeping paths run instantly.
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// runBudget bounds a run's total wall time, including delays and waits,
+// and ends it early once ctx is canceled. The zero value is unlimited.
+type runBudget struct {
+	deadline time.Time
+	ctx      context.Context
+}
+
+func newRunBudget(start time.Time, maxRuntime time.Duration) runBudget {
+	if maxRuntime <= 0 {
+		return runBudget{}
+	}
+	return runBudget{deadline: start.Add(maxRuntime)}
+}
+
+// canceled reports whether the run's context has been canceled.
+func (b runBudget) canceled() bool {
+	return b.ctx != nil && b.ctx.Err() != nil
+}
+
+// expired reports whether the budget has been used up or canceled.
+func (b runBudget) expired() bool {
+	return b.canceled() || (!b.deadline.IsZero() && !now().Before(b.deadline))
+}
+
+// sleep waits for d, clamped to the remaining budget. It returns false when
+// the budget ran out or was canceled before d elapsed, so callers should
+// stop the run.
+func (b runBudget) sleep(d time.Duration) bool {
+	if b.canceled() {
+		return false
+	}
+	if b.deadline.IsZero() {
+		return b.wait(d)
+	}
+	remaining := b.deadline.Sub(now())
+	if remaining <= 0 {
+		return false
+	}
+	if d >= remaining {
+		b.wait(remaining)
+		return false
+	}
+	return b.wait(d)
+}
+
+// wait sleeps for d, returning false if ctx is canceled first. Without a
+// cancelable context it uses the sleep hook, so tests stay instant.
+func (b runBudget) wait(d time.Duration) bool {
+	if b.ctx == nil || b.ctx.Done() == nil {
+		sleep(d)
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-b.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// countdownStep is how often a long wait reports the time left.
+const countdownStep = time.Minute
+
+// sleepWithCountdown is sleep in steps of at most countdownStep, calling
+// report with the time left before each step.
+func (b runBudget) sleepWithCountdown(d time.Duration, report func(left time.Duration)) bool {
+	for d > 0 {
+		report(d)
+		step := min(d, countdownStep)
+		if !b.sleep(step) {
+			return false
+		}
+		d -= step
+	}
+	return true
+}
+
+// maxRetryBackoff caps the exponential wait between --retries attempts.
+const maxRetryBackoff = 10 * time.Minute
+
+// retryBackoff is the wait before retry attempt+1: base doubled per attempt.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 0; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// jitterRand supplies --delay-jitter offsets, seeded per process so
+// concurrent runs drift apart; tests may replace it with a seeded source.
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// jitteredDelay adds a uniform random offset in [0, jitter] to delay. A
+// jitter <= 0 returns delay unchanged, without drawing from rng.
+func jitteredDelay(delay, jitter time.Duration, rng *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return delay
+	}
+	return delay + time.Duration(rng.Int63n(int64(jitter)+1))
+}
+
+// iterationDelay returns the pause before the next iteration. With a positive
+// ratio the pause is ratio times the last opencode call's duration, clamped to
+// [minDelay, maxDelay] (maxDelay <= 0 means no upper bound); otherwise it is
+// the fixed delay.
+func iterationDelay(fixed time.Duration, ratio float64, last, minDelay, maxDelay time.Duration) time.Duration {
+	if ratio <= 0 {
+		return fixed
+	}
+	d := time.Duration(ratio * float64(last))
+	if d < minDelay {
+		d = minDelay
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	return d
+}
@@ -0,0 +1,339 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	current time.Time
+	slept   []time.Duration
+}
+
+// useFakeClock replaces the package clock hooks; sleeping advances the clock instantly.
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+
+	clock := &fakeClock{current: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	origNow, origSleep := now, sleep
+	now = func() time.Time { return clock.current }
+	sleep = func(d time.Duration) {
+		clock.slept = append(clock.slept, d)
+		clock.current = clock.current.Add(d)
+	}
+	t.Cleanup(func() {
+		now, sleep = origNow, origSleep
+	})
+	return clock
+}
+
+func TestRunBudgetSleep(t *testing.T) {
+	clock := useFakeClock(t)
+
+	unlimited := newRunBudget(clock.current, 0)
+	if !unlimited.sleep(time.Hour) || unlimited.expired() {
+		t.Fatalf("expected unlimited budget to never expire")
+	}
+
+	budget := newRunBudget(clock.current, 10*time.Second)
+	if !budget.sleep(4 * time.Second) {
+		t.Fatalf("expected sleep within budget to succeed")
+	}
+	if budget.sleep(time.Minute) {
+		t.Fatalf("expected sleep past budget to report expiry")
+	}
+	if got := clock.slept[len(clock.slept)-1]; got != 6*time.Second {
+		t.Fatalf("expected clamped sleep of 6s, got %s", got)
+	}
+	if !budget.expired() {
+		t.Fatalf("expected budget to be expired")
+	}
+	if budget.sleep(time.Second) {
+		t.Fatalf("expected sleep on expired budget to fail immediately")
+	}
+}
+
+func TestMaxRuntimeAbortsDuringDelay(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "still working", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 5, Quiet: true, Delay: 60, MaxRuntime: 5 * time.Second, SummaryLog: "runs.log"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("runner calls: got %d want %d", calls, 1)
+	}
+	var total time.Duration
+	for _, d := range clock.slept {
+		total += d
+	}
+	if total != 5*time.Second {
+		t.Fatalf("total sleep: got %s want %s", total, 5*time.Second)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":"time_limit"`) {
+		t.Fatalf("expected time_limit status, got %s", data)
+	}
+}
+
+func TestIterationDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		ratio float64
+		last  time.Duration
+		min   time.Duration
+		max   time.Duration
+		want  time.Duration
+	}{
+		{name: "fixed", ratio: 0, last: time.Minute, want: 2 * time.Second},
+		{name: "proportional", ratio: 0.5, last: 30 * time.Second, want: 15 * time.Second},
+		{name: "clamped to min", ratio: 0.5, last: time.Second, min: 5 * time.Second, want: 5 * time.Second},
+		{name: "clamped to max", ratio: 2, last: time.Minute, max: 30 * time.Second, want: 30 * time.Second},
+		{name: "unbounded max", ratio: 2, last: time.Minute, want: 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := iterationDelay(2*time.Second, tt.ratio, tt.last, tt.min, tt.max); got != tt.want {
+				t.Fatalf("got %s want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelayRatioUsesPreviousCallDuration(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	callTimes := []time.Duration{40 * time.Second, 2 * time.Second, time.Second}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			clock.current = clock.current.Add(callTimes[calls])
+			calls++
+			return "", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, Delay: 2, DelayRatio: 0.5, DelayMin: 3 * time.Second}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	want := []time.Duration{20 * time.Second, 3 * time.Second, 3 * time.Second}
+	if len(clock.slept) != len(want) {
+		t.Fatalf("slept %v want %v", clock.slept, want)
+	}
+	for i := range want {
+		if clock.slept[i] != want[i] {
+			t.Fatalf("slept %v want %v", clock.slept, want)
+		}
+	}
+}
+
+func TestWarnOnSlowIteration(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	durations := []time.Duration{2 * time.Minute, 20 * time.Minute, 5 * time.Minute, 11 * time.Minute}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			clock.current = clock.current.Add(durations[calls])
+			calls++
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 4, SlowIterationWarning: 10 * time.Minute, SummaryLog: "runs.log"}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if got := strings.Count(out, "over --warn-on-slow-iteration 10m0s"); got != 2 {
+		t.Fatalf("expected 2 slow-iteration warnings, got %d:\n%s", got, out)
+	}
+	if !strings.Contains(out, "Warning: iteration 2 took 20m0s") || !strings.Contains(out, "Warning: iteration 4 took 11m0s") {
+		t.Fatalf("expected warnings for iterations 2 and 4, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Slow iterations: 2\n") {
+		t.Fatalf("expected slow iteration count in summary, got:\n%s", out)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"slow_iterations":2`) {
+		t.Fatalf("expected slow_iterations in summary log, got %s", data)
+	}
+}
+
+func TestTimedOutIterationIsNotCompletion(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			if calls == 1 {
+				return "<ralph_notes>partial</ralph_notes><ralph_status>COMPLETE</ralph_status>", fmt.Errorf("%w after 1s", ErrIterationTimeout)
+			}
+			return "<ralph_notes>done</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, Timeout: 1, SummaryLog: "runs.log"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected the timed-out iteration not to complete the run, got %d calls", calls)
+	}
+	notes, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if !strings.Contains(string(notes), "partial") {
+		t.Fatalf("expected notes from the partial output, got %q", notes)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"timed_out":1`) || !strings.Contains(string(data), `"status":"complete"`) {
+		t.Fatalf("expected one timed-out iteration before completion, got %s", data)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 2 * time.Second},
+		{1, 4 * time.Second},
+		{3, 16 * time.Second},
+		{20, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(2*time.Second, tt.attempt); got != tt.want {
+			t.Fatalf("retryBackoff(2s, %d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRetriesRerunFailedCalls(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantCalls   int
+		wantSlept   []time.Duration
+		wantRetries int
+	}{
+		{name: "recovers", failures: 2, wantCalls: 3, wantSlept: []time.Duration{2 * time.Second, 4 * time.Second}, wantRetries: 2},
+		{name: "exhausted", failures: 10, wantCalls: 4, wantSlept: []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}, wantRetries: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			clock := useFakeClock(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			var calls int
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					calls++
+					if calls <= tt.failures {
+						return "", errors.New("connection reset")
+					}
+					return "<ralph_notes>n</ralph_notes>", nil
+				},
+			}
+
+			opts := RunOptions{MaxIterations: 1, Quiet: true, Retries: 3, RetryBackoff: 2, SummaryLog: "runs.log"}
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Fatalf("runner calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if len(clock.slept) != len(tt.wantSlept) {
+				t.Fatalf("slept %v, want %v", clock.slept, tt.wantSlept)
+			}
+			for i := range tt.wantSlept {
+				if clock.slept[i] != tt.wantSlept[i] {
+					t.Fatalf("slept %v, want %v", clock.slept, tt.wantSlept)
+				}
+			}
+			state := loadState()
+			if state.TotalIterations != 1 || len(state.Timestamps) != 1 {
+				t.Fatalf("retries must not count as iterations: total=%d timestamps=%d", state.TotalIterations, len(state.Timestamps))
+			}
+			data, err := os.ReadFile("runs.log")
+			if err != nil {
+				t.Fatalf("read summary log: %v", err)
+			}
+			if !strings.Contains(string(data), fmt.Sprintf(`"retries":%d`, tt.wantRetries)) {
+				t.Fatalf("expected retries in summary log, got %s", data)
+			}
+		})
+	}
+}
+
+func TestJitteredDelay(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if got := jitteredDelay(2*time.Second, 0, rng); got != 2*time.Second {
+		t.Fatalf("zero jitter: got %s want 2s", got)
+	}
+	if fresh := rand.New(rand.NewSource(1)); rng.Int63() != fresh.Int63() {
+		t.Fatalf("zero jitter must not draw from the random source")
+	}
+
+	seen := map[time.Duration]bool{}
+	for range 100 {
+		got := jitteredDelay(2*time.Second, time.Second, rng)
+		if got < 2*time.Second || got > 3*time.Second {
+			t.Fatalf("jittered delay %s outside [2s, 3s]", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected varying delays, got %v", seen)
+	}
+}
@@ -0,0 +1,55 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultCheckOutputBytes caps --check-command output in the prompt.
+const defaultCheckOutputBytes = 10000
+
+// CheckResult is the outcome of --check-command, shown to the agent in a
+// <check_results> section.
+type CheckResult struct {
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+// runCheckCommand runs command via sh -c and captures its combined output.
+// A non-zero exit is a result, not an error; err is only set when the
+// command could not be run at all.
+func runCheckCommand(command string, maxBytes int) (CheckResult, error) {
+	result := CheckResult{Command: command}
+	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return result, fmt.Errorf("running check command %q: %w", command, err)
+	}
+	result.Output = tailBytes(sanitizeOutput(string(output)), maxBytes)
+	return result, nil
+}
+
+// tailBytes keeps the last maxBytes bytes of s, where failures usually are,
+// without splitting a UTF-8 character. maxBytes <= 0 means unlimited.
+func tailBytes(s string, maxBytes int) string {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+	start := len(s) - maxBytes
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return fmt.Sprintf("[output truncated: kept last %d of %d bytes]\n", len(s)-start, len(s)) + s[start:]
+}
+
+// renderCheckResults formats r as the prompt's <check_results> section.
+func renderCheckResults(r CheckResult) string {
+	return fmt.Sprintf("\n<check_results command=%q exit_code=\"%d\">\n%s\n</check_results>\n", r.Command, r.ExitCode, strings.TrimRight(r.Output, "\n"))
+}
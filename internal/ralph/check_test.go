@@ -0,0 +1,61 @@
+package ralph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunCheckCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		maxBytes int
+		wantCode int
+		want     string
+	}{
+		{name: "success", command: "echo ok", wantCode: 0, want: "ok\n"},
+		{name: "failure keeps output", command: "echo broken >&2; exit 3", wantCode: 3, want: "broken\n"},
+		{name: "truncates to tail", command: "printf 'aaaaabbbbb'", maxBytes: 5, want: "[output truncated: kept last 5 of 10 bytes]\nbbbbb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runCheckCommand(tt.command, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("runCheckCommand: %v", err)
+			}
+			if got.ExitCode != tt.wantCode || got.Output != tt.want {
+				t.Fatalf("got (%d, %q) want (%d, %q)", got.ExitCode, got.Output, tt.wantCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailBytesKeepsRunesWhole(t *testing.T) {
+	got := tailBytes("aé", 1)
+	if !strings.HasSuffix(got, "]\n") {
+		t.Fatalf("expected only the marker when the tail would split a rune, got %q", got)
+	}
+}
+
+func TestCheckResultsInPrompt(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var prompt string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompt = args.Prompt
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 1, Quiet: true, CheckCommand: "echo FAIL: TestX; exit 1"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	want := "<check_results command=\"echo FAIL: TestX; exit 1\" exit_code=\"1\">\nFAIL: TestX\n</check_results>"
+	if !strings.Contains(prompt, want) {
+		t.Fatalf("expected %q in prompt, got:\n%s", want, prompt)
+	}
+}
@@ -0,0 +1,51 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Clean removes ralph's bookkeeping from .ralph/ (state, iteration history,
+// notes, specs backups, and a stale lock) and, with all, the config too. The
+// user's prompt, conventions, and specs files are never touched. It refuses to
+// run while the lock is held by a live process, and returns the paths removed.
+func Clean(all bool) ([]string, error) {
+	if _, err := os.Stat(lockFile); err == nil {
+		pid, err := readLockPID(lockFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s exists but is unreadable; a run may be starting, remove it manually if not: %w", lockFile, err)
+		}
+		if processAlive(pid) {
+			return nil, fmt.Errorf("a run is active (pid %d holds %s); stop it before cleaning", pid, lockFile)
+		}
+	}
+
+	paths := []string{stateFile, historyLogFile, notesFile, notesArchiveFile, specsBackupDir, lockFile}
+	for n := 1; n <= maxNotesRotations; n++ {
+		paths = append(paths, rotatedNotesFile(notesFile, n))
+	}
+	if all {
+		for _, format := range configFormats {
+			paths = append(paths, format.Path)
+		}
+	}
+
+	var removed []string
+	for _, path := range paths {
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("removing %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	if all {
+		// Only an empty directory is removed, so unknown files survive.
+		if err := os.Remove(ralphDir); err == nil {
+			removed = append(removed, ralphDir)
+		}
+	}
+	return removed, nil
+}
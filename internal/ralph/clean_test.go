@@ -0,0 +1,92 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name     string
+		all      bool
+		wantGone []string
+		wantKept []string
+	}{
+		{
+			name:     "bookkeeping only",
+			wantGone: []string{stateFile, historyLogFile, notesFile, notesArchiveFile, specsBackupDir, lockFile},
+			wantKept: []string{configFile, "PROMPT.md", "CONVENTIONS.md", "SPECS.md"},
+		},
+		{
+			name:     "all",
+			all:      true,
+			wantGone: []string{stateFile, notesFile, configFile, lockFile, ralphDir},
+			wantKept: []string{"PROMPT.md", "CONVENTIONS.md", "SPECS.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			useFakeProcesses(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+			if err := SaveConfig(cfg); err != nil {
+				t.Fatalf("SaveConfig: %v", err)
+			}
+			saveState(State{TotalIterations: 3})
+			for _, path := range []string{historyLogFile, notesFile, notesArchiveFile, filepath.Join(specsBackupDir, "iteration-1.md")} {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+					t.Fatalf("write %s: %v", path, err)
+				}
+			}
+			// A stale lock left behind by a crashed run.
+			if err := os.WriteFile(lockFile, []byte("4242\n"), 0o644); err != nil {
+				t.Fatalf("write lock: %v", err)
+			}
+
+			removed, err := Clean(tt.all)
+			if err != nil {
+				t.Fatalf("Clean: %v", err)
+			}
+			for _, path := range tt.wantGone {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Fatalf("expected %s to be removed (removed %v)", path, removed)
+				}
+			}
+			for _, path := range tt.wantKept {
+				if _, err := os.Stat(path); err != nil {
+					t.Fatalf("expected %s to be kept: %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanRefusesWhileRunActive(t *testing.T) {
+	withTempCWD(t)
+	useFakeProcesses(t, 4242)
+
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	saveState(State{TotalIterations: 3})
+	if err := os.WriteFile(lockFile, []byte("4242\n"), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	_, err := Clean(true)
+	if err == nil || !strings.Contains(err.Error(), "a run is active (pid 4242") {
+		t.Fatalf("expected active-run error, got %v", err)
+	}
+	for _, path := range []string{stateFile, lockFile} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to be untouched: %v", path, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package ralph
 
 import (
+	"fmt"
 	"os"
 	"strings"
 )
@@ -25,18 +26,45 @@ const (
 	ansiGray   = "\033[90m"
 )
 
-func shouldUseColor(quiet bool) bool {
-	if quiet {
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+func validateColorMode(mode string) error {
+	switch mode {
+	case "", colorAuto, colorAlways, colorNever:
+		return nil
+	default:
+		return fmt.Errorf("invalid --color value: %s (expected always, auto, or never)", mode)
+	}
+}
+
+// resolveColor applies a --color mode; auto (or empty) falls back to detection.
+func resolveColor(mode string, quiet bool) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
 		return false
+	default:
+		return shouldUseColor(quiet)
 	}
-	if os.Getenv("NO_COLOR") != "" {
+}
+
+func shouldUseColor(quiet bool) bool {
+	return autoColor(quiet, os.Getenv("NO_COLOR"), isTerminal(os.Stdout))
+}
+
+func autoColor(quiet bool, noColor string, isTTY bool) bool {
+	if quiet {
 		return false
 	}
-	fi, err := os.Stdout.Stat()
-	if err != nil {
+	if noColor != "" {
 		return false
 	}
-	return (fi.Mode() & os.ModeCharDevice) != 0
+	return isTTY
 }
 
 func style(text string, codes ...string) string {
@@ -64,8 +92,10 @@ func statusStyle(status string) (string, []string) {
 	switch strings.ToLower(status) {
 	case "complete":
 		return strings.ToUpper(status), []string{ansiGreen, ansiBold}
-	case "rate_limited", "max_iterations":
+	case "rate_limited", "max_iterations", "time_limit", "token_budget", "canceled":
 		return strings.ToUpper(status), []string{ansiYellow, ansiBold}
+	case "failed", "error":
+		return strings.ToUpper(status), []string{ansiRed, ansiBold}
 	case "dry_run":
 		return strings.ToUpper(status), []string{ansiCyan, ansiBold}
 	case "unknown":
@@ -0,0 +1,54 @@
+package ralph
+
+import "testing"
+
+func TestResolveColorModes(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	if !resolveColor(colorAlways, false) {
+		t.Fatalf("always: expected color even with NO_COLOR set")
+	}
+	if resolveColor(colorNever, false) {
+		t.Fatalf("never: expected no color")
+	}
+	if resolveColor(colorAuto, false) {
+		t.Fatalf("auto: expected NO_COLOR to disable color")
+	}
+	if resolveColor("", false) {
+		t.Fatalf("empty mode: expected auto behavior")
+	}
+}
+
+func TestAutoColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		quiet   bool
+		noColor string
+		isTTY   bool
+		want    bool
+	}{
+		{name: "tty", isTTY: true, want: true},
+		{name: "pipe", isTTY: false, want: false},
+		{name: "no color env", noColor: "1", isTTY: true, want: false},
+		{name: "quiet", quiet: true, isTTY: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autoColor(tt.quiet, tt.noColor, tt.isTTY); got != tt.want {
+				t.Fatalf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateColorMode(t *testing.T) {
+	for _, mode := range []string{"", "auto", "always", "never"} {
+		if err := validateColorMode(mode); err != nil {
+			t.Fatalf("validateColorMode(%q): %v", mode, err)
+		}
+	}
+	if err := validateColorMode("sometimes"); err == nil {
+		t.Fatalf("expected error for invalid mode")
+	}
+}
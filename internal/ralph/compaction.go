@@ -0,0 +1,106 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+const notesArchiveFile = ".ralph/notes-archive.md"
+
+const summarizeNotesPrompt = `Summarize the following notes from earlier iterations of an automated development loop.
+Keep decisions, open problems, and anything the next iteration must know; drop detail that is no longer relevant.
+The summary must be at most %d characters. Reply with the summary inside <ralph_notes>...</ralph_notes>.
+
+<ralph_notes_history>
+%s
+</ralph_notes_history>
+`
+
+// notesSummarizer condenses notes to at most maxChars characters.
+type notesSummarizer func(notes string, maxChars int) (string, error)
+
+// runnerSummarizer asks opencode, through runner, to summarize the notes. It
+// always starts a fresh session so the summary does not pollute the working one.
+func runnerSummarizer(runner OpencodeRunner, base OpencodeRunArgs) notesSummarizer {
+	return func(notes string, maxChars int) (string, error) {
+		args := base
+		args.Prompt = fmt.Sprintf(summarizeNotesPrompt, maxChars, notes)
+		args.Format = ""
+		args.ContinueSession = false
+		args.Session = ""
+		args.Files = nil
+		args.Title = ""
+		output, err := runner.Run(args)
+		if err != nil {
+			return "", fmt.Errorf("summarizing notes: %w", err)
+		}
+		summary := extractNotes(output, defaultNotesTag)
+		if summary == "" {
+			return "", errors.New("summarizing notes: no <ralph_notes> in output")
+		}
+		return summary, nil
+	}
+}
+
+// fitPromptBudget shrinks data.Notes so the rendered prompt stays within
+// maxChars. It tries summarize first (when non-nil) and falls back to keeping
+// the most recent notes. It returns the new notes and whether they were
+// summarized; a summarize failure is returned alongside the truncated notes.
+func fitPromptBudget(data PromptData, maxChars int, summarize notesSummarizer) (notes string, summarized bool, err error) {
+	withoutNotes := data
+	withoutNotes.Notes = ""
+	budget := maxChars - utf8.RuneCountInString(renderPrompt(withoutNotes))
+	if budget < 0 {
+		budget = 0
+	}
+
+	if summarize != nil {
+		summary, sumErr := summarize(data.Notes, budget)
+		if sumErr == nil && utf8.RuneCountInString(summary) > budget {
+			sumErr = fmt.Errorf("summarizing notes: summary is %d characters, budget is %d", utf8.RuneCountInString(summary), budget)
+		}
+		if sumErr == nil {
+			return summary, true, nil
+		}
+		err = sumErr
+	}
+	return keepRecentNotes(data.Notes, budget), false, err
+}
+
+// keepRecentNotes keeps the tail of notes, marker included, within maxChars.
+func keepRecentNotes(notes string, maxChars int) string {
+	runes := []rune(notes)
+	if len(runes) <= maxChars {
+		return notes
+	}
+	marker := fmt.Sprintf("[earlier notes dropped: kept last %d of %d characters]\n", maxChars, len(runes))
+	keep := maxChars - utf8.RuneCountInString(marker)
+	if keep <= 0 {
+		return ""
+	}
+	marker = fmt.Sprintf("[earlier notes dropped: kept last %d of %d characters]\n", keep, len(runes))
+	return marker + string(runes[len(runes)-keep:])
+}
+
+// compactNotesFile replaces the notes file with summary, appending the old
+// contents to the notes archive so nothing is lost.
+func compactNotesFile(previous, summary string) error {
+	f, err := os.OpenFile(notesArchiveFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening notes archive: %w", err)
+	}
+	if _, err := f.WriteString(previous); err != nil {
+		f.Close()
+		return fmt.Errorf("writing notes archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing notes archive: %w", err)
+	}
+	timestamp := now().Format("2006-01-02 15:04:05")
+	if err := os.WriteFile(notesFile, []byte(fmt.Sprintf("# Compacted notes (%s)\n%s\n", timestamp, summary)), 0644); err != nil {
+		return fmt.Errorf("writing notes: %w", err)
+	}
+	return nil
+}
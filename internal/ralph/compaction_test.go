@@ -0,0 +1,101 @@
+package ralph
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestKeepRecentNotes(t *testing.T) {
+	notes := strings.Repeat("a", 100) + "TAIL"
+	got := keepRecentNotes(notes, 60)
+	if utf8.RuneCountInString(got) > 60 {
+		t.Fatalf("expected at most 60 characters, got %d", utf8.RuneCountInString(got))
+	}
+	if !strings.HasSuffix(got, "TAIL") || !strings.HasPrefix(got, "[earlier notes dropped") {
+		t.Fatalf("got %q", got)
+	}
+	if got := keepRecentNotes("short", 60); got != "short" {
+		t.Fatalf("expected short notes untouched, got %q", got)
+	}
+}
+
+func TestFitPromptBudget(t *testing.T) {
+	data := PromptData{Prompt: "P", Notes: strings.Repeat("n", 2000), Iteration: 1, MaxIterations: 1}
+	withoutNotes := data
+	withoutNotes.Notes = ""
+	maxChars := utf8.RuneCountInString(renderPrompt(withoutNotes)) + 100
+
+	var gotBudget int
+	summarize := func(notes string, budget int) (string, error) {
+		gotBudget = budget
+		return "summary", nil
+	}
+	notes, summarized, err := fitPromptBudget(data, maxChars, summarize)
+	if err != nil || !summarized || notes != "summary" {
+		t.Fatalf("got (%q, %v, %v)", notes, summarized, err)
+	}
+	if gotBudget != 100 {
+		t.Fatalf("summarizer budget: got %d want 100", gotBudget)
+	}
+
+	failing := func(string, int) (string, error) { return "", errors.New("boom") }
+	notes, summarized, err = fitPromptBudget(data, maxChars, failing)
+	if err == nil || summarized {
+		t.Fatalf("expected summarize error and fallback, got (%v, %v)", summarized, err)
+	}
+	data.Notes = notes
+	if got := utf8.RuneCountInString(renderPrompt(data)); got > maxChars {
+		t.Fatalf("fallback prompt is %d characters, budget %d", got, maxChars)
+	}
+}
+
+func TestMaxPromptCharsSummarizesNotes(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	oldNotes := strings.Repeat("old note\n", 500)
+	if err := os.WriteFile(notesFile, []byte(oldNotes), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			if strings.HasPrefix(args.Prompt, "Summarize the following notes") {
+				return "<ralph_notes>condensed</ralph_notes>", nil
+			}
+			return "<ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, MaxPromptChars: 1000, SummarizeNotes: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected summarize call plus iteration, got %d calls", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "<ralph_notes_history>\ncondensed\n</ralph_notes_history>") {
+		t.Fatalf("expected summarized notes in prompt, got %q", prompts[1])
+	}
+	if utf8.RuneCountInString(prompts[1]) > 1000 {
+		t.Fatalf("prompt exceeds budget: %d", utf8.RuneCountInString(prompts[1]))
+	}
+	archived, err := os.ReadFile(notesArchiveFile)
+	if err != nil || string(archived) != oldNotes {
+		t.Fatalf("expected old notes archived, got %q (%v)", archived, err)
+	}
+	compacted, err := os.ReadFile(notesFile)
+	if err != nil || !strings.Contains(string(compacted), "condensed") {
+		t.Fatalf("expected compacted notes file, got %q (%v)", compacted, err)
+	}
+}
@@ -4,60 +4,188 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Config holds project configuration.
 type Config struct {
-	PromptFile      string `json:"prompt_file"`
-	ConventionsFile string `json:"conventions_file"`
-	SpecsFile       string `json:"specs_file"`
-	MaxIterations   int    `json:"max_iterations"`
-	MaxPerHour      int    `json:"max_per_hour"`
-	MaxPerDay       int    `json:"max_per_day"`
-	Model           string `json:"model,omitempty"`
+	PromptFile          string            `json:"prompt_file"`
+	ConventionsFile     string            `json:"conventions_file"`
+	SpecsFile           string            `json:"specs_file"`
+	MaxIterations       int               `json:"max_iterations"`
+	MaxPerHour          int               `json:"max_per_hour"`
+	MaxPerDay           int               `json:"max_per_day"`
+	MaxPerMinute        int               `json:"max_per_minute,omitempty"`
+	Model               string            `json:"model,omitempty"`
+	PromptArgStyle      string            `json:"prompt_arg_style"`
+	OpencodeSubcommand  string            `json:"opencode_subcommand"`
+	BlockedDirs         []string          `json:"blocked_dirs,omitempty"`
+	EscalationHints     []EscalationHint  `json:"escalation_hints"`
+	ResumeWindow        string            `json:"resume_window"`
+	ExitCodePolicy      map[string]string `json:"exit_code_policy"`
+	CompletionSignal    string            `json:"completion_signal"`
+	CommitEachIteration bool              `json:"commit_each_iteration"`
+	Format              string            `json:"format,omitempty"`
+	PromptTemplate      string            `json:"prompt_template,omitempty"`
+	MaxNotesBytes       int               `json:"max_notes_bytes,omitempty"`
+	NotesRotation       string            `json:"notes_rotation,omitempty"`
+	OnComplete          string            `json:"on_complete,omitempty"`
+	OnFailure           string            `json:"on_failure,omitempty"`
+	WebhookURL          string            `json:"webhook_url,omitempty"`
+	MinOpencodeVersion  string            `json:"min_opencode_version,omitempty"`
+	OpencodeBin         string            `json:"opencode_bin,omitempty"`
+	NotesTag            string            `json:"notes_tag"`
+	StatusTag           string            `json:"status_tag"`
+}
+
+const defaultCompletionSignal = "COMPLETE"
+
+// Default tag names for the agent's notes and status blocks.
+const (
+	defaultNotesTag  = "ralph_notes"
+	defaultStatusTag = "ralph_status"
+)
+
+var tagNameRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// validateTagName accepts tag names made of letters, digits, and underscores.
+func validateTagName(value string) error {
+	if !tagNameRe.MatchString(value) {
+		return fmt.Errorf("invalid tag name %q: use only letters, digits, and underscores", value)
+	}
+	return nil
+}
+
+// requirePath returns a validator rejecting an empty path for key.
+func requirePath(key string) func(string) error {
+	return func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s must not be empty", key)
+		}
+		return nil
+	}
+}
+
+// validateModel accepts an empty model or one in opencode's provider/model
+// form, e.g. anthropic/claude-sonnet-4 or ollama/qwen3-coder:30b.
+func validateModel(value string) error {
+	if value == "" {
+		return nil
+	}
+	provider, model, ok := strings.Cut(value, "/")
+	if !ok || provider == "" || model == "" || strings.ContainsAny(value, " \t\n") {
+		return fmt.Errorf("invalid model %q: expected provider/model, e.g. anthropic/claude-sonnet-4", value)
+	}
+	return nil
+}
+
+func validateCompletionSignal(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("completion_signal must not be empty")
+	}
+	return nil
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
-		PromptFile:      "PROMPT.md",
-		ConventionsFile: "CONVENTIONS.md",
-		SpecsFile:       "SPECS.md",
-		MaxIterations:   50,
-		MaxPerHour:      0,
-		MaxPerDay:       0,
+		PromptFile:         "PROMPT.md",
+		ConventionsFile:    "CONVENTIONS.md",
+		SpecsFile:          "SPECS.md",
+		MaxIterations:      50,
+		MaxPerHour:         0,
+		MaxPerDay:          0,
+		PromptArgStyle:     promptArgStylePositional,
+		OpencodeSubcommand: "run",
+		EscalationHints:    defaultEscalationHints(),
+		ResumeWindow:       defaultResumeWindow,
+		ExitCodePolicy:     defaultExitCodePolicy(),
+		CompletionSignal:   defaultCompletionSignal,
+		NotesTag:           defaultNotesTag,
+		StatusTag:          defaultStatusTag,
+	}
+}
+
+// LoadConfig returns the effective configuration: defaults, overlaid by
+// the config file if present (.ralph/config.json, .toml, or .yaml), overlaid
+// by RALPH_* environment variables. CLI flags are applied on top by the
+// caller.
+func LoadConfig() (Config, error) {
+	cfg := loadConfigFile()
+	if err := applyEnvOverrides(&cfg, os.LookupEnv); err != nil {
+		return cfg, err
 	}
+	if err := ValidateConfig(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %w", activeConfigFormat().Path, err)
+	}
+	return cfg, nil
 }
 
-// LoadConfig loads .ralph/config.json if present.
-func LoadConfig() Config {
+// loadConfigFile loads the config file over the defaults, ignoring the
+// environment; commands that rewrite the file start from this.
+func loadConfigFile() Config {
 	cfg := DefaultConfig()
-	data, err := os.ReadFile(configFile)
+	format := activeConfigFormat()
+	data, err := os.ReadFile(format.Path)
 	if err != nil {
 		return cfg
 	}
-	_ = json.Unmarshal(data, &cfg)
+	_ = format.Codec.Unmarshal(data, &cfg)
 	return cfg
 }
 
-// SaveConfig persists cfg to .ralph/config.json.
+// SaveConfig persists cfg to the config file, keeping the format of the one
+// already present (JSON for a new project).
 func SaveConfig(cfg Config) error {
 	if err := os.MkdirAll(ralphDir, 0755); err != nil {
 		return fmt.Errorf("creating %s directory: %w", ralphDir, err)
 	}
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	format := activeConfigFormat()
+	data, err := format.Codec.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("marshalling config: %w", err)
 	}
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
-		return fmt.Errorf("writing %s: %w", configFile, err)
+	if err := os.WriteFile(format.Path, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", format.Path, err)
 	}
 	return nil
 }
 
-// ConfigView renders the current config as JSON.
+// ValidateConfig checks every field against the constraints in
+// configFields(): minimums for iteration and rate limits, non-empty file
+// paths, the provider/model form of model, and each key's own validator.
+func ValidateConfig(cfg Config) error {
+	for _, field := range configFields() {
+		value, ok := configValue(&cfg, field.Key)
+		if !ok {
+			continue
+		}
+		if field.validate != nil {
+			raw := value.String()
+			if value.Kind() != reflect.String {
+				data, err := json.Marshal(value.Interface())
+				if err != nil {
+					return fmt.Errorf("marshalling %s: %w", field.Key, err)
+				}
+				raw = string(data)
+			}
+			if err := field.validate(raw); err != nil {
+				return err
+			}
+		}
+		if field.Minimum != nil && value.Kind() == reflect.Int && value.Int() < int64(*field.Minimum) {
+			return fmt.Errorf("%s must be at least %d", field.Key, *field.Minimum)
+		}
+	}
+	return nil
+}
+
+// ConfigView renders .ralph/config.json, with defaults filled in, as JSON.
 func ConfigView() (string, error) {
-	cfg := LoadConfig()
+	cfg := loadConfigFile()
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("marshalling config: %w", err)
@@ -71,48 +199,157 @@ func ConfigReset() error {
 	return SaveConfig(cfg)
 }
 
+// configField describes a user-settable key in .ralph/config.json. Keys
+// match the Config struct's json tags.
+type configField struct {
+	Key         string
+	Description string
+	// Minimum, when set, is the smallest accepted value for integer keys.
+	Minimum  *int
+	Enum     []string
+	validate func(value string) error
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// configFields lists every settable config key in display order.
+func configFields() []configField {
+	return []configField{
+		{Key: "prompt_file", Description: "Path to the prompt file", validate: requirePath("prompt_file")},
+		{Key: "conventions_file", Description: "Path to the conventions file", validate: requirePath("conventions_file")},
+		{Key: "specs_file", Description: "Path to the specs file", validate: requirePath("specs_file")},
+		{Key: "max_iterations", Description: "Maximum iterations per run", Minimum: intPtr(1)},
+		{Key: "max_per_hour", Description: "Maximum iterations per hour (0 = unlimited)", Minimum: intPtr(0)},
+		{Key: "max_per_day", Description: "Maximum iterations per day (0 = unlimited)", Minimum: intPtr(0)},
+		{Key: "max_per_minute", Description: "Maximum iterations per minute (0 = unlimited)", Minimum: intPtr(0)},
+		{Key: "model", Description: "Model passed to opencode run -m, as provider/model (empty for opencode's default)", validate: validateModel},
+		{Key: "prompt_arg_style", Description: "How the prompt is passed to opencode: positional or flag", Enum: []string{promptArgStylePositional, promptArgStyleFlag}, validate: validatePromptArgStyle},
+		{Key: "opencode_subcommand", Description: "opencode subcommand placed before the flags (empty for none)", validate: validateOpencodeSubcommand},
+		{Key: "blocked_dirs", Description: "Extra directories where runs are refused (comma-separated)"},
+		{Key: "escalation_hints", Description: "Budget escalation hints as a JSON array of {at, message}"},
+		{Key: "resume_window", Description: "How recent the last run must be for --resume to continue it (e.g. 1h)", validate: validateResumeWindow},
+		{Key: "exit_code_policy", Description: "How to handle non-zero opencode exit codes as a JSON object of code to continue, retry, or fatal", validate: validateExitCodePolicy},
+		{Key: "completion_signal", Description: "Word the agent puts in status_tag tags to finish the run, or a full tag such as <task_finished/>", validate: validateCompletionSignal},
+		{Key: "notes_tag", Description: "Tag name of the agent's notes blocks, e.g. ralph_notes for <ralph_notes>...</ralph_notes>", validate: validateTagName},
+		{Key: "status_tag", Description: "Tag name of the agent's status block that carries completion_signal, e.g. ralph_status", validate: validateTagName},
+		{Key: "commit_each_iteration", Description: "Commit the working tree with git after each successful iteration (like --commit)"},
+		{Key: "prompt_template", Description: "Go text/template file that lays out the prompt from .Prompt, .Conventions, .Specs, .Notes, .Iteration, .MaxIterations (empty for the built-in layout)"},
+		{Key: "max_notes_bytes", Description: "Size cap for .ralph/notes.md in bytes before notes_rotation applies (0 = unlimited)", Minimum: intPtr(0)},
+		{Key: "notes_rotation", Description: "What happens at max_notes_bytes: rotate (move to notes.md.1, keeping 5 old files) or truncate (drop the oldest entries)", Enum: []string{notesRotate, notesTruncate}, validate: validateNotesRotation},
+		{Key: "on_complete", Description: "Shell command to run when a run completes (like --on-complete)"},
+		{Key: "on_failure", Description: "Shell command to run when a run fails or stops at max iterations (like --on-failure)"},
+		{Key: "webhook_url", Description: "URL that receives a JSON POST when a run finishes (like --webhook)", validate: validateWebhookURL},
+		{Key: "min_opencode_version", Description: "Warn at the start of a run when opencode --version is older than this (e.g. 0.3.0)", validate: validateMinOpencodeVersion},
+		{Key: "opencode_bin", Description: "opencode binary to run: a name looked up on PATH or a path to the binary or a wrapper script (empty for opencode)"},
+		{Key: "format", Description: "Output format passed to opencode run --format: default or json (--format overrides)", Enum: []string{"default", "json"}, validate: validateFormat},
+	}
+}
+
+func lookupConfigField(key string) (configField, bool) {
+	for _, field := range configFields() {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return configField{}, false
+}
+
+// configValue returns the addressable Config struct field tagged with key.
+func configValue(cfg *Config, key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
 // ConfigSet updates a single config key.
 func ConfigSet(key, value string) error {
-	cfg := LoadConfig()
-
-	switch key {
-	case "prompt_file":
-		cfg.PromptFile = value
-	case "conventions_file":
-		cfg.ConventionsFile = value
-	case "specs_file":
-		cfg.SpecsFile = value
-	case "max_iterations":
-		v, err := parseInt(value)
-		if err != nil {
-			return fmt.Errorf("parsing max_iterations: %w", err)
+	field, ok := lookupConfigField(key)
+	if !ok {
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+	cfg := loadConfigFile()
+	if err := setConfigValue(&cfg, field, value); err != nil {
+		return err
+	}
+	return SaveConfig(cfg)
+}
+
+// setConfigValue validates value and stores it in the cfg field for key,
+// parsing it according to the field's type.
+func setConfigValue(cfg *Config, field configField, value string) error {
+	key := field.Key
+	if field.validate != nil {
+		if err := field.validate(value); err != nil {
+			return err
 		}
-		cfg.MaxIterations = v
-	case "max_per_hour":
+	}
+
+	target, ok := configValue(cfg, key)
+	if !ok {
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+
+	switch {
+	case target.Kind() == reflect.String:
+		target.SetString(value)
+	case target.Kind() == reflect.Int:
 		v, err := parseInt(value)
 		if err != nil {
-			return fmt.Errorf("parsing max_per_hour: %w", err)
+			return fmt.Errorf("parsing %s: %w", key, err)
 		}
-		cfg.MaxPerHour = v
-	case "max_per_day":
-		v, err := parseInt(value)
-		if err != nil {
-			return fmt.Errorf("parsing max_per_day: %w", err)
+		if field.Minimum != nil && v < *field.Minimum {
+			return fmt.Errorf("%s must be at least %d", key, *field.Minimum)
 		}
-		cfg.MaxPerDay = v
-	case "model":
-		cfg.Model = value
+		target.SetInt(int64(v))
+	case target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.String:
+		target.Set(reflect.ValueOf(splitList(value)))
 	default:
+		parsed := reflect.New(target.Type())
+		if err := json.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+			return fmt.Errorf("parsing %s: %w", key, err)
+		}
+		target.Set(parsed.Elem())
+	}
+	return nil
+}
+
+// ConfigUnset restores a single config key to its default value.
+func ConfigUnset(key string) error {
+	if _, ok := lookupConfigField(key); !ok {
 		return fmt.Errorf("unknown config key: %s", key)
 	}
 
+	cfg := loadConfigFile()
+	defaults := DefaultConfig()
+	target, ok := configValue(&cfg, key)
+	if !ok {
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+	def, _ := configValue(&defaults, key)
+	target.Set(def)
+
 	return SaveConfig(cfg)
 }
 
-func parseInt(value string) (int, error) {
-	var v int
-	if _, err := fmt.Sscanf(value, "%d", &v); err != nil {
-		return 0, err
+// splitList parses a comma-separated config value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return v, nil
+	return items
+}
+
+func parseInt(value string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(value))
 }
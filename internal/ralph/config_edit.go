@@ -0,0 +1,134 @@
+package ralph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// configEditor opens a file for the user to edit and returns once they are
+// done; tests substitute a scripted editor.
+type configEditor interface {
+	Edit(path string) error
+}
+
+// execEditor runs $VISUAL or $EDITOR (falling back to vi) through the shell so
+// values such as "code --wait" work.
+type execEditor struct{}
+
+func (execEditor) Edit(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+var editorRunner configEditor = execEditor{}
+
+const configEditInstructions = `config edit needs an interactive terminal.
+Edit .ralph/config.json (or config.toml / config.yaml) directly or use "opencode-ralph config set KEY VALUE";
+"opencode-ralph config schema" describes the valid keys.`
+
+// ConfigEdit opens the config in the user's editor, validating the result
+// before saving it. Outside a terminal it prints instructions instead.
+func ConfigEdit(out io.Writer) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintln(out, configEditInstructions)
+		return nil
+	}
+	return editConfig(editorRunner, out)
+}
+
+// editConfig edits a temporary copy of the config. An invalid result reopens
+// the editor; saving it again unchanged aborts without touching the config.
+func editConfig(editor configEditor, out io.Writer) error {
+	format := activeConfigFormat()
+	data, err := os.ReadFile(format.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = format.Codec.Marshal(loadConfigFile())
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", format.Path, err)
+	}
+
+	tmp, err := os.CreateTemp("", "ralph-config-*"+filepath.Ext(format.Path))
+	if err != nil {
+		return fmt.Errorf("creating temporary config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temporary config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing temporary config: %w", err)
+	}
+
+	var rejected []byte
+	for {
+		if err := editor.Edit(tmp.Name()); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("reading edited config: %w", err)
+		}
+		cfg, err := parseConfig(edited, format.Codec)
+		if err == nil {
+			if err := SaveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Saved %s\n", format.Path)
+			return nil
+		}
+		if rejected != nil && bytes.Equal(edited, rejected) {
+			return fmt.Errorf("config not saved: %w", err)
+		}
+		rejected = edited
+		fmt.Fprintf(out, "Invalid config: %v\nReopening the editor; save without changes to abort.\n", err)
+	}
+}
+
+// parseConfig decodes a full config document in codec's format, rejecting
+// unknown keys and values that fail validation.
+func parseConfig(data []byte, codec configCodec) (Config, error) {
+	var doc map[string]any
+	if err := codec.Unmarshal(data, &doc); err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+	cfg := DefaultConfig()
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && (fi.Mode()&os.ModeCharDevice) != 0
+}
@@ -0,0 +1,64 @@
+package ralph
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// scriptedEditor replaces the file with the next canned content on each Edit.
+type scriptedEditor struct {
+	edits []string
+	calls int
+}
+
+func (e *scriptedEditor) Edit(path string) error {
+	content := e.edits[e.calls]
+	e.calls++
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+func TestEditConfigRejectsAndReopens(t *testing.T) {
+	withTempCWD(t)
+
+	editor := &scriptedEditor{edits: []string{
+		`{"max_iterations": 0}`,
+		`{"max_iterations": 5, "bogus": true}`,
+		`{"max_iterations": 5, "prompt_arg_style": "flag"}`,
+	}}
+	var out bytes.Buffer
+	if err := editConfig(editor, &out); err != nil {
+		t.Fatalf("editConfig: %v", err)
+	}
+
+	if editor.calls != 3 {
+		t.Fatalf("expected editor reopened until valid (3 calls), got %d", editor.calls)
+	}
+	if got := strings.Count(out.String(), "Invalid config:"); got != 2 {
+		t.Fatalf("expected 2 rejections, got %d:\n%s", got, out.String())
+	}
+	cfg := loadConfigFile()
+	if cfg.MaxIterations != 5 || cfg.PromptArgStyle != "flag" {
+		t.Fatalf("saved config: got %+v", cfg)
+	}
+	if cfg.PromptFile != "PROMPT.md" {
+		t.Fatalf("expected omitted keys to keep defaults, got %q", cfg.PromptFile)
+	}
+}
+
+func TestEditConfigAbortsWhenLeftInvalid(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("max_iterations", "7"); err != nil {
+		t.Fatalf("ConfigSet: %v", err)
+	}
+	editor := &scriptedEditor{edits: []string{`{not json`, `{not json`}}
+	err := editConfig(editor, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "config not saved") {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+	if got := loadConfigFile().MaxIterations; got != 7 {
+		t.Fatalf("expected config untouched, got max_iterations %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// envPrefix prefixes the environment variable for each config key, e.g.
+// RALPH_MAX_ITERATIONS for max_iterations.
+const envPrefix = "RALPH_"
+
+// configEnvVar returns the environment variable that overrides key.
+func configEnvVar(key string) string {
+	return envPrefix + strings.ToUpper(key)
+}
+
+// applyEnvOverrides sets every config key whose RALPH_* variable is set,
+// parsing it like config set. Invalid values are reported together rather
+// than ignored; the valid ones are still applied.
+func applyEnvOverrides(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, field := range configFields() {
+		name := configEnvVar(field.Key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setConfigValue(cfg, field, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: %w", name, value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
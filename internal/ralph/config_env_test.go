@@ -0,0 +1,74 @@
+package ralph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	cfg.MaxIterations = 10
+	cfg.Model = "file/model"
+	cfg.PromptFile = "FILE_PROMPT.md"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+
+	t.Setenv("RALPH_MAX_ITERATIONS", "3")
+	t.Setenv("RALPH_MODEL", "env/model")
+	t.Setenv("RALPH_BLOCKED_DIRS", "/srv, /opt")
+	t.Setenv("RALPH_COMMIT_EACH_ITERATION", "true")
+
+	got, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got.MaxIterations != 3 || got.Model != "env/model" {
+		t.Fatalf("env overrides not applied: %+v", got)
+	}
+	if got.PromptFile != "FILE_PROMPT.md" {
+		t.Fatalf("file value lost: prompt_file = %q", got.PromptFile)
+	}
+	if strings.Join(got.BlockedDirs, "|") != "/srv|/opt" || !got.CommitEachIteration {
+		t.Fatalf("list/bool overrides not applied: %+v", got)
+	}
+}
+
+func TestLoadConfigRejectsInvalidEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   string
+		value string
+		want  string
+	}{
+		{name: "not an integer", env: "RALPH_MAX_ITERATIONS", value: "ten", want: `invalid RALPH_MAX_ITERATIONS="ten"`},
+		{name: "trailing garbage", env: "RALPH_MAX_PER_HOUR", value: "5x", want: `invalid RALPH_MAX_PER_HOUR="5x"`},
+		{name: "below minimum", env: "RALPH_MAX_ITERATIONS", value: "0", want: "max_iterations must be at least 1"},
+		{name: "bad enum", env: "RALPH_PROMPT_ARG_STYLE", value: "stdin", want: "invalid prompt arg style"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			t.Setenv(tt.env, tt.value)
+
+			_, err := LoadConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfigSetDoesNotPersistEnvOverrides(t *testing.T) {
+	withTempCWD(t)
+	t.Setenv("RALPH_MODEL", "env/model")
+
+	if err := ConfigSet("max_iterations", "12"); err != nil {
+		t.Fatalf("ConfigSet: %v", err)
+	}
+	if got := loadConfigFile(); got.Model != "" || got.MaxIterations != 12 {
+		t.Fatalf("config file: got model %q, max_iterations %d", got.Model, got.MaxIterations)
+	}
+}
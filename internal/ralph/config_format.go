@@ -0,0 +1,200 @@
+package ralph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configCodec marshals the config to and from one file format.
+type configCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// configFormat pairs a config file path with the codec for its extension.
+type configFormat struct {
+	Path  string
+	Codec configCodec
+}
+
+// configFormats lists the config files looked for, in order. JSON comes
+// first so existing projects keep using config.json.
+var configFormats = []configFormat{
+	{Path: configFile, Codec: jsonCodec{}},
+	{Path: ralphDir + "/config.toml", Codec: tomlCodec{}},
+	{Path: ralphDir + "/config.yaml", Codec: yamlCodec{}},
+	{Path: ralphDir + "/config.yml", Codec: yamlCodec{}},
+}
+
+// activeConfigFormat returns the first config file that exists, or JSON
+// when there is none yet.
+func activeConfigFormat() configFormat {
+	for _, format := range configFormats {
+		if _, err := os.Stat(format.Path); err == nil {
+			return format
+		}
+	}
+	return configFormats[0]
+}
+
+// codecForPath picks the codec for path by its extension, as listed in
+// configFormats, falling back to JSON.
+func codecForPath(path string) configCodec {
+	ext := filepath.Ext(path)
+	for _, format := range configFormats {
+		if filepath.Ext(format.Path) == ext {
+			return format.Codec
+		}
+	}
+	return jsonCodec{}
+}
+
+// configExists reports whether any config file is present.
+func configExists() bool {
+	for _, format := range configFormats {
+		if _, err := os.Stat(format.Path); !errors.Is(err, os.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// tomlCodec and yamlCodec go through JSON so the Config json tags stay the
+// single source of key names.
+type tomlCodec struct{}
+
+func (tomlCodec) Marshal(v any) ([]byte, error) {
+	doc, err := toJSONMap(v)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := toml.NewEncoder(&b).Encode(doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (tomlCodec) Unmarshal(data []byte, v any) error {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return fromJSONValue(doc, v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v any) ([]byte, error) {
+	doc, err := toJSONMap(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return fromJSONValue(stringKeysValue(doc), v)
+}
+
+// toJSONMap converts v to the generic map its JSON encoding describes,
+// dropping null values, which TOML cannot represent.
+func toJSONMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for key, value := range doc {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		doc[key] = plainNumbers(value)
+	}
+	return doc, nil
+}
+
+// plainNumbers turns JSON numbers into int64 where they are whole, so
+// max_iterations is written as 50 rather than 50.0.
+func plainNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = plainNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = plainNumbers(item)
+		}
+	}
+	return value
+}
+
+// fromJSONValue decodes a generic document into v as if it had been JSON.
+func fromJSONValue(doc any, v any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// stringKeys converts YAML mappings with non-string keys, such as the exit
+// codes in exit_code_policy, to string-keyed maps JSON can encode.
+func stringKeys(value map[string]any) map[string]any {
+	for key, item := range value {
+		value[key] = stringKeysValue(item)
+	}
+	return value
+}
+
+func stringKeysValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return stringKeys(v)
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = stringKeysValue(item)
+		}
+		return converted
+	case []any:
+		for i, item := range v {
+			v[i] = stringKeysValue(item)
+		}
+	}
+	return value
+}
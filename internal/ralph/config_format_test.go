@@ -0,0 +1,116 @@
+package ralph
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// createConfigFile makes path the active config file before SaveConfig.
+func createConfigFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigRoundTripFormats(t *testing.T) {
+	for _, path := range []string{configFile, ".ralph/config.toml", ".ralph/config.yaml", ".ralph/config.yml"} {
+		t.Run(path, func(t *testing.T) {
+			withTempCWD(t)
+			createConfigFile(t, path)
+
+			cfg := DefaultConfig()
+			cfg.PromptFile = "PROMPT.custom.md"
+			cfg.MaxIterations = 123
+			cfg.Model = "ollama/qwen3-coder:30b"
+			cfg.BlockedDirs = []string{"/", "/home"}
+			cfg.ExitCodePolicy["1"] = "retry"
+			cfg.CommitEachIteration = true
+
+			if err := SaveConfig(cfg); err != nil {
+				t.Fatalf("SaveConfig: %v", err)
+			}
+			if got := activeConfigFormat().Path; got != path {
+				t.Fatalf("active config: got %s want %s", got, path)
+			}
+
+			loaded := loadConfigFile()
+			if !reflect.DeepEqual(loaded, cfg) {
+				t.Fatalf("round trip through %s:\ngot  %+v\nwant %+v", path, loaded, cfg)
+			}
+		})
+	}
+}
+
+func TestConfigHandWrittenFormats(t *testing.T) {
+	tests := []struct {
+		path string
+		body string
+	}{
+		{
+			path: ".ralph/config.toml",
+			body: "max_iterations = 7\nmodel = \"anthropic/claude-sonnet-4\"\n\n[exit_code_policy]\n1 = \"retry\"\n",
+		},
+		{
+			path: ".ralph/config.yaml",
+			body: "max_iterations: 7\nmodel: anthropic/claude-sonnet-4\nexit_code_policy:\n  1: retry\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			withTempCWD(t)
+			createConfigFile(t, tt.path)
+			if err := os.WriteFile(tt.path, []byte(tt.body), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.MaxIterations != 7 || cfg.Model != "anthropic/claude-sonnet-4" || cfg.ExitCodePolicy["1"] != "retry" {
+				t.Fatalf("unexpected config: %+v", cfg)
+			}
+			if cfg.PromptFile != "PROMPT.md" {
+				t.Fatalf("expected defaults for unset keys, got prompt_file %q", cfg.PromptFile)
+			}
+		})
+	}
+}
+
+func TestConfigResetKeepsFormat(t *testing.T) {
+	withTempCWD(t)
+	createConfigFile(t, ".ralph/config.toml")
+
+	if err := ConfigSet("max_iterations", "9"); err != nil {
+		t.Fatalf("ConfigSet: %v", err)
+	}
+	if err := ConfigReset(); err != nil {
+		t.Fatalf("ConfigReset: %v", err)
+	}
+
+	data, err := os.ReadFile(".ralph/config.toml")
+	if err != nil {
+		t.Fatalf("read config.toml: %v", err)
+	}
+	if !strings.Contains(string(data), "max_iterations = 50\n") {
+		t.Fatalf("expected TOML defaults, got:\n%s", data)
+	}
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no config.json, stat err: %v", err)
+	}
+}
+
+func TestParseConfigRejectsUnknownKeys(t *testing.T) {
+	if _, err := parseConfig([]byte("max_iteration = 3\n"), tomlCodec{}); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+	if _, err := parseConfig([]byte("max_iterations: 0\n"), yamlCodec{}); err == nil || !strings.Contains(err.Error(), "max_iterations must be at least 1") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
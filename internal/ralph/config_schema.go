@@ -0,0 +1,77 @@
+package ralph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigSchema renders a JSON Schema for .ralph/config.json, generated from
+// configFields() and the Config struct so the two cannot drift.
+func ConfigSchema() (string, error) {
+	defaults := DefaultConfig()
+	properties := map[string]any{}
+	for _, field := range configFields() {
+		value, ok := configValue(&defaults, field.Key)
+		if !ok {
+			return "", fmt.Errorf("config field %s has no Config struct tag", field.Key)
+		}
+		prop := jsonSchemaFor(value.Type())
+		prop["description"] = field.Description
+		if value.Kind() != reflect.Slice || !value.IsNil() {
+			prop["default"] = value.Interface()
+		}
+		if field.Minimum != nil {
+			prop["minimum"] = *field.Minimum
+		}
+		if len(field.Enum) > 0 {
+			prop["enum"] = field.Enum
+		}
+		properties[field.Key] = prop
+	}
+
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "opencode-ralph configuration",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshalling config schema: %w", err)
+	}
+	return string(data), nil
+}
+
+// jsonSchemaFor describes a Go type in JSON Schema terms; struct fields are
+// keyed by their json tags.
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = jsonSchemaFor(t.Field(i).Type)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,57 @@
+package ralph
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigSchemaCoversEveryField(t *testing.T) {
+	out, err := ConfigSchema()
+	if err != nil {
+		t.Fatalf("ConfigSchema: %v", err)
+	}
+
+	var schema struct {
+		Type       string                    `json:"type"`
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatalf("schema does not parse: %v", err)
+	}
+	if schema.Type != "object" {
+		t.Fatalf("type: got %q want object", schema.Type)
+	}
+	for _, field := range configFields() {
+		prop, ok := schema.Properties[field.Key]
+		if !ok {
+			t.Fatalf("schema missing key %q", field.Key)
+		}
+		if prop["description"] != field.Description {
+			t.Fatalf("%s description: got %v", field.Key, prop["description"])
+		}
+	}
+	if len(schema.Properties) != len(configFields()) {
+		t.Fatalf("schema has %d properties, want %d", len(schema.Properties), len(configFields()))
+	}
+
+	maxIterations := schema.Properties["max_iterations"]
+	if maxIterations["type"] != "integer" || maxIterations["default"] != float64(50) || maxIterations["minimum"] != float64(1) {
+		t.Fatalf("max_iterations: got %v", maxIterations)
+	}
+	hints := schema.Properties["escalation_hints"]
+	items, _ := hints["items"].(map[string]any)
+	if hints["type"] != "array" || items["type"] != "object" {
+		t.Fatalf("escalation_hints: got %v", hints)
+	}
+}
+
+func TestConfigSetEnforcesMinimum(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("max_iterations", "0"); err == nil {
+		t.Fatalf("expected error for max_iterations below minimum")
+	}
+	if err := ConfigSet("max_per_hour", "0"); err != nil {
+		t.Fatalf("ConfigSet max_per_hour 0: %v", err)
+	}
+}
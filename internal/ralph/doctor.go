@@ -0,0 +1,147 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrDoctorFailed is returned by Doctor when a required check fails.
+var ErrDoctorFailed = errors.New("doctor found problems that will stop a run")
+
+// doctorCheck is one line of doctor output. A failed Warn check is reported
+// but does not fail doctor.
+type doctorCheck struct {
+	Name   string
+	OK     bool
+	Warn   bool
+	Detail string
+}
+
+// doctorChecks inspects the environment a run depends on.
+func doctorChecks(lookPath func(string) (string, error), detectVersion func() (OpencodeVersion, error)) []doctorCheck {
+	var checks []doctorCheck
+	cfg, configCheck := doctorConfig()
+
+	opencode := doctorCheck{Name: "opencode on PATH", OK: true}
+	binary := opencodeBinary(cfg.OpencodeBin)
+	if path, err := lookPath(binary); err != nil {
+		opencode.OK, opencode.Detail = false, opencodeNotFound(binary, err).Error()
+		checks = append(checks, opencode)
+	} else {
+		opencode.Detail = path
+		version := doctorCheck{Name: "opencode version", OK: true, Warn: true}
+		v, err := checkOpencodeVersion(detectVersion, cfg.MinOpencodeVersion)
+		if err != nil {
+			version.OK, version.Detail = false, err.Error()
+		} else {
+			version.Detail = v.String()
+		}
+		checks = append(checks, opencode, version)
+	}
+	checks = append(checks, configCheck)
+
+	for _, file := range []struct{ name, path string }{
+		{"prompt file", cfg.PromptFile},
+		{"conventions file", cfg.ConventionsFile},
+		{"specs file", cfg.SpecsFile},
+	} {
+		check := doctorCheck{Name: file.name, OK: true, Detail: file.path}
+		var err error
+		if file.name == "specs file" {
+			_, err = readSpecs(file.path)
+		} else {
+			_, err = readFile(file.path)
+		}
+		if err != nil {
+			check.OK, check.Detail = false, err.Error()
+			if errors.Is(err, os.ErrNotExist) {
+				check.Detail += " (run opencode-ralph init to create it)"
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	return append(checks, doctorLock())
+}
+
+// doctorConfig parses the config file and the RALPH_* environment the way
+// a run would, but reports errors a run silently falls back from.
+func doctorConfig() (Config, doctorCheck) {
+	format := activeConfigFormat()
+	check := doctorCheck{Name: "config", OK: true, Detail: format.Path}
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(format.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		check.Detail = format.Path + " not found; using defaults"
+	case err != nil:
+		check.OK, check.Detail = false, fmt.Sprintf("reading %s: %v", format.Path, err)
+		return cfg, check
+	default:
+		if err := format.Codec.Unmarshal(data, &cfg); err != nil {
+			check.OK, check.Detail = false, fmt.Sprintf("parsing %s: %v", format.Path, err)
+			return DefaultConfig(), check
+		}
+	}
+	if err := applyEnvOverrides(&cfg, os.LookupEnv); err != nil {
+		check.OK, check.Detail = false, err.Error()
+		return cfg, check
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		check.OK, check.Detail = false, err.Error()
+	}
+	return cfg, check
+}
+
+// doctorLock reports on the run lock. A stale lock only warns, since the
+// next run removes it.
+func doctorLock() doctorCheck {
+	check := doctorCheck{Name: "lock", OK: true, Warn: true}
+	if _, err := os.Stat(lockFile); errors.Is(err, os.ErrNotExist) {
+		check.Detail = "no run in progress"
+		return check
+	}
+	pid, err := readLockPID(lockFile)
+	switch {
+	case err != nil:
+		check.OK, check.Detail = false, fmt.Sprintf("%s is unreadable; remove it if no run is starting", lockFile)
+	case processAlive(pid):
+		check.Detail = fmt.Sprintf("held by running pid %d", pid)
+	default:
+		check.OK, check.Detail = false, fmt.Sprintf("stale %s from pid %d; the next run removes it, or run opencode-ralph clean", lockFile, pid)
+	}
+	return check
+}
+
+// renderDoctor formats checks one per line with a PASS/WARN/FAIL label.
+func renderDoctor(checks []doctorCheck, useColor bool) string {
+	lines := make([]string, 0, len(checks))
+	for _, check := range checks {
+		label, code := "PASS", ansiGreen
+		switch {
+		case !check.OK && check.Warn:
+			label, code = "WARN", ansiYellow
+		case !check.OK:
+			label, code = "FAIL", ansiRed
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", styleIf(useColor, label, code, ansiBold), check.Name, check.Detail))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Doctor prints the result of each environment check to w and returns
+// ErrDoctorFailed if any required check failed.
+func Doctor(w io.Writer) error {
+	checks := doctorChecks(exec.LookPath, DetectOpencodeVersion)
+	fmt.Fprintln(w, renderDoctor(checks, shouldUseColor(false)))
+	for _, check := range checks {
+		if !check.OK && !check.Warn {
+			return ErrDoctorFailed
+		}
+	}
+	return nil
+}
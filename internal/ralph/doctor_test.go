@@ -0,0 +1,116 @@
+package ralph
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func findCheck(t *testing.T, checks []doctorCheck, name string) doctorCheck {
+	t.Helper()
+	for _, check := range checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no %q check in %+v", name, checks)
+	return doctorCheck{}
+}
+
+func foundOpencode(string) (string, error) { return "/usr/bin/opencode", nil }
+
+func opencodeVersion(v OpencodeVersion) func() (OpencodeVersion, error) {
+	return func() (OpencodeVersion, error) { return v, nil }
+}
+
+func TestDoctorChecksHealthyProject(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	for _, check := range doctorChecks(foundOpencode, opencodeVersion(OpencodeVersion{Minor: 3})) {
+		if !check.OK {
+			t.Fatalf("expected every check to pass, got %+v", check)
+		}
+	}
+}
+
+func TestDoctorChecksFailures(t *testing.T) {
+	withTempCWD(t)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockFile, []byte("424242\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origAlive := processAlive
+	processAlive = func(int) bool { return false }
+	t.Cleanup(func() { processAlive = origAlive })
+
+	checks := doctorChecks(func(string) (string, error) { return "", exec.ErrNotFound }, opencodeVersion(OpencodeVersion{}))
+
+	if check := findCheck(t, checks, "opencode on PATH"); check.OK {
+		t.Fatalf("expected missing opencode to fail: %+v", check)
+	}
+	if check := findCheck(t, checks, "config"); check.OK || !strings.Contains(check.Detail, "parsing") {
+		t.Fatalf("expected config parse failure: %+v", check)
+	}
+	if check := findCheck(t, checks, "specs file"); check.OK || !strings.Contains(check.Detail, "init") {
+		t.Fatalf("expected missing specs to fail with a hint: %+v", check)
+	}
+	if check := findCheck(t, checks, "lock"); check.OK || !check.Warn || !strings.Contains(check.Detail, "stale") {
+		t.Fatalf("expected stale lock warning: %+v", check)
+	}
+}
+
+func TestDoctorConfigValidatesValues(t *testing.T) {
+	withTempCWD(t)
+	t.Setenv("RALPH_MAX_ITERATIONS", "lots")
+
+	_, check := doctorConfig()
+	if check.OK || !strings.Contains(check.Detail, "RALPH_MAX_ITERATIONS") {
+		t.Fatalf("expected env override error, got %+v", check)
+	}
+}
+
+func TestRenderDoctor(t *testing.T) {
+	checks := []doctorCheck{
+		{Name: "a", OK: true, Detail: "fine"},
+		{Name: "b", OK: false, Warn: true, Detail: "meh"},
+		{Name: "c", OK: false, Detail: "broken"},
+	}
+	want := "[PASS] a: fine\n[WARN] b: meh\n[FAIL] c: broken"
+	if got := renderDoctor(checks, false); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDoctorFailsWithoutContextFiles(t *testing.T) {
+	withTempCWD(t)
+	t.Setenv("NO_COLOR", "1")
+
+	var out bytes.Buffer
+	if err := Doctor(&out); !errors.Is(err, ErrDoctorFailed) {
+		t.Fatalf("expected ErrDoctorFailed, got %v", err)
+	}
+	if !strings.Contains(out.String(), "[FAIL] prompt file") {
+		t.Fatalf("expected failed prompt check, got:\n%s", out.String())
+	}
+}
+
+func TestDoctorWarnsOnOldOpencode(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+	t.Setenv("RALPH_MIN_OPENCODE_VERSION", "0.4.0")
+
+	checks := doctorChecks(foundOpencode, opencodeVersion(OpencodeVersion{Minor: 3, Patch: 9}))
+	check := findCheck(t, checks, "opencode version")
+	if check.OK || !check.Warn || !strings.Contains(check.Detail, "0.3.9 is older than min_opencode_version 0.4.0") {
+		t.Fatalf("expected old version warning, got %+v", check)
+	}
+}
@@ -0,0 +1,73 @@
+package ralph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+)
+
+// Actions an exit_code_policy entry can map a non-zero opencode exit code to.
+const (
+	exitActionContinue = "continue"
+	exitActionRetry    = "retry"
+	exitActionFatal    = "fatal"
+)
+
+// defaultExitCodePolicy treats an interrupted opencode (128+SIGINT) as a user
+// abort; every other non-zero exit keeps the run going.
+func defaultExitCodePolicy() map[string]string {
+	return map[string]string{"130": exitActionFatal}
+}
+
+// opencodeExitCode extracts the process exit code from a runner error.
+func opencodeExitCode(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// exitCodeAction looks up how to handle runErr. Errors that carry no exit
+// code, and codes the policy does not list, continue the run.
+func exitCodeAction(policy map[string]string, runErr error) (int, string) {
+	code, ok := opencodeExitCode(runErr)
+	if !ok {
+		return 0, exitActionContinue
+	}
+	if action, ok := policy[strconv.Itoa(code)]; ok {
+		return code, action
+	}
+	return code, exitActionContinue
+}
+
+// validateExitCodePolicy checks an exit_code_policy JSON object.
+func validateExitCodePolicy(value string) error {
+	var policy map[string]string
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return fmt.Errorf("invalid exit_code_policy: %w", err)
+	}
+	return checkExitCodePolicy(policy)
+}
+
+func checkExitCodePolicy(policy map[string]string) error {
+	codes := make([]string, 0, len(policy))
+	for code := range policy {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if n, err := strconv.Atoi(code); err != nil || n <= 0 {
+			return fmt.Errorf("invalid exit_code_policy: %q is not a non-zero exit code", code)
+		}
+		switch policy[code] {
+		case exitActionContinue, exitActionRetry, exitActionFatal:
+		default:
+			return fmt.Errorf("invalid exit_code_policy: action %q for exit code %s (use continue, retry, or fatal)", policy[code], code)
+		}
+	}
+	return nil
+}
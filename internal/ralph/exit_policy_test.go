@@ -0,0 +1,110 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// exitError returns the *exec.ExitError produced by a process exiting with code.
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *exec.ExitError, got %v", err)
+	}
+	return err
+}
+
+func TestExitCodeAction(t *testing.T) {
+	policy := map[string]string{"2": exitActionFatal, "3": exitActionRetry}
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantAction string
+	}{
+		{"fatal code", exitError(t, 2), 2, exitActionFatal},
+		{"retry code", exitError(t, 3), 3, exitActionRetry},
+		{"unlisted code", exitError(t, 1), 1, exitActionContinue},
+		{"wrapped exit error", fmt.Errorf("running opencode: %w", exitError(t, 2)), 2, exitActionFatal},
+		{"no exit code", errors.New("start failed"), 0, exitActionContinue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, action := exitCodeAction(policy, tt.err)
+			if code != tt.wantCode || action != tt.wantAction {
+				t.Fatalf("exitCodeAction = (%d, %q), want (%d, %q)", code, action, tt.wantCode, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestValidateExitCodePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{`{"2": "fatal", "1": "retry", "3": "continue"}`, false},
+		{`{}`, false},
+		{`{"0": "fatal"}`, true},
+		{`{"auth": "fatal"}`, true},
+		{`{"2": "explode"}`, true},
+		{`not json`, true},
+	}
+	for _, tt := range tests {
+		err := validateExitCodePolicy(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("validateExitCodePolicy(%s) err = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExitCodePolicyDuringRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		wantCalls int
+		wantErr   string
+	}{
+		{"fatal stops the run", []int{2, 0, 0}, 1, "exited with code 2"},
+		{"retry reruns the iteration", []int{3, 0, 0}, 3, ""},
+		{"failed retry continues", []int{3, 3, 0}, 3, ""},
+		{"continue keeps going", []int{1, 1}, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			cfg.ExitCodePolicy = map[string]string{"2": exitActionFatal, "3": exitActionRetry}
+			writeContextFiles(t, cfg)
+
+			var calls int
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					code := tt.codes[calls]
+					calls++
+					if code == 0 {
+						return "<ralph_notes>n</ralph_notes>", nil
+					}
+					return "", exitError(t, code)
+				},
+			}
+
+			_, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 2, Quiet: true}, runner)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("runner calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// httpGetter fetches URLs; tests substitute canned responses.
+type httpGetter interface {
+	Get(url string) (*http.Response, error)
+}
+
+var httpClient httpGetter = &http.Client{Timeout: 30 * time.Second}
+
+// maxSpecsFetchBytes caps how much --specs-from will download.
+const maxSpecsFetchBytes = 1 << 20
+
+// fetchSpecs downloads a plaintext or markdown document for seeding the specs file.
+func fetchSpecs(client httpGetter, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("fetching %s: only http and https URLs are supported", url)
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", url, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !isTextSpecsType(mediaType) {
+			return nil, fmt.Errorf("fetching %s: unsupported content type %q (expected plain text or markdown)", url, contentType)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecsFetchBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	if len(body) > maxSpecsFetchBytes {
+		return nil, fmt.Errorf("fetching %s: response exceeds %d bytes", url, maxSpecsFetchBytes)
+	}
+	if strings.TrimSpace(string(body)) == "" {
+		return nil, fmt.Errorf("fetching %s: response is empty", url)
+	}
+	return body, nil
+}
+
+func isTextSpecsType(mediaType string) bool {
+	switch mediaType {
+	case "text/plain", "text/markdown", "text/x-markdown":
+		return true
+	}
+	return false
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so a failure never leaves a partial file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// seedSpecs writes the fetched document as the specs file. It refuses to
+// overwrite an existing specs file.
+func seedSpecs(client httpGetter, url, specsPath string) error {
+	if _, err := os.Stat(specsPath); err == nil {
+		return fmt.Errorf("%s already exists; remove it to seed specs from %s", specsPath, url)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("stat %s: %w", specsPath, err)
+	}
+
+	body, err := fetchSpecs(client, url)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(specsPath, body, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s from %s\n", specsPath, url)
+	return nil
+}
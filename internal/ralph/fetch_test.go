@@ -0,0 +1,121 @@
+package ralph
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeHTTP serves a single canned response.
+type fakeHTTP struct {
+	status      int
+	contentType string
+	body        string
+	err         error
+}
+
+func (f fakeHTTP) Get(url string) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	header := http.Header{}
+	if f.contentType != "" {
+		header.Set("Content-Type", f.contentType)
+	}
+	return &http.Response{
+		StatusCode: f.status,
+		Status:     http.StatusText(f.status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+	}, nil
+}
+
+func useFakeHTTP(t *testing.T, client httpGetter) {
+	t.Helper()
+
+	orig := httpClient
+	httpClient = client
+	t.Cleanup(func() { httpClient = orig })
+}
+
+func TestInitSpecsFromURL(t *testing.T) {
+	withTempCWD(t)
+	useFakeHTTP(t, fakeHTTP{status: http.StatusOK, contentType: "text/plain; charset=utf-8", body: "# Issue\n- [ ] do the thing\n"})
+
+	captureOutput(t, &os.Stdout, func() {
+		if err := InitWithOptions(InitOptions{SpecsFrom: "https://example.com/issue.md"}); err != nil {
+			t.Fatalf("InitWithOptions: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(DefaultConfig().SpecsFile)
+	if err != nil {
+		t.Fatalf("read specs: %v", err)
+	}
+	if string(data) != "# Issue\n- [ ] do the thing\n" {
+		t.Fatalf("specs: got %q", data)
+	}
+}
+
+func TestInitSpecsFromURLFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		client fakeHTTP
+		url    string
+		want   string
+	}{
+		{name: "network", client: fakeHTTP{err: errors.New("connection refused")}, url: "https://example.com/x", want: "connection refused"},
+		{name: "status", client: fakeHTTP{status: http.StatusNotFound}, url: "https://example.com/x", want: "unexpected status"},
+		{name: "html", client: fakeHTTP{status: http.StatusOK, contentType: "text/html", body: "<html>"}, url: "https://example.com/x", want: "unsupported content type"},
+		{name: "too large", client: fakeHTTP{status: http.StatusOK, body: strings.Repeat("x", maxSpecsFetchBytes+1)}, url: "https://example.com/x", want: "exceeds"},
+		{name: "empty", client: fakeHTTP{status: http.StatusOK, body: "  \n"}, url: "https://example.com/x", want: "empty"},
+		{name: "scheme", client: fakeHTTP{status: http.StatusOK, body: "x"}, url: "file:///etc/passwd", want: "only http and https"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			useFakeHTTP(t, tt.client)
+
+			var err error
+			captureOutput(t, &os.Stdout, func() {
+				err = InitWithOptions(InitOptions{SpecsFrom: tt.url})
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, statErr := os.Stat(DefaultConfig().SpecsFile); !errors.Is(statErr, os.ErrNotExist) {
+				t.Fatalf("expected no specs file after failure, stat err %v", statErr)
+			}
+			entries, _ := os.ReadDir(".")
+			for _, entry := range entries {
+				if strings.Contains(entry.Name(), ".tmp-") {
+					t.Fatalf("temporary file left behind: %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestInitSpecsFromRefusesToOverwrite(t *testing.T) {
+	withTempCWD(t)
+	useFakeHTTP(t, fakeHTTP{status: http.StatusOK, body: "new"})
+
+	specs := DefaultConfig().SpecsFile
+	if err := os.WriteFile(specs, []byte("existing"), 0o644); err != nil {
+		t.Fatalf("write specs: %v", err)
+	}
+	var err error
+	captureOutput(t, &os.Stdout, func() {
+		err = InitWithOptions(InitOptions{SpecsFrom: "https://example.com/x"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+	if data, _ := os.ReadFile(specs); string(data) != "existing" {
+		t.Fatalf("specs overwritten: %q", data)
+	}
+}
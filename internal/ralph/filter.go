@@ -0,0 +1,26 @@
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// applyOutputFilter pipes output through command (run via sh -c) and returns
+// what the command writes to stdout.
+func applyOutputFilter(command, output string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(output)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("running output filter %q: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("running output filter %q: %w", command, err)
+	}
+	return stdout.String(), nil
+}
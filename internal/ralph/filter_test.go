@@ -0,0 +1,88 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestApplyOutputFilter(t *testing.T) {
+	got, err := applyOutputFilter("tr x y", "xxx")
+	if err != nil {
+		t.Fatalf("applyOutputFilter: %v", err)
+	}
+	if got != "yyy" {
+		t.Fatalf("got %q want %q", got, "yyy")
+	}
+
+	if _, err := applyOutputFilter("echo boom >&2; exit 3", "xxx"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected filter failure mentioning stderr, got %v", err)
+	}
+}
+
+func TestOutputFilterFeedsExtraction(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<ralph_notes>raw</ralph_notes><ralph_status>PENDING</ralph_status>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, OutputFilter: "sed -e s/raw/filtered/ -e s/PENDING/COMPLETE/", OutputDir: "out"}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	notes, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if !strings.Contains(string(notes), "filtered") || strings.Contains(string(notes), "raw") {
+		t.Fatalf("expected filtered notes, got %q", notes)
+	}
+	if _, err := os.Stat(iterationArtifactPath("out", result.RunID, 2, "log")); !os.IsNotExist(err) {
+		t.Fatalf("expected filtered COMPLETE to stop after one iteration")
+	}
+	log, err := os.ReadFile(iterationArtifactPath("out", result.RunID, 1, "log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !strings.Contains(string(log), "raw") {
+		t.Fatalf("expected saved log to keep raw output, got %q", log)
+	}
+}
+
+func TestOutputFilterFailureFallsBackToRaw(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "<ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, OutputFilter: "exit 1"}
+	var runErr error
+	warning := captureOutput(t, &os.Stderr, func() {
+		_, runErr = runIterationsWithRunner(cfg, opts, runner)
+	})
+	if runErr != nil {
+		t.Fatalf("runIterationsWithRunner: %v", runErr)
+	}
+	if !strings.Contains(warning, "using unfiltered output") {
+		t.Fatalf("expected fallback warning, got %q", warning)
+	}
+	if calls != 1 {
+		t.Fatalf("runner calls: got %d want %d (raw COMPLETE should stop the run)", calls, 1)
+	}
+}
@@ -0,0 +1,206 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitRunner runs git subcommands; tests substitute canned output.
+type gitRunner interface {
+	Run(args ...string) (string, error)
+}
+
+type execGit struct{}
+
+func (execGit) Run(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+var git gitRunner = execGit{}
+
+// gitSnapshot is the repository state captured at the start of a run.
+type gitSnapshot struct {
+	head   string
+	status map[string]string
+	// prefix is the working directory relative to the repository root, as
+	// git prints it ("sub/dir/"), so local paths can be matched against git's.
+	prefix string
+}
+
+// takeGitSnapshot records HEAD and the working tree status. ok is false
+// outside a git repository.
+func takeGitSnapshot(g gitRunner) (snap gitSnapshot, ok bool) {
+	if _, err := g.Run("rev-parse", "--is-inside-work-tree"); err != nil {
+		return gitSnapshot{}, false
+	}
+	head, err := g.Run("rev-parse", "HEAD")
+	if err == nil {
+		snap.head = strings.TrimSpace(head)
+	}
+	if prefix, err := g.Run("rev-parse", "--show-prefix"); err == nil {
+		snap.prefix = strings.TrimSpace(prefix)
+	}
+	porcelain, err := g.Run("status", "--porcelain")
+	if err != nil {
+		return gitSnapshot{}, false
+	}
+	snap.status = parsePorcelain(porcelain)
+	return snap, true
+}
+
+// changedFiles lists files modified since start, including changes the agent
+// has already committed, as "<status> <path>" sorted by path. Files under
+// any of the local paths in exclude, such as ralph's own bookkeeping, are
+// left out.
+func changedFiles(g gitRunner, start gitSnapshot, exclude []string) ([]string, error) {
+	changes := map[string]string{}
+	if start.head != "" {
+		diff, err := g.Run("diff", "--name-status", start.head, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		for path, status := range parseNameStatus(diff) {
+			changes[path] = status
+		}
+	}
+	porcelain, err := g.Run("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	for path, status := range parsePorcelain(porcelain) {
+		if start.status[path] != status {
+			changes[path] = status
+		}
+	}
+
+	excluded := repoPaths(start.prefix, exclude)
+	paths := make([]string, 0, len(changes))
+	for path := range changes {
+		if !underAny(path, excluded) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, changes[path]+" "+path)
+	}
+	return files, nil
+}
+
+// repoPaths converts local paths, relative to the working directory or
+// absolute, into repository-relative slash paths like git prints. Paths
+// outside the working directory are dropped, since they cannot be resolved
+// against the repository without asking git.
+func repoPaths(prefix string, paths []string) []string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(cwd, path)
+			if err != nil {
+				continue
+			}
+			path = rel
+		}
+		path = filepath.ToSlash(filepath.Clean(path))
+		if path == ".." || strings.HasPrefix(path, "../") {
+			continue
+		}
+		out = append(out, pathpkg.Join(prefix, path))
+	}
+	return out
+}
+
+// underAny reports whether path is one of dirs or inside one of them.
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePorcelain maps paths in `git status --porcelain` output to a single
+// status letter (M, A, D, R); untracked files count as added.
+func parsePorcelain(out string) map[string]string {
+	files := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		code, path := line[:2], line[3:]
+		if i := strings.Index(path, " -> "); i >= 0 {
+			path = path[i+4:]
+		}
+		status := strings.TrimSpace(code)
+		if status == "" {
+			continue
+		}
+		if status == "??" {
+			status = "A"
+		}
+		files[path] = status[:1]
+	}
+	return files
+}
+
+// parseNameStatus maps paths in `git diff --name-status` output to their status letter.
+func parseNameStatus(out string) map[string]string {
+	files := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		files[fields[len(fields)-1]] = fields[0][:1]
+	}
+	return files
+}
+
+var (
+	errNotGitRepo = errors.New("not a git repository")
+	errNoChanges  = errors.New("no changes to commit")
+)
+
+// commitIteration stages the working tree, leaving out ralph's own runtime
+// files in .ralph/, and commits it as "ralph: iteration N" with the
+// iteration's notes as the body.
+func commitIteration(g gitRunner, iteration int, notes string) error {
+	if _, err := g.Run("rev-parse", "--is-inside-work-tree"); err != nil {
+		return errNotGitRepo
+	}
+	if _, err := g.Run("add", "-A", "--", ".", ":(exclude)"+ralphDir); err != nil {
+		return err
+	}
+	staged, err := g.Run("diff", "--cached", "--name-only")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(staged) == "" {
+		return errNoChanges
+	}
+	args := []string{"commit", "-m", fmt.Sprintf("ralph: iteration %d", iteration)}
+	if notes = strings.TrimSpace(notes); notes != "" {
+		args = append(args, "-m", notes)
+	}
+	_, err = g.Run(args...)
+	return err
+}
@@ -0,0 +1,225 @@
+package ralph
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeGit answers git commands from a map keyed by the joined arguments.
+type fakeGit struct {
+	responses map[string]string
+}
+
+func (g *fakeGit) Run(args ...string) (string, error) {
+	out, ok := g.responses[strings.Join(args, " ")]
+	if !ok {
+		return "", errors.New("fatal: not a git repository")
+	}
+	return out, nil
+}
+
+func useFakeGit(t *testing.T, g gitRunner) {
+	t.Helper()
+
+	orig := git
+	git = g
+	t.Cleanup(func() { git = orig })
+}
+
+func TestParsePorcelain(t *testing.T) {
+	got := parsePorcelain(" M a.go\nA  b.go\n D c.go\n?? d.go\nR  old.go -> new.go\nMM e.go\n")
+	want := map[string]string{"a.go": "M", "b.go": "A", "c.go": "D", "d.go": "A", "new.go": "R", "e.go": "M"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for path, status := range want {
+		if got[path] != status {
+			t.Fatalf("%s: got %q want %q", path, got[path], status)
+		}
+	}
+}
+
+func TestChangedFilesCombinesCommitsAndWorkingTree(t *testing.T) {
+	g := &fakeGit{responses: map[string]string{
+		"rev-parse --is-inside-work-tree": "true\n",
+		"rev-parse HEAD":                  "abc123\n",
+		"status --porcelain":              " M preexisting.go\n",
+	}}
+
+	start, ok := takeGitSnapshot(g)
+	if !ok {
+		t.Fatalf("expected snapshot inside repo")
+	}
+
+	g.responses["diff --name-status abc123 HEAD"] = "M\tcommitted.go\nA\tnew.go\nR100\told.go\trenamed.go\n"
+	g.responses["status --porcelain"] = " M preexisting.go\n D deleted.go\n?? untracked.go\n"
+
+	got, err := changedFiles(g, start, nil)
+	if err != nil {
+		t.Fatalf("changedFiles: %v", err)
+	}
+	want := []string{"M committed.go", "D deleted.go", "A new.go", "R renamed.go", "A untracked.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestChangedFilesExcludesRalphFiles(t *testing.T) {
+	withTempCWD(t)
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &fakeGit{responses: map[string]string{
+		"rev-parse --is-inside-work-tree": "true\n",
+		"rev-parse HEAD":                  "abc123\n",
+		"rev-parse --show-prefix":         "sub/\n",
+		"status --porcelain":              "",
+	}}
+	start, ok := takeGitSnapshot(g)
+	if !ok {
+		t.Fatalf("expected snapshot inside repo")
+	}
+
+	g.responses["diff --name-status abc123 HEAD"] = "M\tsub/.ralph/notes.md\nM\tsub/main.go\n"
+	g.responses["status --porcelain"] = "?? sub/.ralph/state.json\n?? sub/runs.log\n?? sub/logs/iteration-1.log\n?? sub/logs.go\n?? .ralph/other.json\n"
+
+	exclude := []string{ralphDir, "runs.log", filepath.Join(dir, "logs")}
+	got, err := changedFiles(g, start, exclude)
+	if err != nil {
+		t.Fatalf("changedFiles: %v", err)
+	}
+	want := []string{"A .ralph/other.json", "A sub/logs.go", "M sub/main.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestTakeGitSnapshotOutsideRepo(t *testing.T) {
+	if _, ok := takeGitSnapshot(&fakeGit{}); ok {
+		t.Fatalf("expected no snapshot outside a git repository")
+	}
+}
+
+func TestSummaryListsChangedFiles(t *testing.T) {
+	withTempCWD(t)
+
+	g := &fakeGit{responses: map[string]string{
+		"rev-parse --is-inside-work-tree": "true\n",
+		"rev-parse HEAD":                  "abc123\n",
+		"status --porcelain":              "",
+		"diff --name-status abc123 HEAD":  "M\tmain.go\n",
+	}}
+	useFakeGit(t, g)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1}, completeRunner()); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Files changed: 1\n  M main.go\n") {
+		t.Fatalf("expected changed files in summary, got %q", out)
+	}
+}
+
+// recordingGit is a fakeGit that also records every command it receives.
+type recordingGit struct {
+	fakeGit
+	calls []string
+}
+
+func (g *recordingGit) Run(args ...string) (string, error) {
+	g.calls = append(g.calls, strings.Join(args, "\x00"))
+	return g.fakeGit.Run(args...)
+}
+
+func TestCommitIteration(t *testing.T) {
+	repo := map[string]string{
+		"rev-parse --is-inside-work-tree": "true\n",
+		"add -A -- . :(exclude).ralph":    "",
+	}
+	tests := []struct {
+		name       string
+		staged     string
+		commitFail bool
+		notRepo    bool
+		wantErr    error
+		wantCommit bool
+	}{
+		{name: "commits staged changes", staged: "main.go\n", wantCommit: true},
+		{name: "nothing staged", staged: "", wantErr: errNoChanges},
+		{name: "not a repository", notRepo: true, wantErr: errNotGitRepo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]string{
+				"diff --cached --name-only":                   tt.staged,
+				"commit -m ralph: iteration 3 -m fixed a bug": "",
+			}
+			if !tt.notRepo {
+				for k, v := range repo {
+					responses[k] = v
+				}
+			}
+			g := &recordingGit{fakeGit: fakeGit{responses: responses}}
+
+			err := commitIteration(g, 3, "  fixed a bug\n")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("commitIteration err = %v, want %v", err, tt.wantErr)
+			}
+			committed := false
+			for _, call := range g.calls {
+				if strings.HasPrefix(call, "commit\x00") {
+					committed = true
+					if call != "commit\x00-m\x00ralph: iteration 3\x00-m\x00fixed a bug" {
+						t.Fatalf("unexpected commit command %q", call)
+					}
+				}
+			}
+			if committed != tt.wantCommit {
+				t.Fatalf("committed = %v, want %v (calls %q)", committed, tt.wantCommit, g.calls)
+			}
+		})
+	}
+}
+
+func TestCommitFailureDoesNotAbortRun(t *testing.T) {
+	withTempCWD(t)
+	g := &recordingGit{fakeGit: fakeGit{responses: map[string]string{
+		"rev-parse --is-inside-work-tree": "true\n",
+		"add -A -- . :(exclude).ralph":    "",
+		"diff --cached --name-only":       "main.go\n",
+		// No response for commit, so every commit fails.
+	}}}
+	useFakeGit(t, g)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	stderr := captureOutput(t, &os.Stderr, func() {
+		if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 2, Quiet: true, Commit: true}, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+	if calls != 2 {
+		t.Fatalf("expected the run to continue after a failed commit, got %d calls", calls)
+	}
+	if got := strings.Count(stderr, "Warning: could not commit iteration"); got != 2 {
+		t.Fatalf("expected a warning per failed commit, got %d:\n%s", got, stderr)
+	}
+}
@@ -0,0 +1,46 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkSafeCWD refuses to run in the filesystem root, the user's home
+// directory, or any configured blocked directory, since ralph writes .ralph/
+// there and the agent may modify files.
+func checkSafeCWD(cwd, home string, blocked []string) error {
+	cwd = canonicalDir(cwd)
+	reasons := map[string]string{canonicalDir(string(filepath.Separator)): "the filesystem root"}
+	if home != "" {
+		reasons[canonicalDir(home)] = "your home directory"
+	}
+	for _, dir := range blocked {
+		if dir != "" {
+			reasons[canonicalDir(dir)] = "listed in blocked_dirs"
+		}
+	}
+	if reason, ok := reasons[cwd]; ok {
+		return fmt.Errorf("refusing to run in %s (%s): opencode-ralph writes .ralph/ here and the agent may modify any file under it; cd into a project directory or pass --allow-unsafe-cwd", cwd, reason)
+	}
+	return nil
+}
+
+func canonicalDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return filepath.Clean(dir)
+}
+
+func checkSafeWorkingDir(blocked []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+	return checkSafeCWD(cwd, home, blocked)
+}
@@ -0,0 +1,72 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSafeCWD(t *testing.T) {
+	home := t.TempDir()
+	project := filepath.Join(home, "project")
+	blocked := filepath.Join(home, "shared")
+	for _, dir := range []string{project, blocked} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cwd     string
+		wantErr string
+	}{
+		{name: "root", cwd: "/", wantErr: "filesystem root"},
+		{name: "home", cwd: home, wantErr: "home directory"},
+		{name: "home trailing slash", cwd: home + "/", wantErr: "home directory"},
+		{name: "blocklisted", cwd: blocked, wantErr: "blocked_dirs"},
+		{name: "project", cwd: project},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSafeCWD(tt.cwd, home, []string{blocked})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected %s to be allowed, got %v", tt.cwd, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "--allow-unsafe-cwd") {
+				t.Fatalf("expected refusal mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunWithOptionsRefusesBlockedCWD(t *testing.T) {
+	withTempCWD(t)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := ConfigSet("blocked_dirs", cwd); err != nil {
+		t.Fatalf("ConfigSet blocked_dirs: %v", err)
+	}
+
+	err = RunWithOptions(RunOptions{Quiet: true}, 1, 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "refusing to run") {
+		t.Fatalf("expected refusal, got %v", err)
+	}
+	if _, statErr := os.Stat(lockFile); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no lock to be taken when refusing")
+	}
+
+	// With the override the run proceeds as far as reading the missing prompt file.
+	err = RunWithOptions(RunOptions{Quiet: true, AllowUnsafeCWD: true}, 1, 0, 0)
+	if err == nil || strings.Contains(err.Error(), "refusing to run") {
+		t.Fatalf("expected override to bypass the guard, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package ralph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notesTimestampLayout is the timestamp format of notes.md headers.
+const notesTimestampLayout = "2006-01-02 15:04:05"
+
+// NotesEntry is one iteration's notes as recorded in notes.md.
+type NotesEntry struct {
+	Iteration int       `json:"iteration"`
+	Timestamp time.Time `json:"timestamp"`
+	// RunID is the run the entry belongs to, from the nearest preceding
+	// "# Run" header; empty for notes written before run headers existed.
+	RunID string `json:"run_id,omitempty"`
+	Body  string `json:"body"`
+}
+
+// notesHeader matches the headers written by appendRunHeader and appendNotes.
+var notesHeader = regexp.MustCompile(`(?m)^(?:# Run (\S+)|## Iteration (\d+)) \((\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\)$`)
+
+// parseNotes splits notes.md content into iteration entries, oldest first.
+// Timestamps are read in the local zone, as appendNotes writes them.
+func parseNotes(notes string) []NotesEntry {
+	notes = strings.ReplaceAll(notes, "\r\n", "\n")
+	headers := notesHeader.FindAllStringSubmatchIndex(notes, -1)
+
+	var entries []NotesEntry
+	runID := ""
+	for i, h := range headers {
+		end := len(notes)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		if h[2] >= 0 {
+			runID = notes[h[2]:h[3]]
+			continue
+		}
+		iteration, err := strconv.Atoi(notes[h[4]:h[5]])
+		if err != nil {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(notesTimestampLayout, notes[h[6]:h[7]], time.Local)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, NotesEntry{
+			Iteration: iteration,
+			Timestamp: timestamp,
+			RunID:     runID,
+			Body:      strings.Trim(notes[h[1]:end], "\n"),
+		})
+	}
+	return entries
+}
+
+// renderHistory lists entries one per line with the first line of each body.
+func renderHistory(entries []NotesEntry) string {
+	if len(entries) == 0 {
+		return "No iterations recorded in " + notesFile
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		summary, _, _ := strings.Cut(entry.Body, "\n")
+		if runes := []rune(summary); len(runes) > 80 {
+			summary = string(runes[:77]) + "..."
+		}
+		lines = append(lines, fmt.Sprintf("#%-4d %s  %s", entry.Iteration, entry.Timestamp.Format(notesTimestampLayout), summary))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// History renders the last limit iterations recorded in notes.md (all when
+// limit <= 0), as text or JSON.
+func History(limit int, asJSON bool) (string, error) {
+	if limit < 0 {
+		return "", fmt.Errorf("invalid --limit: %d (must be 0 or greater)", limit)
+	}
+	data, err := os.ReadFile(notesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("reading notes file: %w", err)
+	}
+	entries := parseNotes(string(data))
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	if !asJSON {
+		return renderHistory(entries), nil
+	}
+	if entries == nil {
+		entries = []NotesEntry{}
+	}
+	out, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshalling history: %w", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,109 @@
+package ralph
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNotesReadsAppendNotesFormat(t *testing.T) {
+	withTempCWD(t)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Truncate(time.Second)
+	if err := appendRunHeader("run-1", map[string]string{"ticket": "ABC-1"}, notesLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendNotes("Added the parser.\nTests pass.", 1, notesLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendNotes("## Not a header\nFixed lint.", 2, notesLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendRunHeader("run-2", nil, notesLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendNotes("Started docs.", 3, notesLimit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := parseNotes(string(data))
+
+	want := []NotesEntry{
+		{Iteration: 1, RunID: "run-1", Body: "Added the parser.\nTests pass."},
+		{Iteration: 2, RunID: "run-1", Body: "## Not a header\nFixed lint."},
+		{Iteration: 3, RunID: "run-2", Body: "Started docs."},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries: got %d want %d\n%+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Iteration != want[i].Iteration || entry.RunID != want[i].RunID || entry.Body != want[i].Body {
+			t.Fatalf("entry %d: got %+v want %+v", i, entry, want[i])
+		}
+		if entry.Timestamp.Before(before) || entry.Timestamp.After(time.Now()) {
+			t.Fatalf("entry %d: timestamp %s not around now", i, entry.Timestamp)
+		}
+	}
+}
+
+func TestParseNotesWithoutRunHeaders(t *testing.T) {
+	notes := "# Preamble\n\n## Iteration 7 (2026-01-02 03:04:05)\nold notes\n"
+	entries := parseNotes(notes)
+	if len(entries) != 1 {
+		t.Fatalf("entries: got %+v", entries)
+	}
+	got := entries[0]
+	if got.Iteration != 7 || got.RunID != "" || got.Body != "old notes" || got.Timestamp.Format(notesTimestampLayout) != "2026-01-02 03:04:05" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestHistoryLimitAndJSON(t *testing.T) {
+	withTempCWD(t)
+	writeNotes(t, "\n## Iteration 1 (2026-01-02 03:04:05)\nfirst\n"+
+		"\n## Iteration 2 (2026-01-02 03:05:05)\nsecond\nmore detail\n"+
+		"\n## Iteration 3 (2026-01-02 03:06:05)\nthird\n")
+
+	out, err := History(2, false)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := "#2    2026-01-02 03:05:05  second\n#3    2026-01-02 03:06:05  third"
+	if out != want {
+		t.Fatalf("text history:\ngot  %q\nwant %q", out, want)
+	}
+
+	out, err = History(1, true)
+	if err != nil {
+		t.Fatalf("History json: %v", err)
+	}
+	var entries []NotesEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, out)
+	}
+	if len(entries) != 1 || entries[0].Iteration != 3 || entries[0].Body != "third" {
+		t.Fatalf("json history: got %+v", entries)
+	}
+}
+
+func TestHistoryWithoutNotes(t *testing.T) {
+	withTempCWD(t)
+
+	out, err := History(0, false)
+	if err != nil || !strings.HasPrefix(out, "No iterations recorded") {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	out, err = History(0, true)
+	if err != nil || out != "[]" {
+		t.Fatalf("json: got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,67 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+)
+
+// runHook runs command via sh -c with env added to the environment. The
+// hook's output goes straight to the terminal.
+func runHook(command string, env map[string]string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running hook %q: %w", command, err)
+	}
+	return nil
+}
+
+// finishHook returns the --on-complete or --on-failure command for a run
+// that ended with status, or "" when neither applies.
+func finishHook(opts RunOptions, status string) string {
+	switch status {
+	case "complete":
+		return opts.OnComplete
+	case "failed", "max_iterations":
+		return opts.OnFailure
+	default:
+		return ""
+	}
+}
+
+// runFinishHook runs the finish hook for status with RALPH_STATUS added to
+// env. A failing hook only warns; the run's own outcome stands.
+func runFinishHook(opts RunOptions, status string, env map[string]string) {
+	command := finishHook(opts, status)
+	if command == "" || opts.DryRun {
+		return
+	}
+	hookEnv := map[string]string{"RALPH_STATUS": status}
+	for key, value := range env {
+		hookEnv[key] = value
+	}
+	if err := runHook(command, hookEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// extendedLimit returns the iteration limit after one --auto-extend step,
+// never exceeding limitCap. It returns current when no extension is possible.
+func extendedLimit(current, step, limitCap int) int {
+	if step <= 0 || current >= limitCap {
+		return current
+	}
+	return min(current+step, limitCap)
+}
@@ -0,0 +1,148 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtendedLimit(t *testing.T) {
+	tests := []struct {
+		current, step, limitCap, want int
+	}{
+		{current: 3, step: 0, limitCap: 10, want: 3},
+		{current: 3, step: 2, limitCap: 10, want: 5},
+		{current: 9, step: 2, limitCap: 10, want: 10},
+		{current: 10, step: 2, limitCap: 10, want: 10},
+		{current: 12, step: 2, limitCap: 10, want: 12},
+	}
+	for _, tt := range tests {
+		if got := extendedLimit(tt.current, tt.step, tt.limitCap); got != tt.want {
+			t.Fatalf("extendedLimit(%d, %d, %d): got %d want %d", tt.current, tt.step, tt.limitCap, got, tt.want)
+		}
+	}
+}
+
+func TestAutoExtendRespectsCapAndRunsHook(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "", nil
+		},
+	}
+
+	opts := RunOptions{
+		MaxIterations:      2,
+		Quiet:              true,
+		AutoExtend:         2,
+		AutoExtendCap:      5,
+		OnMaxIterationsCmd: `echo "$RALPH_STATUS $RALPH_ITERATIONS $RALPH_MAX_ITERATIONS" > hook.txt`,
+	}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if calls != 5 {
+		t.Fatalf("expected 5 iterations (2, extended to 4, capped at 5), got %d", calls)
+	}
+	data, err := os.ReadFile("hook.txt")
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "max_iterations 5 5" {
+		t.Fatalf("hook env: got %q", got)
+	}
+}
+
+func TestAutoExtendStopsOnComplete(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			if calls == 3 {
+				return "<ralph_status>COMPLETE</ralph_status>", nil
+			}
+			return "", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, AutoExtend: 2, AutoExtendCap: 10, OnMaxIterationsCmd: "touch hook.txt"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected extension then completion after 3 iterations, got %d", calls)
+	}
+	if _, err := os.Stat("hook.txt"); err == nil {
+		t.Fatalf("hook should not run when the run completes")
+	}
+}
+
+func TestFinishHooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "complete", output: "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", want: "complete complete 1"},
+		{name: "max iterations", output: "<ralph_notes>n</ralph_notes>", want: "failure max_iterations 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) { return tt.output, nil },
+			}
+			opts := RunOptions{
+				MaxIterations: 2,
+				Quiet:         true,
+				OnComplete:    `echo "complete $RALPH_STATUS $RALPH_ITERATIONS" > hook.txt`,
+				OnFailure:     `echo "failure $RALPH_STATUS $RALPH_ITERATIONS" > hook.txt`,
+			}
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+			data, err := os.ReadFile("hook.txt")
+			if err != nil {
+				t.Fatalf("hook did not run: %v", err)
+			}
+			if got := strings.TrimSpace(string(data)); got != tt.want {
+				t.Fatalf("hook: got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailingFinishHookKeepsRunResult(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, OnComplete: "exit 7"}
+	var runErr error
+	stderr := captureOutput(t, &os.Stderr, func() {
+		_, runErr = runIterationsWithRunner(cfg, opts, completeRunner())
+	})
+	if runErr != nil {
+		t.Fatalf("a failing hook must not fail the run: %v", runErr)
+	}
+	if !strings.Contains(stderr, `Warning: running hook "exit 7"`) {
+		t.Fatalf("expected hook warning, got %q", stderr)
+	}
+}
@@ -0,0 +1,62 @@
+package ralph
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// historyLogFile is an append-only record of every iteration that ran
+// opencode. Unlike the timestamps in state, which only cover the past day
+// for rate limiting, it is never pruned, so stats can span a project's life.
+const historyLogFile = ".ralph/history.jsonl"
+
+// IterationRecord is one line of .ralph/history.jsonl.
+type IterationRecord struct {
+	Timestamp       time.Time `json:"timestamp"`
+	RunID           string    `json:"run_id"`
+	Iteration       int       `json:"iteration"`
+	Status          string    `json:"status"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Model           string    `json:"model,omitempty"`
+	Tokens          int       `json:"tokens,omitempty"`
+}
+
+// appendIterationRecord adds record to the history log.
+func appendIterationRecord(record IterationRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling history record: %w", err)
+	}
+	return appendLineLocked(historyLogFile, string(data))
+}
+
+// loadIterationHistory reads the history log. A missing file yields no
+// records; unparseable lines, such as one cut short by a crash, are skipped.
+func loadIterationHistory() ([]IterationRecord, error) {
+	f, err := os.Open(historyLogFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", historyLogFile, err)
+	}
+	defer f.Close()
+
+	var records []IterationRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record IterationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Timestamp.IsZero() {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", historyLogFile, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,124 @@
+package ralph
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIterationsAppendToHistoryLog(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	calls := 0
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			clock.current = clock.current.Add(5 * time.Second)
+			if calls == 2 {
+				return "<ralph_status>COMPLETE</ralph_status>", nil
+			}
+			return "", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 3, Quiet: true, Model: "test/model"}
+	for range 2 {
+		calls = 0
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	}
+
+	records, err := loadIterationHistory()
+	if err != nil {
+		t.Fatalf("loadIterationHistory: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("records: got %d want 4: %+v", len(records), records)
+	}
+	for i, record := range records {
+		wantStatus := "incomplete"
+		if i%2 == 1 {
+			wantStatus = "complete"
+		}
+		if record.Iteration != i+1 || record.Status != wantStatus || record.Model != "test/model" || record.DurationSeconds != 5 || record.RunID == "" {
+			t.Fatalf("record %d: got %+v", i, record)
+		}
+	}
+	if records[0].RunID == records[2].RunID {
+		t.Fatalf("expected each run to have its own run ID")
+	}
+}
+
+func TestIterationsThatEndTheRunAreRecorded(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy map[string]string
+		opts   RunOptions
+		output string
+		code   int
+	}{
+		{name: "strict notes", opts: RunOptions{StrictNotes: true}, output: "no notes here"},
+		{name: "fatal exit code", policy: map[string]string{"2": exitActionFatal}, code: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			cfg.ExitCodePolicy = tt.policy
+			writeContextFiles(t, cfg)
+
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					if tt.code != 0 {
+						return tt.output, exitError(t, tt.code)
+					}
+					return tt.output, nil
+				},
+			}
+			opts := tt.opts
+			opts.MaxIterations, opts.Quiet = 3, true
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err == nil {
+				t.Fatalf("expected the run to end with an error")
+			}
+
+			records, err := loadIterationHistory()
+			if err != nil {
+				t.Fatalf("loadIterationHistory: %v", err)
+			}
+			if len(records) != 1 || records[0].Iteration != 1 || records[0].Status != "failed" {
+				t.Fatalf("expected one failed record, got %+v", records)
+			}
+		})
+	}
+}
+
+func TestLoadIterationHistorySkipsDamagedLines(t *testing.T) {
+	withTempCWD(t)
+
+	if records, err := loadIterationHistory(); err != nil || records != nil {
+		t.Fatalf("missing log: got %v, %v", records, err)
+	}
+
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	log := `{"timestamp":"2026-01-01T12:00:00Z","run_id":"r1","iteration":1,"status":"incomplete","duration_seconds":3}
+not json
+{}
+{"timestamp":"2026-01-01T13:00:00Z","run_id":"r1","iteration":2,"sta`
+	if err := os.WriteFile(historyLogFile, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	records, err := loadIterationHistory()
+	if err != nil {
+		t.Fatalf("loadIterationHistory: %v", err)
+	}
+	if len(records) != 1 || records[0].Iteration != 1 {
+		t.Fatalf("expected only the intact record, got %+v", records)
+	}
+}
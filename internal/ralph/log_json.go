@@ -0,0 +1,80 @@
+package ralph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// --log-format values: the default colored human output, or one JSON object
+// per line for log collectors.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+func validateLogFormat(format string) error {
+	switch format {
+	case "", logFormatText, logFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid --log-format value: %s (expected text or json)", format)
+	}
+}
+
+// iterationEvent is the --log-format json record written after each
+// iteration that ran opencode.
+type iterationEvent struct {
+	Type             string  `json:"type"`
+	RunID            string  `json:"run_id"`
+	Iteration        int     `json:"iteration"`
+	SessionIteration int     `json:"session_iteration"`
+	MaxIterations    int     `json:"max_iterations"`
+	Status           string  `json:"status"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	RateHour         int     `json:"rate_hour"`
+	RateDay          int     `json:"rate_day"`
+	NotesExtracted   bool    `json:"notes_extracted"`
+	Tokens           int     `json:"tokens,omitempty"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// dryRunEvent is the --log-format json record that replaces the printed
+// prompt of a --dry-run; the summary that follows has status dry_run.
+type dryRunEvent struct {
+	Type      string `json:"type"`
+	RunID     string `json:"run_id"`
+	Iteration int    `json:"iteration"`
+	Prompt    string `json:"prompt"`
+}
+
+// summaryEvent is the --log-format json record that replaces the printed
+// summary at the end of a run.
+type summaryEvent struct {
+	Type string `json:"type"`
+	RunSummary
+}
+
+// iterationStatus classifies a finished iteration for iterationEvent.
+func iterationStatus(complete, timedOut bool, runErr error) string {
+	switch {
+	case timedOut:
+		return "timed_out"
+	case runErr != nil:
+		return "failed"
+	case complete:
+		return "complete"
+	default:
+		return "incomplete"
+	}
+}
+
+// printJSONLine writes v to stdout as a single line of JSON.
+func printJSONLine(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to encode log event: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
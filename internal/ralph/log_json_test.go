@@ -0,0 +1,150 @@
+package ralph
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogFormatJSONEmitsIterationAndSummaryObjects(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	outputs := []struct {
+		out string
+		err error
+	}{
+		{"<ralph_notes>first</ralph_notes>", nil},
+		{"", errors.New("provider unavailable")},
+		{"<ralph_notes>done</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil},
+	}
+	calls := 0
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			if args.Quiet || args.Verbose {
+				t.Errorf("--log-format json must not stream opencode output")
+			}
+			o := outputs[calls]
+			calls++
+			return o.out, o.err
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 5, LogFormat: logFormatJSON}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 3 iteration objects and a summary, got %d lines:\n%s", len(lines), out)
+	}
+
+	wantStatus := []string{"incomplete", "failed", "complete"}
+	wantNotes := []bool{true, false, true}
+	for i, line := range lines[:3] {
+		var event iterationEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %d is not JSON: %q: %v", i, line, err)
+		}
+		if event.Type != "iteration" || event.SessionIteration != i+1 || event.Status != wantStatus[i] || event.NotesExtracted != wantNotes[i] {
+			t.Fatalf("line %d: got %+v", i, event)
+		}
+	}
+	if !strings.Contains(lines[1], `"error":"provider unavailable"`) {
+		t.Fatalf("expected error in failed iteration event, got %s", lines[1])
+	}
+	if !strings.Contains(lines[0], `"rate_hour":1`) {
+		t.Fatalf("expected rate counts in iteration event, got %s", lines[0])
+	}
+
+	var summary summaryEvent
+	if err := json.Unmarshal([]byte(lines[3]), &summary); err != nil {
+		t.Fatalf("summary is not JSON: %q: %v", lines[3], err)
+	}
+	if summary.Type != "summary" || summary.Status != "complete" || summary.Iterations != 3 || summary.Failures != 1 {
+		t.Fatalf("summary: got %+v", summary)
+	}
+}
+
+func TestLogFormatJSONStrictDryRunEmitsSummary(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		err = RunWithOptions(RunOptions{LogFormat: logFormatJSON, DryRun: true, Strict: true}, 3, 0, 0)
+	})
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("expected ErrDryRun, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a dry_run object and a summary, got %d lines:\n%s", len(lines), out)
+	}
+	var event dryRunEvent
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("dry_run event is not JSON: %q: %v", lines[0], err)
+	}
+	if event.Type != "dry_run" || event.Iteration != 1 || !strings.Contains(event.Prompt, "SPECS") {
+		t.Fatalf("dry_run event: got %+v", event)
+	}
+	var summary summaryEvent
+	if err := json.Unmarshal([]byte(lines[1]), &summary); err != nil {
+		t.Fatalf("summary is not JSON: %q: %v", lines[1], err)
+	}
+	if summary.Type != "summary" || summary.Status != "dry_run" {
+		t.Fatalf("summary: got %+v", summary)
+	}
+}
+
+func TestRunErrorStillEmitsSummary(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) { return "no notes here", nil },
+	}
+	opts := RunOptions{MaxIterations: 3, LogFormat: logFormatJSON, StrictNotes: true, SummaryLog: "runs.log"}
+	var result RunResult
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		result, err = runIterationsWithRunner(cfg, opts, runner)
+	})
+	if err == nil {
+		t.Fatalf("expected --strict-notes to fail the run")
+	}
+	if result.Status != "error" {
+		t.Fatalf("result status: got %q want error", result.Status)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	var summary summaryEvent
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil || summary.Type != "summary" || summary.Status != "error" {
+		t.Fatalf("expected an error summary last, got %v:\n%s", err, out)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil || !strings.Contains(string(data), `"status":"error"`) {
+		t.Fatalf("expected an error record in the summary log, got %q, %v", data, err)
+	}
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	for _, format := range []string{"", "text", "json"} {
+		if err := validateLogFormat(format); err != nil {
+			t.Fatalf("%q: unexpected error %v", format, err)
+		}
+	}
+	if err := validateLogFormat("yaml"); err == nil {
+		t.Fatalf("expected error for unknown log format")
+	}
+}
@@ -0,0 +1,61 @@
+package ralph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxEscalationSteps bounds the iteration count of a single
+// --model-escalation step, since the schedule is expanded into overlays.
+const maxEscalationSteps = 10000
+
+// parseModelEscalation turns a --model-escalation spec such as
+// "ollama/small:3,anthropic/big" into one overlay per iteration: each model
+// but the last runs for its count, and the last one runs from then on.
+func parseModelEscalation(spec string) ([]IterationOverlay, error) {
+	steps := strings.Split(spec, ",")
+	var overlays []IterationOverlay
+	for i, step := range steps {
+		step = strings.TrimSpace(step)
+		last := i == len(steps)-1
+		model, count, hasCount := splitEscalationStep(step)
+		if err := validateModel(model); err != nil || model == "" {
+			return nil, fmt.Errorf("invalid --model-escalation step %q: expected provider/model:N, e.g. ollama/qwen3-coder:30b:3", step)
+		}
+		switch {
+		case last && hasCount:
+			return nil, fmt.Errorf("invalid --model-escalation step %q: the last model runs for all remaining iterations, so it takes no count", step)
+		case last:
+			overlays = append(overlays, IterationOverlay{Model: model})
+		case !hasCount:
+			return nil, fmt.Errorf("invalid --model-escalation step %q: every model but the last needs an iteration count, e.g. %s:3", step, model)
+		case count < 1 || count > maxEscalationSteps:
+			return nil, fmt.Errorf("invalid --model-escalation step %q: count must be between 1 and %d", step, maxEscalationSteps)
+		default:
+			for range count {
+				overlays = append(overlays, IterationOverlay{Model: model})
+			}
+		}
+	}
+	return overlays, nil
+}
+
+// splitEscalationStep separates a trailing ":N" count from a step. Model tags
+// may contain colons too (qwen3-coder:30b), so only an all-digit suffix counts.
+func splitEscalationStep(step string) (model string, count int, ok bool) {
+	i := strings.LastIndex(step, ":")
+	if i < 0 {
+		return step, 0, false
+	}
+	suffix := step[i+1:]
+	if suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+		return step, 0, false
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil {
+		// Too many digits to be a count; report it as out of range.
+		n = -1
+	}
+	return step[:i], n, true
+}
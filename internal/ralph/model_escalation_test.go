@@ -0,0 +1,86 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseModelEscalation(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{name: "single model", spec: "anthropic/big", want: []string{"anthropic/big"}},
+		{name: "small then big", spec: "ollama/small:3,anthropic/big", want: []string{"ollama/small", "ollama/small", "ollama/small", "anthropic/big"}},
+		{name: "tagged models", spec: "ollama/qwen3-coder:30b:2, ollama/qwen3-coder:480b", want: []string{"ollama/qwen3-coder:30b", "ollama/qwen3-coder:30b", "ollama/qwen3-coder:480b"}},
+		{name: "three steps", spec: "a/x:1,b/y:1,c/z", want: []string{"a/x", "b/y", "c/z"}},
+		{name: "missing count", spec: "ollama/small,anthropic/big", wantErr: "needs an iteration count"},
+		{name: "count on last", spec: "ollama/small:3,anthropic/big:2", wantErr: "takes no count"},
+		{name: "zero count", spec: "ollama/small:0,anthropic/big", wantErr: "between 1 and"},
+		{name: "huge count", spec: "ollama/small:99999999999999999999,anthropic/big", wantErr: "between 1 and"},
+		{name: "no provider", spec: "small:3,anthropic/big", wantErr: "expected provider/model:N"},
+		{name: "empty step", spec: "ollama/small:3,", wantErr: "expected provider/model:N"},
+		{name: "empty model", spec: ":3,anthropic/big", wantErr: "expected provider/model:N"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlays, err := parseModelEscalation(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseModelEscalation: %v", err)
+			}
+			var got []string
+			for _, o := range overlays {
+				got = append(got, o.Model)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelEscalationSwitchesModelsInPlan(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	out := captureOutput(t, &os.Stdout, func() {
+		opts := RunOptions{Plan: true, MaxIterations: 5, Model: "base/model", ModelEscalation: "ollama/small:3,anthropic/big"}
+		if err := RunWithOptions(opts, 5, 0, 0); err != nil {
+			t.Fatalf("RunWithOptions: %v", err)
+		}
+	})
+
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		rows = append(rows, fields[0]+" "+fields[1])
+	}
+	if got := strings.Join(rows, ", "); got != "1-3 ollama/small, 4-5 anthropic/big" {
+		t.Fatalf("plan rows: got %q\n%s", got, out)
+	}
+	if strings.Contains(out, "base/model") || strings.Contains(out, "#") {
+		t.Fatalf("expected only escalation models and no overlay numbers, got:\n%s", out)
+	}
+}
+
+func TestModelEscalationRejectsIterationsFile(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	opts := RunOptions{Plan: true, MaxIterations: 2, IterationsFile: "iters.json", ModelEscalation: "a/x:1,b/y"}
+	if err := os.WriteFile("iters.json", []byte(`[{"agent":"a"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunWithOptions(opts, 2, 0, 0); err == nil || !strings.Contains(err.Error(), "--iterations-file") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	notesHeadingPattern  = regexp.MustCompile(`^(# Run |## Iteration )`)
+	excessBlankLinesExpr = regexp.MustCompile(`\n{3,}`)
+)
+
+// sanitizeNotes keeps an iteration's notes from breaking the structure of
+// notes.md: nested tags (the notes and status tags) are stripped, lines that would read as run or
+// iteration headings are escaped, an unclosed code fence is closed, and
+// whitespace is normalized.
+func sanitizeNotes(notes string, tags ...string) string {
+	notes = strings.ReplaceAll(notes, "\r\n", "\n")
+	if names := tagAlternation(tags); names != "" {
+		notes = regexp.MustCompile(`</?`+names+`\b>?`).ReplaceAllString(notes, "")
+	}
+
+	lines := strings.Split(notes, "\n")
+	fences := 0
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if notesHeadingPattern.MatchString(line) {
+			line = `\` + line
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fences++
+		}
+		lines[i] = line
+	}
+	notes = strings.Join(lines, "\n")
+	notes = excessBlankLinesExpr.ReplaceAllString(notes, "\n\n")
+	notes = strings.TrimSpace(notes)
+	if fences%2 == 1 {
+		notes += "\n```"
+	}
+	return notes
+}
+
+// notesTracker remembers the last contents of the notes file seen during a
+// run so that a file deleted mid-run can be noticed and restored.
+type notesTracker struct {
+	path string
+	seen bool
+	last string
+}
+
+// observe records the notes file's current contents, if it exists.
+func (t *notesTracker) observe() {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return
+	}
+	t.seen = true
+	t.last = string(data)
+}
+
+// restoreIfDeleted rewrites the notes file from the last observed contents
+// when it existed earlier in the run but has since vanished.
+func (t *notesTracker) restoreIfDeleted() (restored bool, err error) {
+	if !t.seen {
+		return false, nil
+	}
+	if _, err := os.Stat(t.path); !errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err := os.WriteFile(t.path, []byte(t.last), 0644); err != nil {
+		return false, fmt.Errorf("restoring %s: %w", t.path, err)
+	}
+	return true, nil
+}
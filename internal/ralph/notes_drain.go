@@ -0,0 +1,64 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// terminalStatus reports whether a run with status has finished for good,
+// as opposed to pausing (rate_limited) or never really running.
+func terminalStatus(status string) bool {
+	switch status {
+	case "complete", "max_iterations", "time_limit", "token_budget", "failed":
+		return true
+	default:
+		return false
+	}
+}
+
+// drainNotes copies notesFile to target, creating its directory, and with
+// move removes the original afterwards. A missing notes file drains nothing.
+func drainNotes(target string, move bool) (bool, error) {
+	data, err := os.ReadFile(notesFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", notesFile, err)
+	}
+	if dir := filepath.Dir(target); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return false, fmt.Errorf("creating %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return false, fmt.Errorf("writing %s: %w", target, err)
+	}
+	if move {
+		if err := os.Remove(notesFile); err != nil {
+			return true, fmt.Errorf("removing %s: %w", notesFile, err)
+		}
+	}
+	return true, nil
+}
+
+// drainNotesOnFinish applies --drain-notes-to once a run ends with a
+// terminal status; failures only warn, as the run itself already finished.
+func drainNotesOnFinish(opts RunOptions, status string) {
+	if opts.DrainNotesTo == "" || opts.DryRun || !terminalStatus(status) {
+		return
+	}
+	drained, err := drainNotes(opts.DrainNotesTo, opts.DrainNotesMove)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to drain notes to %s: %v\n", opts.DrainNotesTo, err)
+		return
+	}
+	if drained && !opts.Quiet && !opts.QuietSummary && opts.LogFormat != logFormatJSON {
+		verb := "Copied"
+		if opts.DrainNotesMove {
+			verb = "Moved"
+		}
+		fmt.Printf("%s %s to %s\n", verb, notesFile, opts.DrainNotesTo)
+	}
+}
@@ -0,0 +1,110 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrainNotesCopyAndMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		move     bool
+		wantKept bool
+	}{
+		{name: "copy keeps original", move: false, wantKept: true},
+		{name: "move removes original", move: true, wantKept: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			target := filepath.Join("archive", "run-notes.md")
+			opts := RunOptions{MaxIterations: 1, Quiet: true, DrainNotesTo: target, DrainNotesMove: tt.move}
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					return "<ralph_notes>handed off</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+				},
+			}
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+
+			drained, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatalf("read drained notes: %v", err)
+			}
+			if !strings.Contains(string(drained), "handed off") {
+				t.Fatalf("drained notes missing content: %q", drained)
+			}
+			_, err = os.Stat(notesFile)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Fatalf("original kept = %v, want %v (stat err: %v)", kept, tt.wantKept, err)
+			}
+		})
+	}
+}
+
+func TestDrainNotesOnlyOnTerminalStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{"complete", true},
+		{"max_iterations", true},
+		{"time_limit", true},
+		{"token_budget", true},
+		{"failed", true},
+		{"rate_limited", false},
+		{"dry_run", false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			withTempCWD(t)
+
+			if err := os.MkdirAll(ralphDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(notesFile, []byte("notes\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			drainNotesOnFinish(RunOptions{Quiet: true, DrainNotesTo: "out.md", DrainNotesMove: true}, tt.status)
+
+			_, err := os.Stat("out.md")
+			if drained := err == nil; drained != tt.want {
+				t.Fatalf("drained = %v, want %v", drained, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrainNotesSkippedWhenRateLimited(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(notesFile, []byte("earlier notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := State{Timestamps: []int64{time.Now().Unix()}}
+	saveState(state)
+
+	opts := RunOptions{MaxIterations: 1, MaxPerHour: 1, Quiet: true, DrainNotesTo: "out.md"}
+	if _, err := runIterationsWithRunner(cfg, opts, completeRunner()); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if _, err := os.Stat("out.md"); !os.IsNotExist(err) {
+		t.Fatalf("expected no drain for a rate-limited run, stat err: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// notes_rotation modes for max_notes_bytes.
+const (
+	// notesRotate moves the full notes file to notes.md.1 (shifting older
+	// copies up) and starts a fresh one.
+	notesRotate = "rotate"
+	// notesTruncate drops the oldest entries from the notes file in place.
+	notesTruncate = "truncate"
+)
+
+// maxNotesRotations is how many rotated notes files are kept.
+const maxNotesRotations = 5
+
+func validateNotesRotation(mode string) error {
+	switch mode {
+	case "", notesRotate, notesTruncate:
+		return nil
+	default:
+		return fmt.Errorf("invalid notes_rotation: %s (expected rotate or truncate)", mode)
+	}
+}
+
+// notesLimit caps the size of the notes file; a zero MaxBytes is unlimited.
+type notesLimit struct {
+	MaxBytes int
+	Mode     string
+}
+
+// makeRoom keeps the notes file at path within the limit once incoming
+// more bytes are appended, by rotating or truncating it first.
+func (l notesLimit) makeRoom(path string, incoming int) error {
+	if l.MaxBytes <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if len(data)+incoming <= l.MaxBytes {
+		return nil
+	}
+	if l.Mode == notesTruncate {
+		kept := dropOldestNotes(string(data), l.MaxBytes-incoming)
+		if err := os.WriteFile(path, []byte(kept), 0644); err != nil {
+			return fmt.Errorf("truncating %s: %w", path, err)
+		}
+		return nil
+	}
+	return rotateNotesFile(path)
+}
+
+// rotatedNotesFile is the path of the n-th rotated copy of path.
+func rotatedNotesFile(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// rotateNotesFile shifts path.1.. up by one, dropping the oldest beyond
+// maxNotesRotations, and moves path to path.1.
+func rotateNotesFile(path string) error {
+	_ = os.Remove(rotatedNotesFile(path, maxNotesRotations))
+	for n := maxNotesRotations - 1; n >= 1; n-- {
+		err := os.Rename(rotatedNotesFile(path, n), rotatedNotesFile(path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotating %s: %w", path, err)
+		}
+	}
+	if err := os.Rename(path, rotatedNotesFile(path, 1)); err != nil {
+		return fmt.Errorf("rotating %s: %w", path, err)
+	}
+	return nil
+}
+
+// notesEntryStart matches the start of a run header or iteration entry.
+var notesEntryStart = regexp.MustCompile(`(?m)^(# Run |## Iteration )`)
+
+// splitNotesEntries splits notes into a preamble followed by one chunk per
+// run header or iteration entry.
+func splitNotesEntries(notes string) []string {
+	starts := notesEntryStart.FindAllStringIndex(notes, -1)
+	if len(starts) == 0 {
+		return []string{notes}
+	}
+	chunks := []string{notes[:starts[0][0]]}
+	for i, start := range starts {
+		end := len(notes)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		chunks = append(chunks, notes[start[0]:end])
+	}
+	return chunks
+}
+
+// dropOldestNotes removes whole entries from the front of notes until it
+// fits in maxBytes, keeping at least the newest entry.
+func dropOldestNotes(notes string, maxBytes int) string {
+	chunks := splitNotesEntries(notes)
+	size := len(notes)
+	i := 0
+	for ; i < len(chunks)-1 && size > maxBytes; i++ {
+		size -= len(chunks[i])
+	}
+	return strings.Join(chunks[i:], "")
+}
+
+// tailNotes keeps only the last n iteration entries of notes for the prompt,
+// with a marker saying how many were left out.
+func tailNotes(notes string, n int) string {
+	if n <= 0 {
+		return notes
+	}
+	var iterations []int
+	for _, loc := range notesEntryStart.FindAllStringIndex(notes, -1) {
+		if strings.HasPrefix(notes[loc[0]:], "## Iteration ") {
+			iterations = append(iterations, loc[0])
+		}
+	}
+	if len(iterations) <= n {
+		return notes
+	}
+	kept := notes[iterations[len(iterations)-n]:]
+	return fmt.Sprintf("[earlier notes omitted: showing the last %d of %d iterations]\n\n", n, len(iterations)) + kept
+}
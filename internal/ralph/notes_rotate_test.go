@@ -0,0 +1,148 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func writeNotes(t *testing.T, content string) {
+	t.Helper()
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(notesFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readNotes(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestNotesLimitRotatesAndShifts(t *testing.T) {
+	withTempCWD(t)
+
+	limit := notesLimit{MaxBytes: 200, Mode: notesRotate}
+	writeNotes(t, strings.Repeat("a", 150))
+	if err := appendNotes("first overflow", 1, limit); err != nil {
+		t.Fatalf("appendNotes: %v", err)
+	}
+	if got := readNotes(t, rotatedNotesFile(notesFile, 1)); got != strings.Repeat("a", 150) {
+		t.Fatalf("notes.md.1: got %q", got)
+	}
+	if got := readNotes(t, notesFile); !strings.Contains(got, "## Iteration 1") || !strings.Contains(got, "first overflow") {
+		t.Fatalf("fresh notes.md: got %q", got)
+	}
+
+	writeNotes(t, strings.Repeat("b", 190))
+	if err := appendNotes("second overflow", 2, limit); err != nil {
+		t.Fatalf("appendNotes: %v", err)
+	}
+	if got := readNotes(t, rotatedNotesFile(notesFile, 2)); got != strings.Repeat("a", 150) {
+		t.Fatalf("notes.md.2 should hold the older rotation, got %q", got)
+	}
+	if got := readNotes(t, rotatedNotesFile(notesFile, 1)); got != strings.Repeat("b", 190) {
+		t.Fatalf("notes.md.1: got %q", got)
+	}
+}
+
+func TestNotesLimitKeepsAtMostMaxRotations(t *testing.T) {
+	withTempCWD(t)
+
+	limit := notesLimit{MaxBytes: 10, Mode: notesRotate}
+	for i := 0; i < maxNotesRotations+3; i++ {
+		writeNotes(t, strings.Repeat("x", 20))
+		if err := limit.makeRoom(notesFile, 1); err != nil {
+			t.Fatalf("makeRoom: %v", err)
+		}
+	}
+	if _, err := os.Stat(rotatedNotesFile(notesFile, maxNotesRotations)); err != nil {
+		t.Fatalf("expected notes.md.%d: %v", maxNotesRotations, err)
+	}
+	if _, err := os.Stat(rotatedNotesFile(notesFile, maxNotesRotations+1)); !os.IsNotExist(err) {
+		t.Fatalf("expected no notes.md.%d, stat err: %v", maxNotesRotations+1, err)
+	}
+}
+
+func TestNotesLimitTruncateDropsOldestEntries(t *testing.T) {
+	withTempCWD(t)
+
+	writeNotes(t, "\n## Iteration 1 (t)\nold old old\n\n## Iteration 2 (t)\nmiddle\n")
+	limit := notesLimit{MaxBytes: 80, Mode: notesTruncate}
+	if err := appendNotes("newest", 3, limit); err != nil {
+		t.Fatalf("appendNotes: %v", err)
+	}
+
+	got := readNotes(t, notesFile)
+	if strings.Contains(got, "old old old") {
+		t.Fatalf("expected the oldest entry to be dropped, got %q", got)
+	}
+	if !strings.Contains(got, "middle") || !strings.Contains(got, "newest") {
+		t.Fatalf("expected recent entries kept, got %q", got)
+	}
+	if len(got) > 80 {
+		t.Fatalf("notes are %d bytes, over the 80-byte cap", len(got))
+	}
+	if _, err := os.Stat(rotatedNotesFile(notesFile, 1)); !os.IsNotExist(err) {
+		t.Fatalf("truncate mode must not rotate, stat err: %v", err)
+	}
+}
+
+func TestTailNotes(t *testing.T) {
+	notes := "\n# Run r1 (t)\n\n## Iteration 1 (t)\none\n\n## Iteration 2 (t)\ntwo\n\n# Run r2 (t)\n\n## Iteration 3 (t)\nthree\n"
+
+	tests := []struct {
+		name    string
+		n       int
+		want    []string
+		notWant []string
+	}{
+		{name: "zero keeps all", n: 0, want: []string{"one", "two", "three"}},
+		{name: "more than present keeps all", n: 5, want: []string{"one", "two", "three"}, notWant: []string{"omitted"}},
+		{name: "last two", n: 2, want: []string{"showing the last 2 of 3 iterations", "two", "# Run r2", "three"}, notWant: []string{"one"}},
+		{name: "last one", n: 1, want: []string{"three"}, notWant: []string{"one", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tailNotes(notes, tt.n)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("expected %q in %q", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Fatalf("did not expect %q in %q", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNotesTailBoundsPrompt(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writeNotes(t, "\n## Iteration 1 (t)\nancient history\n\n## Iteration 2 (t)\nrecent work\n")
+
+	var prompt string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompt = args.Prompt
+			return "", nil
+		},
+	}
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, Quiet: true, NotesTail: 1}, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if strings.Contains(prompt, "ancient history") || !strings.Contains(prompt, "recent work") {
+		t.Fatalf("expected only the last iteration's notes in the prompt, got:\n%s", prompt)
+	}
+}
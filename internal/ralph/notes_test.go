@@ -0,0 +1,128 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeNotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes string
+		want  string
+	}{
+		{name: "clean", notes: "did a thing", want: "did a thing"},
+		{name: "nested tags", notes: "start <ralph_notes>inner</ralph_notes> <ralph_status>COMPLETE", want: "start inner COMPLETE"},
+		{name: "whitespace", notes: "  line one   \r\n\r\n\r\n\r\nline two\t\n\n", want: "line one\n\nline two"},
+		{name: "headings", notes: "# Run fake\n## Iteration 3\n## Other", want: "\\# Run fake\n\\## Iteration 3\n## Other"},
+		{name: "unclosed fence", notes: "```go\nfmt.Println()", want: "```go\nfmt.Println()\n```"},
+		{name: "closed fence", notes: "```\nx\n```", want: "```\nx\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeNotes(tt.notes, defaultNotesTag, defaultStatusTag); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendNotesSanitizes(t *testing.T) {
+	withTempCWD(t)
+
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", ralphDir, err)
+	}
+	if err := appendNotes("a <ralph_notes>b   \n\n\n\nc", 1, notesLimit{}, defaultNotesTag, defaultStatusTag); err != nil {
+		t.Fatalf("appendNotes: %v", err)
+	}
+
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	text := string(data)
+	if strings.Contains(text, "<ralph_") {
+		t.Fatalf("expected ralph tags stripped, got %q", text)
+	}
+	if !strings.HasSuffix(text, "\na b\n\nc\n") {
+		t.Fatalf("expected normalized body, got %q", text)
+	}
+}
+
+func TestNotesDeletedMidRunAreRestored(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			if calls == 2 {
+				if err := os.Remove(notesFile); err != nil {
+					t.Fatalf("remove notes: %v", err)
+				}
+			}
+			return "<ralph_notes>entry " + string(rune('0'+calls)) + "</ralph_notes>", nil
+		},
+	}
+
+	var err error
+	stderr := captureOutput(t, &os.Stderr, func() {
+		_, err = runIterationsWithRunner(cfg, RunOptions{MaxIterations: 2, Quiet: true}, runner)
+	})
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if !strings.Contains(stderr, "was deleted during the run; restored it") {
+		t.Fatalf("expected deletion warning, got %q", stderr)
+	}
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if !strings.Contains(string(data), "entry 1") || !strings.Contains(string(data), "entry 2") {
+		t.Fatalf("expected both iterations' notes after restore, got %q", data)
+	}
+}
+
+func TestDedupeNotes(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	outputs := []string{"same", " same ", "different", "different"}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			out := "<ralph_notes>" + outputs[calls] + "</ralph_notes>"
+			calls++
+			return out, nil
+		},
+	}
+
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 4, Quiet: true, DedupeNotes: true}, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	text := string(data)
+	if got := strings.Count(text, "\nsame\n"); got != 1 {
+		t.Fatalf("expected one full copy of the repeated note, got %d:\n%s", got, text)
+	}
+	if !strings.Contains(text, "(same as iteration 1)") || !strings.Contains(text, "(same as iteration 3)") {
+		t.Fatalf("expected dedupe markers, got:\n%s", text)
+	}
+	if got := strings.Count(text, "\ndifferent\n"); got != 1 {
+		t.Fatalf("expected one full copy of the second note, got %d:\n%s", got, text)
+	}
+}
@@ -0,0 +1,165 @@
+package ralph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// OpencodeEvent is one event from `opencode run --format json` output.
+type OpencodeEvent struct {
+	Type      string       `json:"type"`
+	SessionID string       `json:"sessionID,omitempty"`
+	Part      OpencodePart `json:"part"`
+}
+
+// OpencodePart is the payload of an OpencodeEvent.
+type OpencodePart struct {
+	Type string `json:"type,omitempty"`
+	Text string `json:"text,omitempty"`
+	Tool string `json:"tool,omitempty"`
+	// Tokens is reported on step-finish parts.
+	Tokens *OpencodeTokens `json:"tokens,omitempty"`
+}
+
+// OpencodeTokens is the token usage of one model step.
+type OpencodeTokens struct {
+	Input     int `json:"input"`
+	Output    int `json:"output"`
+	Reasoning int `json:"reasoning"`
+}
+
+// OpencodeResult is the parsed form of an iteration's JSON output.
+type OpencodeResult struct {
+	Events []OpencodeEvent
+}
+
+// parseOpencodeJSON decodes a stream of JSON events (one per line, or a
+// single JSON array).
+func parseOpencodeJSON(output string) (OpencodeResult, error) {
+	trimmed := strings.TrimSpace(output)
+	if trimmed == "" {
+		return OpencodeResult{}, errors.New("empty output")
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		var events []OpencodeEvent
+		if err := json.Unmarshal([]byte(trimmed), &events); err != nil {
+			return OpencodeResult{}, fmt.Errorf("parsing opencode JSON: %w", err)
+		}
+		return OpencodeResult{Events: events}, nil
+	}
+
+	var result OpencodeResult
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	for {
+		var event OpencodeEvent
+		err := dec.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("parsing opencode JSON: %w", err)
+		}
+		result.Events = append(result.Events, event)
+	}
+}
+
+// sessionID returns the first session ID reported in the events, if any.
+func (r OpencodeResult) sessionID() string {
+	for _, event := range r.Events {
+		if event.SessionID != "" {
+			return event.SessionID
+		}
+	}
+	return ""
+}
+
+// answerText joins the text parts of the assistant's final message, so notes
+// and status are read from the answer rather than from reasoning or from
+// narration earlier in the session that merely mentions them. A message ends
+// at a new step or a tool call.
+func (r OpencodeResult) answerText() string {
+	var message, answer []string
+	for _, event := range r.Events {
+		switch {
+		case event.Type == "step_start" || event.Part.Type == "step-start",
+			event.Type == "tool_use" || event.Part.Type == "tool":
+			message = nil
+		case event.Part.Type == "text" || (event.Part.Type == "" && event.Type == "text"):
+			message = append(message, event.Part.Text)
+			answer = message
+		}
+	}
+	return strings.Join(answer, "\n")
+}
+
+// tokens sums the input, output, and reasoning tokens of every step.
+func (r OpencodeResult) tokens() int {
+	total := 0
+	for _, event := range r.Events {
+		if t := event.Part.Tokens; t != nil {
+			total += t.Input + t.Output + t.Reasoning
+		}
+	}
+	return total
+}
+
+// toolActivity describes a kind of tool call, e.g. "edited" + "file".
+type toolActivity struct {
+	verb string
+	noun string
+}
+
+var toolActivities = map[string]toolActivity{
+	"edit":      {"edited", "file"},
+	"multiedit": {"edited", "file"},
+	"write":     {"edited", "file"},
+	"patch":     {"edited", "file"},
+	"bash":      {"ran", "command"},
+	"read":      {"read", "file"},
+	"grep":      {"ran", "search"},
+	"glob":      {"ran", "search"},
+	"list":      {"ran", "search"},
+}
+
+// countToolCalls tallies the tool calls in result by activity.
+func countToolCalls(result OpencodeResult) map[toolActivity]int {
+	counts := map[toolActivity]int{}
+	for _, event := range result.Events {
+		if event.Part.Tool == "" || (event.Type != "tool_use" && event.Part.Type != "tool") {
+			continue
+		}
+		activity, ok := toolActivities[event.Part.Tool]
+		if !ok {
+			activity = toolActivity{verb: "used", noun: event.Part.Tool + " tool"}
+		}
+		counts[activity]++
+	}
+	return counts
+}
+
+// summarizeToolCalls describes the tool calls in result as one line of
+// activity, e.g. "edited 3 files, ran 2 commands".
+func summarizeToolCalls(result OpencodeResult) string {
+	return formatToolCounts(countToolCalls(result))
+}
+
+// formatToolCounts renders counts as e.g. "edited 3 files, ran 2 commands".
+func formatToolCounts(counts map[toolActivity]int) string {
+	parts := make([]string, 0, len(counts))
+	for activity, n := range counts {
+		noun := activity.noun
+		if n != 1 {
+			noun += "s"
+			if strings.HasSuffix(activity.noun, "h") {
+				noun = activity.noun + "es"
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s %d %s", activity.verb, n, noun))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,350 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const sampleOpencodeJSON = `{"type":"step_start","sessionID":"ses_1","part":{"type":"step-start"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"read"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"edit"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"write"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"bash"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"grep"}}
+{"type":"tool_use","sessionID":"ses_1","part":{"type":"tool","tool":"glob"}}
+{"type":"text","sessionID":"ses_1","part":{"type":"text","text":"done"}}
+`
+
+func TestParseOpencodeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantEvents int
+		wantErr    bool
+	}{
+		{name: "ndjson", output: sampleOpencodeJSON, wantEvents: 8},
+		{name: "array", output: `[{"type":"tool_use","part":{"tool":"bash"}},{"type":"text","part":{"text":"hi"}}]`, wantEvents: 2},
+		{name: "empty", output: "  \n", wantErr: true},
+		{name: "invalid", output: "not json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseOpencodeJSON(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOpencodeJSON: %v", err)
+			}
+			if len(result.Events) != tt.wantEvents {
+				t.Fatalf("got %d events want %d", len(result.Events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestSummarizeToolCalls(t *testing.T) {
+	result, err := parseOpencodeJSON(sampleOpencodeJSON + `{"type":"tool_use","part":{"type":"tool","tool":"webfetch"}}`)
+	if err != nil {
+		t.Fatalf("parseOpencodeJSON: %v", err)
+	}
+	got := summarizeToolCalls(result)
+	want := "edited 2 files, ran 1 command, ran 2 searches, read 1 file, used 1 webfetch tool"
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if got := summarizeToolCalls(OpencodeResult{}); got != "" {
+		t.Fatalf("expected no summary without tool calls, got %q", got)
+	}
+}
+
+func TestJSONFormatRecordsActivityInNotes(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return sampleOpencodeJSON, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, Format: "json", SummaryLog: "summary.jsonl"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	want := "Activity: edited 2 files, ran 1 command, ran 2 searches, read 1 file"
+	if !strings.Contains(string(data), want) {
+		t.Fatalf("expected %q in notes, got %q", want, data)
+	}
+
+	summary, err := os.ReadFile("summary.jsonl")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(summary), `"activity":"edited 2 files, ran 1 command, ran 2 searches, read 1 file"`) {
+		t.Fatalf("expected activity in summary log, got %q", summary)
+	}
+}
+
+func TestStrictFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		strict       bool
+		wantFailures int
+		warn         bool
+	}{
+		{name: "valid strict", output: sampleOpencodeJSON, strict: true},
+		{name: "invalid strict", output: "plain text", strict: true, wantFailures: 1, warn: true},
+		{name: "valid lenient", output: sampleOpencodeJSON},
+		{name: "invalid lenient", output: "plain text", warn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					return tt.output, nil
+				},
+			}
+
+			opts := RunOptions{MaxIterations: 1, Quiet: true, Format: "json", StrictFormat: tt.strict}
+			var result RunResult
+			var err error
+			stderr := captureOutput(t, &os.Stderr, func() {
+				result, err = runIterationsWithRunner(cfg, opts, runner)
+			})
+			if err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+			if result.Failures != tt.wantFailures {
+				t.Fatalf("failures: got %d want %d", result.Failures, tt.wantFailures)
+			}
+			if got := strings.Contains(stderr, "did not honor --format json"); got != tt.warn {
+				t.Fatalf("warning printed = %v, want %v (stderr %q)", got, tt.warn, stderr)
+			}
+		})
+	}
+}
+
+func TestStrictFormatFailuresTripBreaker(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "plain text", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 5, Quiet: true, Format: "json", StrictFormat: true, MaxConsecutiveFailures: 2}
+	var result RunResult
+	var err error
+	captureOutput(t, &os.Stderr, func() {
+		result, err = runIterationsWithRunner(cfg, opts, runner)
+	})
+	if err != nil {
+		t.Fatalf("expected the breaker, not an error, to stop the run: %v", err)
+	}
+	if calls != 2 || result.Status != "failed" || result.Failures != 2 {
+		t.Fatalf("expected the run to fail after 2 calls, got %d calls and %+v", calls, result)
+	}
+}
+
+const stepFinishJSON = `{"type":"step_finish","sessionID":"ses_1","part":{"type":"step-finish","tokens":{"input":300,"output":150,"reasoning":50,"cache":{"read":1000,"write":0}}}}
+`
+
+func TestOpencodeResultTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int
+	}{
+		{name: "no step finish", output: sampleOpencodeJSON, want: 0},
+		{name: "one step", output: sampleOpencodeJSON + stepFinishJSON, want: 500},
+		{name: "two steps", output: stepFinishJSON + stepFinishJSON, want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseOpencodeJSON(tt.output)
+			if err != nil {
+				t.Fatalf("parseOpencodeJSON: %v", err)
+			}
+			if got := result.tokens(); got != tt.want {
+				t.Fatalf("tokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBudgetStopsRun(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	saveState(State{TotalTokens: 10000})
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return sampleOpencodeJSON + stepFinishJSON, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 5, Format: "json", TokenBudget: 1200, SummaryLog: "summary.jsonl"}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if calls != 3 {
+		t.Fatalf("expected 3 iterations before exceeding 1200 tokens, got %d", calls)
+	}
+	if !strings.Contains(out, "Tokens: 1500\n") || !strings.Contains(out, "TOKEN_BUDGET") {
+		t.Fatalf("expected tokens and status in summary, got %q", out)
+	}
+	if got := loadState().TotalTokens; got != 11500 {
+		t.Fatalf("state total tokens = %d, want 11500", got)
+	}
+	summary, err := os.ReadFile("summary.jsonl")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(summary), `"status":"token_budget"`) || !strings.Contains(string(summary), `"tokens":1500`) {
+		t.Fatalf("expected token_budget record, got %q", summary)
+	}
+}
+
+func TestAnswerTextIgnoresReasoning(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		wantComplete bool
+		wantNotes    string
+	}{
+		{
+			name: "complete only in reasoning",
+			output: `{"type":"reasoning","part":{"type":"reasoning","text":"Once done I will print <ralph_status>COMPLETE</ralph_status>"}}
+{"type":"tool_use","part":{"type":"tool","tool":"edit"}}
+{"type":"text","part":{"type":"text","text":"Fixed one task.\n<ralph_notes>fixed the parser</ralph_notes>"}}
+`,
+			wantComplete: false,
+			wantNotes:    "fixed the parser",
+		},
+		{
+			name: "complete in final answer",
+			output: `{"type":"reasoning","part":{"type":"reasoning","text":"<ralph_notes>draft notes</ralph_notes>"}}
+{"type":"text","part":{"type":"text","text":"<ralph_notes>all tasks done</ralph_notes>\n<ralph_status>COMPLETE</ralph_status>"}}
+`,
+			wantComplete: true,
+			wantNotes:    "all tasks done",
+		},
+		{
+			name: "complete only in earlier narration",
+			output: `{"type":"step_start","part":{"type":"step-start"}}
+{"type":"text","part":{"type":"text","text":"I will emit <ralph_status>COMPLETE</ralph_status> once tests pass. <ralph_notes>draft notes</ralph_notes>"}}
+{"type":"tool_use","part":{"type":"tool","tool":"bash"}}
+{"type":"step_finish","part":{"type":"step-finish"}}
+{"type":"step_start","part":{"type":"step-start"}}
+{"type":"text","part":{"type":"text","text":"Tests still fail."}}
+{"type":"text","part":{"type":"text","text":"<ralph_notes>tests failing</ralph_notes>"}}
+{"type":"step_finish","part":{"type":"step-finish"}}
+`,
+			wantComplete: false,
+			wantNotes:    "tests failing",
+		},
+		{
+			name: "narration before a tool call without steps",
+			output: `{"type":"text","part":{"type":"text","text":"Printing <ralph_status>COMPLETE</ralph_status> soon."}}
+{"type":"tool_use","part":{"type":"tool","tool":"edit"}}
+{"type":"text","part":{"type":"text","text":"<ralph_notes>edited one file</ralph_notes>"}}
+`,
+			wantComplete: false,
+			wantNotes:    "edited one file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			var calls int
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					calls++
+					return tt.output, nil
+				},
+			}
+			opts := RunOptions{MaxIterations: 2, Quiet: true, Format: "json"}
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+
+			wantCalls := 2
+			if tt.wantComplete {
+				wantCalls = 1
+			}
+			if calls != wantCalls {
+				t.Fatalf("runner calls = %d, want %d", calls, wantCalls)
+			}
+			notes, err := os.ReadFile(notesFile)
+			if err != nil {
+				t.Fatalf("read notes: %v", err)
+			}
+			if !strings.Contains(string(notes), tt.wantNotes) || strings.Contains(string(notes), "draft notes") {
+				t.Fatalf("expected notes from the answer only, got %q", notes)
+			}
+		})
+	}
+}
+
+func TestUnparsedJSONFallsBackToWholeOutput(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "plain text <ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 2, Quiet: true, Format: "json"}
+	captureOutput(t, &os.Stderr, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+	if calls != 1 {
+		t.Fatalf("expected completion from the raw text output, got %d calls", calls)
+	}
+}
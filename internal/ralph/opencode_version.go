@@ -0,0 +1,127 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// OpencodeVersion is a parsed `opencode --version`.
+type OpencodeVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v OpencodeVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is an older release than other.
+func (v OpencodeVersion) Less(other OpencodeVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// versionPattern finds the first MAJOR.MINOR[.PATCH] in version output such
+// as "0.3.51" or "opencode v0.3.51".
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+func parseOpencodeVersion(out string) (OpencodeVersion, error) {
+	m := versionPattern.FindStringSubmatch(out)
+	if m == nil {
+		return OpencodeVersion{}, fmt.Errorf("no version number in %q", out)
+	}
+	var v OpencodeVersion
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+func validateMinOpencodeVersion(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := parseOpencodeVersion(value); err != nil {
+		return fmt.Errorf("invalid min_opencode_version: %w", err)
+	}
+	return nil
+}
+
+// opencodeVersionCache holds the first DetectOpencodeVersion result; tests
+// reset it.
+var opencodeVersionCache struct {
+	once    sync.Once
+	version OpencodeVersion
+	err     error
+}
+
+// DetectOpencodeVersion runs `opencode --version` once per process and
+// returns the parsed version. It returns ErrOpencodeNotFound when opencode
+// is not installed.
+func DetectOpencodeVersion() (OpencodeVersion, error) {
+	opencodeVersionCache.once.Do(func() {
+		opencodeVersionCache.version, opencodeVersionCache.err = detectOpencodeVersion(opencodeTool)
+	})
+	return opencodeVersionCache.version, opencodeVersionCache.err
+}
+
+func detectOpencodeVersion(cli opencodeCLI) (OpencodeVersion, error) {
+	out, err := cli.Run("--version")
+	if errors.Is(err, exec.ErrNotFound) {
+		return OpencodeVersion{}, ErrOpencodeNotFound
+	}
+	if err != nil {
+		return OpencodeVersion{}, fmt.Errorf("detecting opencode version: %w", err)
+	}
+	v, err := parseOpencodeVersion(out)
+	if err != nil {
+		return OpencodeVersion{}, fmt.Errorf("detecting opencode version: %w", err)
+	}
+	return v, nil
+}
+
+// checkOpencodeVersion compares the detected version against minimum (a
+// min_opencode_version value, "" for none) and describes any problem.
+func checkOpencodeVersion(detect func() (OpencodeVersion, error), minimum string) (OpencodeVersion, error) {
+	v, err := detect()
+	if err != nil {
+		return v, err
+	}
+	if minimum == "" {
+		return v, nil
+	}
+	want, err := parseOpencodeVersion(minimum)
+	if err != nil {
+		return v, fmt.Errorf("invalid min_opencode_version: %w", err)
+	}
+	if v.Less(want) {
+		return v, fmt.Errorf("opencode %s is older than min_opencode_version %s; upgrade opencode if runs misbehave", v, want)
+	}
+	return v, nil
+}
+
+// warnOldOpencode prints a warning at the start of a run when min_opencode_version
+// is set and binary is older or its version is unknown.
+func warnOldOpencode(binary, minimum string) {
+	if minimum == "" {
+		return
+	}
+	detect := DetectOpencodeVersion
+	if binary != configuredOpencodeBinary() {
+		detect = func() (OpencodeVersion, error) { return detectOpencodeVersion(execOpencodeCLI{binary: binary}) }
+	}
+	if _, err := checkOpencodeVersion(detect, minimum); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,96 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+func TestParseOpencodeVersion(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    OpencodeVersion
+		wantErr bool
+	}{
+		{out: "0.3.51\n", want: OpencodeVersion{0, 3, 51}},
+		{out: "opencode v1.2.3-beta", want: OpencodeVersion{1, 2, 3}},
+		{out: "1.4", want: OpencodeVersion{1, 4, 0}},
+		{out: "dev build", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.out, func(t *testing.T) {
+			got, err := parseOpencodeVersion(tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpencodeVersionLess(t *testing.T) {
+	if !(OpencodeVersion{0, 3, 9}).Less(OpencodeVersion{0, 4, 0}) {
+		t.Fatalf("0.3.9 should be older than 0.4.0")
+	}
+	if (OpencodeVersion{1, 0, 0}).Less(OpencodeVersion{0, 9, 9}) {
+		t.Fatalf("1.0.0 should not be older than 0.9.9")
+	}
+	if (OpencodeVersion{0, 4, 0}).Less(OpencodeVersion{0, 4, 0}) {
+		t.Fatalf("equal versions are not older")
+	}
+}
+
+type errOpencodeCLI struct{ err error }
+
+func (c errOpencodeCLI) Run(args ...string) (string, error) { return "", c.err }
+
+func TestDetectOpencodeVersion(t *testing.T) {
+	got, err := detectOpencodeVersion(fakeOpencodeCLI{responses: map[string]string{"--version": "0.3.51\n"}})
+	if err != nil || got != (OpencodeVersion{0, 3, 51}) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	notFound := &exec.Error{Name: "opencode", Err: exec.ErrNotFound}
+	if _, err := detectOpencodeVersion(errOpencodeCLI{fmt.Errorf("opencode --version: %w", notFound)}); !errors.Is(err, ErrOpencodeNotFound) {
+		t.Fatalf("expected ErrOpencodeNotFound, got %v", err)
+	}
+}
+
+func TestDetectOpencodeVersionIsCached(t *testing.T) {
+	orig := opencodeTool
+	t.Cleanup(func() {
+		opencodeTool = orig
+		opencodeVersionCache.once = sync.Once{}
+	})
+	opencodeVersionCache.once = sync.Once{}
+
+	opencodeTool = fakeOpencodeCLI{responses: map[string]string{"--version": "0.3.51"}}
+	first, err := DetectOpencodeVersion()
+	if err != nil {
+		t.Fatalf("DetectOpencodeVersion: %v", err)
+	}
+	opencodeTool = fakeOpencodeCLI{responses: map[string]string{"--version": "9.9.9"}}
+	if second, _ := DetectOpencodeVersion(); second != first {
+		t.Fatalf("expected cached %v, got %v", first, second)
+	}
+}
+
+func TestCheckOpencodeVersion(t *testing.T) {
+	detect := func() (OpencodeVersion, error) { return OpencodeVersion{0, 3, 0}, nil }
+	if _, err := checkOpencodeVersion(detect, ""); err != nil {
+		t.Fatalf("no minimum: %v", err)
+	}
+	if _, err := checkOpencodeVersion(detect, "0.3.0"); err != nil {
+		t.Fatalf("equal to minimum: %v", err)
+	}
+	if _, err := checkOpencodeVersion(detect, "0.3.1"); err == nil {
+		t.Fatalf("expected error below minimum")
+	}
+	if err := validateMinOpencodeVersion("latest"); err == nil {
+		t.Fatalf("expected invalid min_opencode_version to be rejected")
+	}
+}
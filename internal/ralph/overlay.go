@@ -0,0 +1,61 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+)
+
+// IterationOverlay overrides run options for a single iteration. Empty fields
+// keep the base value.
+type IterationOverlay struct {
+	Model   string `json:"model,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+	Variant string `json:"variant,omitempty"`
+	Specs   string `json:"specs,omitempty"`
+}
+
+// loadIterationOverlays reads a list of overlays from path, decoded as JSON
+// or YAML by its extension.
+func loadIterationOverlays(path string) ([]IterationOverlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading iterations file %s: %w", path, err)
+	}
+	var overlays []IterationOverlay
+	if err := codecForPath(path).Unmarshal(data, &overlays); err != nil {
+		return nil, fmt.Errorf("parsing iterations file %s: %w", path, err)
+	}
+	if len(overlays) == 0 {
+		return nil, fmt.Errorf("iterations file %s has no entries", path)
+	}
+	return overlays, nil
+}
+
+// overlayFor returns the overlay for the zero-based session iteration i; the
+// last entry repeats once the list is exhausted.
+func overlayFor(overlays []IterationOverlay, i int) IterationOverlay {
+	if len(overlays) == 0 {
+		return IterationOverlay{}
+	}
+	if i >= len(overlays) {
+		return overlays[len(overlays)-1]
+	}
+	return overlays[i]
+}
+
+// applyOverlay merges o onto copies of the base config and options.
+func applyOverlay(cfg Config, opts RunOptions, o IterationOverlay) (Config, RunOptions) {
+	if o.Model != "" {
+		opts.Model = o.Model
+	}
+	if o.Agent != "" {
+		opts.Agent = o.Agent
+	}
+	if o.Variant != "" {
+		opts.Variant = o.Variant
+	}
+	if o.Specs != "" {
+		cfg.SpecsFile = o.Specs
+	}
+	return cfg, opts
+}
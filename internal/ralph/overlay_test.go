@@ -0,0 +1,96 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadIterationOverlays(t *testing.T) {
+	withTempCWD(t)
+
+	if err := os.WriteFile("iters.json", []byte(`[{"model":"a"},{"agent":"b","specs":"S2.md"}]`), 0o644); err != nil {
+		t.Fatalf("write iterations file: %v", err)
+	}
+	overlays, err := loadIterationOverlays("iters.json")
+	if err != nil {
+		t.Fatalf("loadIterationOverlays: %v", err)
+	}
+	if len(overlays) != 2 || overlays[0].Model != "a" || overlays[1].Agent != "b" || overlays[1].Specs != "S2.md" {
+		t.Fatalf("unexpected overlays: %+v", overlays)
+	}
+
+	if err := os.WriteFile("iters.yaml", []byte("- model: a\n- agent: b\n  specs: S2.md\n"), 0o644); err != nil {
+		t.Fatalf("write YAML iterations file: %v", err)
+	}
+	overlays, err = loadIterationOverlays("iters.yaml")
+	if err != nil {
+		t.Fatalf("loadIterationOverlays YAML: %v", err)
+	}
+	if len(overlays) != 2 || overlays[0].Model != "a" || overlays[1].Agent != "b" || overlays[1].Specs != "S2.md" {
+		t.Fatalf("unexpected YAML overlays: %+v", overlays)
+	}
+
+	if err := os.WriteFile("bad.json", []byte(`{"model":"a"}`), 0o644); err != nil {
+		t.Fatalf("write bad file: %v", err)
+	}
+	if _, err := loadIterationOverlays("bad.json"); err == nil {
+		t.Fatalf("expected error for non-array iterations file")
+	}
+	if err := os.WriteFile("empty.json", []byte(`[]`), 0o644); err != nil {
+		t.Fatalf("write empty file: %v", err)
+	}
+	if _, err := loadIterationOverlays("empty.json"); err == nil {
+		t.Fatalf("expected error for empty iterations file")
+	}
+}
+
+func TestOverlaysApplyPerIterationAndLastRepeats(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.WriteFile("SPECS2.md", []byte("SECOND SPECS"), 0o644); err != nil {
+		t.Fatalf("write specs2: %v", err)
+	}
+
+	var got []OpencodeRunArgs
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			got = append(got, args)
+			return "", nil
+		},
+	}
+
+	opts := RunOptions{
+		MaxIterations: 4,
+		Quiet:         true,
+		Model:         "base/model",
+		Agent:         "base-agent",
+		Overlays: []IterationOverlay{
+			{Model: "small/model"},
+			{Model: "big/model", Agent: "closer", Specs: "SPECS2.md"},
+		},
+	}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("runner calls: got %d want %d", len(got), 4)
+	}
+	if got[0].Model != "small/model" || got[0].Agent != "base-agent" {
+		t.Fatalf("iteration 1: got model %q agent %q", got[0].Model, got[0].Agent)
+	}
+	if !strings.Contains(got[0].Prompt, "SPECS") || strings.Contains(got[0].Prompt, "SECOND SPECS") {
+		t.Fatalf("iteration 1: expected base specs in prompt")
+	}
+	for i := 1; i < 4; i++ {
+		if got[i].Model != "big/model" || got[i].Agent != "closer" {
+			t.Fatalf("iteration %d: got model %q agent %q", i+1, got[i].Model, got[i].Agent)
+		}
+		if !strings.Contains(got[i].Prompt, "SECOND SPECS") {
+			t.Fatalf("iteration %d: expected overlay specs in prompt", i+1)
+		}
+	}
+}
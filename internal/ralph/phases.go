@@ -0,0 +1,110 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// phaseOutcome records how one --phases specs file finished; Status is empty
+// for phases that never ran.
+type phaseOutcome struct {
+	Specs  string
+	Status string
+}
+
+// validatePhases checks that every --phases specs file exists before the
+// first phase starts.
+func validatePhases(phases []string, specsFlag string) error {
+	if len(phases) == 0 {
+		return nil
+	}
+	if specsFlag != "" {
+		return fmt.Errorf("--phases and --specs cannot be used together")
+	}
+	for _, specs := range phases {
+		if _, err := os.Stat(specs); err != nil {
+			return fmt.Errorf("invalid --phases: %w", err)
+		}
+	}
+	return nil
+}
+
+// runPhases runs the loop against each specs file in turn, each with a fresh
+// iteration budget, moving on only when a phase completes. The RunResult covers
+// all phases together.
+func runPhases(ctx context.Context, cfg Config, opts RunOptions, runner OpencodeRunner) ([]phaseOutcome, RunResult, error) {
+	outcomes := make([]phaseOutcome, len(opts.Phases))
+	for i, specs := range opts.Phases {
+		outcomes[i].Specs = specs
+	}
+
+	phaseOpts := opts
+	if cfg.PromptFile == stdinPromptFile {
+		if err := loadStdinPrompt(&phaseOpts); err != nil {
+			return outcomes, RunResult{Status: "unknown"}, err
+		}
+	}
+	// Notes carry across phases; drain them only once the last phase ends.
+	phaseOpts.DrainNotesTo = ""
+	phaseOpts.OnComplete = ""
+	phaseOpts.OnFailure = ""
+	phaseOpts.Webhook = ""
+	start := now()
+	if opts.QuietSummary {
+		// One status line for the whole command, printed below.
+		phaseOpts.QuietSummary = false
+		phaseOpts.Quiet = true
+	}
+	quiet := phaseOpts.Quiet || opts.LogFormat == logFormatJSON
+	useColor := resolveColor(opts.Color, quiet)
+
+	status := "unknown"
+	var total RunResult
+	for i, specs := range opts.Phases {
+		if !quiet {
+			header := fmt.Sprintf("##### Phase %d/%d: %s #####", i+1, len(opts.Phases), specs)
+			fmt.Printf("\n%s\n", styleIf(useColor, header, ansiCyan, ansiBold))
+		}
+		phaseCfg := cfg
+		phaseCfg.SpecsFile = specs
+		result, err := runLoop(ctx, phaseCfg, phaseOpts, runner)
+		status = result.Status
+		outcomes[i].Status = status
+		total.Iterations += result.Iterations
+		total.Tokens += result.Tokens
+		if err != nil {
+			total.Status, total.Duration = status, now().Sub(start)
+			return outcomes, total, err
+		}
+		if status != "complete" {
+			break
+		}
+	}
+
+	total.Status, total.Duration = status, now().Sub(start)
+	drainNotesOnFinish(opts, status)
+	runFinishHook(opts, status, nil)
+	notifyWebhook(opts, WebhookPayload{
+		Status:          status,
+		Iterations:      total.Iterations,
+		DurationSeconds: total.Duration.Seconds(),
+		Tokens:          total.Tokens,
+		Model:           opts.Model,
+		Meta:            opts.Meta,
+	})
+
+	if opts.QuietSummary {
+		fmt.Println(status)
+	} else if !quiet && !opts.DryRun {
+		fmt.Println("\n--- Phases ---")
+		for i, outcome := range outcomes {
+			label, codes := "NOT RUN", []string{ansiGray}
+			if outcome.Status != "" {
+				label, codes = statusStyle(outcome.Status)
+			}
+			fmt.Printf("%d. %s: %s\n", i+1, outcome.Specs, styleIf(useColor, label, codes...))
+		}
+	}
+	return outcomes, total, nil
+}
@@ -0,0 +1,185 @@
+package ralph
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func writePhaseFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestRunPhasesCompletesEachInTurn(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writePhaseFiles(t, map[string]string{"PHASE1.md": "- [ ] phase one task\n", "PHASE2.md": "- [ ] phase two task\n"})
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			// Each phase takes two iterations to complete.
+			if len(prompts)%2 == 0 {
+				return "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+			}
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Phases: []string{"PHASE1.md", "PHASE2.md"}}
+	var outcomes []phaseOutcome
+	out := captureOutput(t, &os.Stdout, func() {
+		var err error
+		outcomes, _, err = runPhases(context.Background(), cfg, opts, runner)
+		if err != nil {
+			t.Fatalf("runPhases: %v", err)
+		}
+	})
+
+	if len(prompts) != 4 {
+		t.Fatalf("expected a fresh two-iteration budget per phase (4 calls), got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "phase one task") || !strings.Contains(prompts[2], "phase two task") {
+		t.Fatalf("expected phase specs in prompts, got %q", prompts)
+	}
+	want := []phaseOutcome{{"PHASE1.md", "complete"}, {"PHASE2.md", "complete"}}
+	if len(outcomes) != 2 || outcomes[0] != want[0] || outcomes[1] != want[1] {
+		t.Fatalf("outcomes = %v, want %v", outcomes, want)
+	}
+	if !strings.Contains(out, "1. PHASE1.md: COMPLETE\n2. PHASE2.md: COMPLETE\n") {
+		t.Fatalf("expected per-phase outcomes in summary, got:\n%s", out)
+	}
+}
+
+func TestRunPhasesStopsAtMaxIterations(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writePhaseFiles(t, map[string]string{"PHASE1.md": "one", "PHASE2.md": "two"})
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Phases: []string{"PHASE1.md", "PHASE2.md"}}
+	var outcomes []phaseOutcome
+	out := captureOutput(t, &os.Stdout, func() {
+		var err error
+		outcomes, _, err = runPhases(context.Background(), cfg, opts, runner)
+		if err != nil {
+			t.Fatalf("runPhases: %v", err)
+		}
+	})
+
+	if calls != 2 {
+		t.Fatalf("expected phase 2 not to start, got %d calls", calls)
+	}
+	if outcomes[0].Status != "max_iterations" || outcomes[1].Status != "" {
+		t.Fatalf("unexpected outcomes %v", outcomes)
+	}
+	if !strings.Contains(out, "2. PHASE2.md: NOT RUN") {
+		t.Fatalf("expected phase 2 reported as not run, got:\n%s", out)
+	}
+}
+
+func TestRunPhasesRecordsEachPhaseInSummaryLog(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writePhaseFiles(t, map[string]string{"PHASE1.md": "one", "PHASE2.md": "two", "PHASE3.md": "three"})
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			if calls == 1 {
+				return "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+			}
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, SummaryLog: "runs.log", Phases: []string{"PHASE1.md", "PHASE2.md", "PHASE3.md"}}
+	if _, _, err := runPhases(context.Background(), cfg, opts, runner); err != nil {
+		t.Fatalf("runPhases: %v", err)
+	}
+
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record RunSummary
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("parse summary record %q: %v", line, err)
+		}
+		got = append(got, record.Phase+"="+record.Status)
+	}
+	want := "PHASE1.md=complete,PHASE2.md=max_iterations"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("summary log phases = %q, want %q", got, want)
+	}
+}
+
+func TestRunPhasesQuietSummaryPrintsOneStatus(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writePhaseFiles(t, map[string]string{"PHASE1.md": "one", "PHASE2.md": "two"})
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, QuietSummary: true, Phases: []string{"PHASE1.md", "PHASE2.md"}}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, _, err := runPhases(context.Background(), cfg, opts, runner); err != nil {
+			t.Fatalf("runPhases: %v", err)
+		}
+	})
+	if out != "complete\n" {
+		t.Fatalf("stdout = %q, want a single status line", out)
+	}
+}
+
+func TestValidatePhases(t *testing.T) {
+	withTempCWD(t)
+	writePhaseFiles(t, map[string]string{"PHASE1.md": "one"})
+
+	tests := []struct {
+		name    string
+		phases  []string
+		specs   string
+		wantErr bool
+	}{
+		{name: "none", phases: nil},
+		{name: "existing", phases: []string{"PHASE1.md"}},
+		{name: "missing file", phases: []string{"PHASE1.md", "PHASE9.md"}, wantErr: true},
+		{name: "with --specs", phases: []string{"PHASE1.md"}, specs: "SPECS.md", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validatePhases(tt.phases, tt.specs); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
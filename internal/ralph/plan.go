@@ -0,0 +1,103 @@
+package ralph
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// planRow is one line of --plan output: a range of session iterations that
+// share the same targets.
+type planRow struct {
+	Phase   string
+	First   int
+	Last    int
+	Model   string
+	Agent   string
+	Variant string
+	Specs   string
+	// Overlay is the 1-based --iterations-file entry applied, or 0 for none.
+	Overlay int
+}
+
+func (r planRow) sameTargets(o planRow) bool {
+	r.First, r.Last, o.First, o.Last = 0, 0, 0, 0
+	return r == o
+}
+
+// buildPlan works out what each iteration of the run would target, without
+// running anything. Consecutive iterations with identical targets collapse
+// into one row; with --phases every phase gets its own rows.
+func buildPlan(cfg Config, opts RunOptions) []planRow {
+	phases := opts.Phases
+	if len(phases) == 0 {
+		phases = []string{""}
+	}
+
+	var rows []planRow
+	for _, phase := range phases {
+		phaseCfg := cfg
+		if phase != "" {
+			phaseCfg.SpecsFile = phase
+		}
+		for i := 0; i < opts.MaxIterations; i++ {
+			overlay := 0
+			// --model-escalation overlays are not file entries worth numbering.
+			if len(opts.Overlays) > 0 && opts.ModelEscalation == "" {
+				overlay = min(i, len(opts.Overlays)-1) + 1
+			}
+			iterCfg, iterOpts := applyOverlay(phaseCfg, opts, overlayFor(opts.Overlays, i))
+			row := planRow{
+				Phase:   phase,
+				First:   i + 1,
+				Last:    i + 1,
+				Model:   iterOpts.Model,
+				Agent:   iterOpts.Agent,
+				Variant: iterOpts.Variant,
+				Specs:   iterCfg.SpecsFile,
+				Overlay: overlay,
+			}
+			if n := len(rows); n > 0 && rows[n-1].sameTargets(row) {
+				rows[n-1].Last = row.Last
+				continue
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// renderPlan formats rows as a table; unset values show as "-".
+func renderPlan(rows []planRow) string {
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	withPhases := len(rows) > 0 && rows[0].Phase != ""
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	header := "ITERATIONS\tMODEL\tAGENT\tVARIANT\tSPECS\tOVERLAY"
+	if withPhases {
+		header = "PHASE\t" + header
+	}
+	fmt.Fprintln(w, header)
+	for _, r := range rows {
+		iterations := fmt.Sprint(r.First)
+		if r.Last != r.First {
+			iterations = fmt.Sprintf("%d-%d", r.First, r.Last)
+		}
+		overlay := "-"
+		if r.Overlay > 0 {
+			overlay = fmt.Sprintf("#%d", r.Overlay)
+		}
+		if withPhases {
+			fmt.Fprintf(w, "%s\t", r.Phase)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", iterations, orDash(r.Model), orDash(r.Agent), orDash(r.Variant), r.Specs, overlay)
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
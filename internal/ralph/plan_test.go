@@ -0,0 +1,85 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildPlanReflectsOverlaysAndPhases(t *testing.T) {
+	cfg := DefaultConfig()
+	overlays := []IterationOverlay{
+		{Model: "fast/model"},
+		{Agent: "reviewer", Specs: "REVIEW.md"},
+		{Model: "big/model", Variant: "high"},
+	}
+
+	tests := []struct {
+		name string
+		opts RunOptions
+		want []planRow
+	}{
+		{
+			name: "no overlays collapses to one row",
+			opts: RunOptions{MaxIterations: 4, Model: "base/model", Agent: "build"},
+			want: []planRow{
+				{First: 1, Last: 4, Model: "base/model", Agent: "build", Specs: "SPECS.md"},
+			},
+		},
+		{
+			name: "overlays per iteration and last repeats",
+			opts: RunOptions{MaxIterations: 5, Model: "base/model", Agent: "build", Overlays: overlays},
+			want: []planRow{
+				{First: 1, Last: 1, Model: "fast/model", Agent: "build", Specs: "SPECS.md", Overlay: 1},
+				{First: 2, Last: 2, Model: "base/model", Agent: "reviewer", Specs: "REVIEW.md", Overlay: 2},
+				{First: 3, Last: 5, Model: "big/model", Agent: "build", Variant: "high", Specs: "SPECS.md", Overlay: 3},
+			},
+		},
+		{
+			name: "phases restart overlays",
+			opts: RunOptions{MaxIterations: 2, Model: "base/model", Phases: []string{"A.md", "B.md"}, Overlays: overlays[:1]},
+			want: []planRow{
+				{Phase: "A.md", First: 1, Last: 2, Model: "fast/model", Specs: "A.md", Overlay: 1},
+				{Phase: "B.md", First: 1, Last: 2, Model: "fast/model", Specs: "B.md", Overlay: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildPlan(cfg, tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("rows: got %+v want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("row %d: got %+v want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlanPrintsWithoutRunningOrTouchingState(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+	if err := os.WriteFile("iters.json", []byte(`[{"model":"first/model"},{"agent":"fixer"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureOutput(t, &os.Stdout, func() {
+		opts := RunOptions{Plan: true, MaxIterations: 3, Model: "base/model", IterationsFile: "iters.json"}
+		if err := RunWithOptions(opts, 3, 0, 0); err != nil {
+			t.Fatalf("RunWithOptions: %v", err)
+		}
+	})
+
+	for _, want := range []string{"ITERATIONS", "first/model", "#1", "2-3", "fixer", "#2"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in plan, got:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Fatalf("--plan must not write state, stat err: %v", err)
+	}
+}
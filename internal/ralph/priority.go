@@ -0,0 +1,52 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+var ioClassArgs = map[string]string{
+	"best-effort": "2",
+	"idle":        "3",
+}
+
+func validatePriority(niceLevel int, ioClass string) error {
+	if niceLevel < 0 || niceLevel > 19 {
+		return fmt.Errorf("invalid --nice value: %d (expected 0-19)", niceLevel)
+	}
+	if _, ok := ioClassArgs[ioClass]; ioClass != "" && !ok {
+		return fmt.Errorf("invalid --ionice value: %s (expected best-effort or idle)", ioClass)
+	}
+	return nil
+}
+
+// priorityPrefix returns a command prefix that launches the child with lower
+// CPU (nice) and IO (ionice) priority. Tools missing on this platform are
+// skipped and reported in warnings.
+func priorityPrefix(niceLevel int, ioClass string, lookPath func(string) (string, error)) (prefix []string, warnings []string) {
+	if niceLevel > 0 {
+		if _, err := lookPath("nice"); err != nil {
+			warnings = append(warnings, "nice not found; running opencode at normal CPU priority")
+		} else {
+			prefix = append(prefix, "nice", "-n", strconv.Itoa(niceLevel))
+		}
+	}
+	if class, ok := ioClassArgs[ioClass]; ok {
+		if _, err := lookPath("ionice"); err != nil {
+			warnings = append(warnings, "ionice not supported here; running opencode at normal IO priority")
+		} else {
+			prefix = append(prefix, "ionice", "-c", class)
+		}
+	}
+	return prefix, warnings
+}
+
+// opencodeCommand builds the exec.Cmd for runArgs, wrapped in any priority prefix.
+func opencodeCommand(ctx context.Context, runArgs OpencodeRunArgs) *exec.Cmd {
+	prefix, _ := priorityPrefix(runArgs.Nice, runArgs.IOClass, exec.LookPath)
+	argv := append(prefix, opencodeBinary(runArgs.Binary))
+	argv = append(argv, buildOpencodeArgs(runArgs)...)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
@@ -0,0 +1,56 @@
+package ralph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPriorityPrefix(t *testing.T) {
+	all := func(string) (string, error) { return "/usr/bin/tool", nil }
+	noIonice := func(name string) (string, error) {
+		if name == "ionice" {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + name, nil
+	}
+
+	tests := []struct {
+		name         string
+		nice         int
+		ioClass      string
+		lookPath     func(string) (string, error)
+		want         []string
+		wantWarnings int
+	}{
+		{name: "none", lookPath: all},
+		{name: "nice", nice: 10, lookPath: all, want: []string{"nice", "-n", "10"}},
+		{name: "ionice idle", ioClass: "idle", lookPath: all, want: []string{"ionice", "-c", "3"}},
+		{name: "both", nice: 5, ioClass: "best-effort", lookPath: all, want: []string{"nice", "-n", "5", "ionice", "-c", "2"}},
+		{name: "ionice unsupported", nice: 5, ioClass: "idle", lookPath: noIonice, want: []string{"nice", "-n", "5"}, wantWarnings: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := priorityPrefix(tt.nice, tt.ioClass, tt.lookPath)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("prefix: got %q want %q", got, tt.want)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Fatalf("warnings: got %q want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidatePriority(t *testing.T) {
+	if err := validatePriority(10, "idle"); err != nil {
+		t.Fatalf("validatePriority: %v", err)
+	}
+	if err := validatePriority(-1, ""); err == nil {
+		t.Fatalf("expected error for negative nice")
+	}
+	if err := validatePriority(0, "realtime"); err == nil {
+		t.Fatalf("expected error for unsupported io class")
+	}
+}
@@ -0,0 +1,67 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useFakeProcesses makes the given PIDs the only ones considered alive.
+func useFakeProcesses(t *testing.T, alive ...int) {
+	t.Helper()
+	orig := processAlive
+	processAlive = func(pid int) bool {
+		for _, p := range alive {
+			if p == pid {
+				return true
+			}
+		}
+		return false
+	}
+	t.Cleanup(func() { processAlive = orig })
+}
+
+func TestAcquireLockUsesProcessLiveness(t *testing.T) {
+	tests := []struct {
+		name       string
+		alive      []int
+		wantLocked bool
+		wantErr    string
+	}{
+		{name: "dead holder is cleaned", alive: nil, wantLocked: true},
+		{name: "live holder errors", alive: []int{4242}, wantErr: "(pid 4242); another run may be active"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			useFakeProcesses(t, tt.alive...)
+
+			lockPath := filepath.Join(ralphDir, "lock")
+			if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(lockPath, []byte("4242\n"), 0o644); err != nil {
+				t.Fatalf("write lock: %v", err)
+			}
+
+			locked, err := acquireLock(lockPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if locked {
+					t.Fatalf("expected locked=false")
+				}
+				return
+			}
+			if err != nil || locked != tt.wantLocked {
+				t.Fatalf("acquireLock = (%v, %v), want (%v, nil)", locked, err, tt.wantLocked)
+			}
+			pid, err := readLockPID(lockPath)
+			if err != nil || pid != os.Getpid() {
+				t.Fatalf("expected lock rewritten with our pid, got %d (%v)", pid, err)
+			}
+		})
+	}
+}
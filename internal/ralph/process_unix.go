@@ -0,0 +1,54 @@
+//go:build unix
+
+package ralph
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isProcessRunning probes pid with signal 0: ESRCH means it is gone, while
+// EPERM means it exists but belongs to someone else.
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	if err == nil {
+		return true
+	}
+
+	if errno, ok := err.(syscall.Errno); ok {
+		switch errno {
+		case syscall.ESRCH:
+			return false
+		case syscall.EPERM:
+			return true
+		}
+	}
+
+	return true
+}
+
+func (syscallGroupKiller) Kill(pgid int, sig syscall.Signal) error {
+	return syscall.Kill(-pgid, sig)
+}
+
+// startProcessGroup makes cmd the leader of a new process group, so the
+// group ID is the child's PID.
+func startProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func lockFileExclusive(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,74 @@
+//go:build unix
+
+package ralph
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsProcessRunning(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 0")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pid  int
+		want bool
+	}{
+		{"self", os.Getpid(), true},
+		{"exited child", cmd.Process.Pid, false},
+		{"invalid", 0, false},
+	}
+	for _, tt := range tests {
+		if got := isProcessRunning(tt.pid); got != tt.want {
+			t.Fatalf("%s: isProcessRunning(%d) = %v, want %v", tt.name, tt.pid, got, tt.want)
+		}
+	}
+}
+
+func TestRunInProcessGroup(t *testing.T) {
+	killer := &fakeGroupKiller{}
+	groups := newProcessGroups(killer)
+
+	cmd := exec.Command("sh", "-c", "exit 0")
+	if err := runInProcessGroup(cmd, groups); err != nil {
+		t.Fatalf("runInProcessGroup: %v", err)
+	}
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		t.Fatalf("expected the command to start in its own process group")
+	}
+	if len(groups.pgids) != 0 {
+		t.Fatalf("expected group to be untracked after exit, got %v", groups.pgids)
+	}
+}
+
+func TestRunOpencodeTimeoutKillsGroupAndKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	// The stand-in opencode prints partial notes, then hangs in a child
+	// process that holds stdout open.
+	script := "#!/bin/sh\necho '<ralph_notes>partial</ralph_notes>'\nsleep 30 &\nwait\n"
+	if err := os.WriteFile(filepath.Join(dir, "opencode"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake opencode: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	start := time.Now()
+	output, err := runOpencode(OpencodeRunArgs{Subcommand: "run", Prompt: "p", Timeout: 200 * time.Millisecond})
+	if !errors.Is(err, ErrIterationTimeout) {
+		t.Fatalf("expected ErrIterationTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > groupWaitDelay {
+		t.Fatalf("expected the hung group to be killed promptly, took %s", elapsed)
+	}
+	if !strings.Contains(output, "<ralph_notes>partial</ralph_notes>") {
+		t.Fatalf("expected partial output to be captured, got %q", output)
+	}
+}
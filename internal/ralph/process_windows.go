@@ -0,0 +1,88 @@
+//go:build windows
+
+package ralph
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	// stillActive is the exit code GetExitCodeProcess reports for a process
+	// that has not exited.
+	stillActive            = 259
+	lockfileExclusiveLock  = 0x2
+	createNewProcessGroup  = 0x200
+	errorAccessDenied      = syscall.Errno(5)
+	errorInvalidParameter  = syscall.Errno(87)
+	lockWholeFileBytesLow  = ^uint32(0)
+	lockWholeFileBytesHigh = ^uint32(0)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// isProcessRunning opens pid for a status query: a PID that cannot be opened
+// is gone unless access was denied, in which case it exists but belongs to
+// someone else; an open process is alive until it has an exit code.
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		if errors.Is(err, errorInvalidParameter) {
+			return false
+		}
+		return errors.Is(err, errorAccessDenied)
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
+
+// Kill terminates the process tree rooted at pgid. Windows has no signals to
+// forward, so every signal is a forced termination.
+func (syscallGroupKiller) Kill(pgid int, sig syscall.Signal) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pgid)).Run()
+}
+
+// startProcessGroup starts cmd in a new process group so console interrupts
+// aimed at ralph are not delivered to it.
+func startProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= createNewProcessGroup
+}
+
+func lockFileExclusive(f *os.File) error {
+	var overlapped syscall.Overlapped
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, uintptr(lockWholeFileBytesLow), uintptr(lockWholeFileBytesHigh), uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, uintptr(lockWholeFileBytesLow), uintptr(lockWholeFileBytesHigh), uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
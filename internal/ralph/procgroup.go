@@ -0,0 +1,83 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// processGroupKiller signals every process in a process group; tests
+// substitute a fake so nothing is actually killed.
+type processGroupKiller interface {
+	Kill(pgid int, sig syscall.Signal) error
+}
+
+type syscallGroupKiller struct{}
+
+// processGroups tracks the process groups of running opencode invocations so
+// they can be torn down, grandchildren included, when the run is interrupted.
+type processGroups struct {
+	mu     sync.Mutex
+	pgids  map[int]struct{}
+	killer processGroupKiller
+}
+
+func newProcessGroups(killer processGroupKiller) *processGroups {
+	return &processGroups{pgids: map[int]struct{}{}, killer: killer}
+}
+
+var opencodeGroups = newProcessGroups(syscallGroupKiller{})
+
+func (g *processGroups) add(pgid int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pgids[pgid] = struct{}{}
+}
+
+func (g *processGroups) remove(pgid int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.pgids, pgid)
+}
+
+// killAll sends sig to every tracked group and stops tracking them.
+func (g *processGroups) killAll(sig syscall.Signal) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var errs []error
+	for pgid := range g.pgids {
+		if err := g.killer.Kill(pgid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			errs = append(errs, fmt.Errorf("killing process group %d: %w", pgid, err))
+		}
+		delete(g.pgids, pgid)
+	}
+	return errors.Join(errs...)
+}
+
+// groupWaitDelay bounds how long Wait blocks on output pipes still held by
+// stray descendants once a cancelled group has been killed.
+const groupWaitDelay = 5 * time.Second
+
+// runInProcessGroup runs cmd as the leader of a new process group, tracked in
+// groups for the duration of the call.
+func runInProcessGroup(cmd *exec.Cmd, groups *processGroups) error {
+	startProcessGroup(cmd)
+	if cmd.Cancel != nil {
+		// Cancelling the command's context (e.g. --timeout) kills the whole
+		// group, not just the direct child.
+		cmd.Cancel = func() error {
+			return groups.killer.Kill(cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = groupWaitDelay
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pgid := cmd.Process.Pid
+	groups.add(pgid)
+	defer groups.remove(pgid)
+	return cmd.Wait()
+}
@@ -0,0 +1,47 @@
+package ralph
+
+import (
+	"sort"
+	"syscall"
+	"testing"
+)
+
+type killCall struct {
+	pgid int
+	sig  syscall.Signal
+}
+
+type fakeGroupKiller struct {
+	calls []killCall
+}
+
+func (k *fakeGroupKiller) Kill(pgid int, sig syscall.Signal) error {
+	k.calls = append(k.calls, killCall{pgid, sig})
+	if pgid == 3 {
+		return syscall.ESRCH
+	}
+	return nil
+}
+
+func TestProcessGroupsKillAll(t *testing.T) {
+	killer := &fakeGroupKiller{}
+	groups := newProcessGroups(killer)
+	groups.add(1)
+	groups.add(2)
+	groups.add(3)
+	groups.remove(2)
+
+	if err := groups.killAll(syscall.SIGTERM); err != nil {
+		t.Fatalf("killAll: %v (already-exited groups should be ignored)", err)
+	}
+	sort.Slice(killer.calls, func(i, j int) bool { return killer.calls[i].pgid < killer.calls[j].pgid })
+	want := []killCall{{1, syscall.SIGTERM}, {3, syscall.SIGTERM}}
+	if len(killer.calls) != len(want) || killer.calls[0] != want[0] || killer.calls[1] != want[1] {
+		t.Fatalf("kill calls: got %v want %v", killer.calls, want)
+	}
+
+	killer.calls = nil
+	if err := groups.killAll(syscall.SIGTERM); err != nil || len(killer.calls) != 0 {
+		t.Fatalf("expected groups to be forgotten after killAll, got %v (%v)", killer.calls, err)
+	}
+}
@@ -0,0 +1,178 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PromptData is everything that goes into a constructed prompt.
+type PromptData struct {
+	Prompt        string
+	Conventions   string
+	Specs         string
+	Notes         string
+	Iteration     int
+	MaxIterations int
+	// Order lists the context sections in render order; empty uses defaultPromptOrder.
+	Order []string
+	// PreviousOutput is the prior iteration's output; empty omits the section.
+	PreviousOutput string
+	// Check is the --check-command result; nil omits the section.
+	Check *CheckResult
+	// Feedback holds extra guidance for this iteration, rendered after the iteration line.
+	Feedback []string
+	// Template, when set, replaces the built-in layout (and Order); previous
+	// output and guidance are still appended after it.
+	Template *template.Template
+}
+
+func constructPrompt(promptMD, conventionsMD, specsMD, notesMD string, iteration, maxIterations int) string {
+	return renderPrompt(PromptData{
+		Prompt:        promptMD,
+		Conventions:   conventionsMD,
+		Specs:         specsMD,
+		Notes:         notesMD,
+		Iteration:     iteration,
+		MaxIterations: maxIterations,
+	})
+}
+
+// Prompt section names accepted by --prompt-order.
+const (
+	sectionPrompt      = "prompt"
+	sectionConventions = "conventions"
+	sectionSpecs       = "specs"
+	sectionNotes       = "notes"
+)
+
+// defaultPromptOrder is the built-in section order.
+var defaultPromptOrder = []string{sectionPrompt, sectionConventions, sectionSpecs, sectionNotes}
+
+// validatePromptOrder checks a --prompt-order list: every section must appear
+// exactly once. An empty list keeps the default order.
+func validatePromptOrder(order []string) error {
+	if len(order) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, name := range order {
+		if !slices.Contains(defaultPromptOrder, name) {
+			return fmt.Errorf("invalid --prompt-order: unknown section %q (expected %s)", name, strings.Join(defaultPromptOrder, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("invalid --prompt-order: section %q listed twice", name)
+		}
+		seen[name] = true
+	}
+	for _, name := range defaultPromptOrder {
+		if !seen[name] {
+			return fmt.Errorf("invalid --prompt-order: missing section %q", name)
+		}
+	}
+	return nil
+}
+
+func renderPrompt(data PromptData) string {
+	sections := map[string]string{
+		sectionPrompt:      fmt.Sprintf("<prompt>\n%s\n</prompt>\n\n", data.Prompt),
+		sectionConventions: fmt.Sprintf("<conventions>\n%s\n</conventions>\n\n", data.Conventions),
+		sectionSpecs: fmt.Sprintf(`NOTE: The full, current contents of the specs are included below in <specs>.
+Do not re-read SPECS.md unless you have modified it and need to confirm your updates.
+
+<specs>
+%s
+</specs>
+
+`, data.Specs),
+		sectionNotes: fmt.Sprintf("<ralph_notes_history>\n%s\n</ralph_notes_history>\n\n", data.Notes),
+	}
+	order := data.Order
+	if len(order) == 0 {
+		order = defaultPromptOrder
+	}
+
+	var p strings.Builder
+	p.WriteString("You are operating in Ralph Wiggum mode.\n\n## Context Files\n\n")
+	for _, name := range order {
+		p.WriteString(sections[name])
+	}
+	fmt.Fprintf(&p, "## Current Iteration\nIteration: %d of %d\n", data.Iteration, data.MaxIterations)
+	prompt := p.String()
+
+	if data.Template != nil {
+		// The template was checked when loaded; if it still fails, say so
+		// and keep the run going with the built-in layout.
+		if custom, err := executePromptTemplate(data.Template, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; using the built-in prompt\n", err)
+		} else {
+			prompt = custom
+		}
+	}
+
+	if data.Check != nil {
+		prompt += renderCheckResults(*data.Check)
+	}
+
+	if data.PreviousOutput != "" {
+		prompt += fmt.Sprintf("\n<previous_output>\n%s\n</previous_output>\n", data.PreviousOutput)
+	}
+
+	if len(data.Feedback) > 0 {
+		var b strings.Builder
+		b.WriteString(prompt)
+		b.WriteString("\n## Guidance\n")
+		for _, feedback := range data.Feedback {
+			fmt.Fprintf(&b, "%s\n", feedback)
+		}
+		prompt = b.String()
+	}
+	return prompt
+}
+
+// EscalationHint is guidance added to the prompt once a run has used At
+// (a fraction from 0 to 1) of its iteration budget.
+type EscalationHint struct {
+	At      float64 `json:"at"`
+	Message string  `json:"message"`
+}
+
+func defaultEscalationHints() []EscalationHint {
+	return []EscalationHint{
+		{At: 0.5, Message: "You have used half of your iteration budget. Focus on the highest-priority remaining tasks."},
+		{At: 0.8, Message: "You have used 80% of your iteration budget. Prioritize finishing; do not start large new work."},
+		{At: 1.0, Message: "This is the final iteration. Leave the project in a working, committed state and record clear notes for the next run."},
+	}
+}
+
+// escalationHint returns the message for the highest threshold reached by
+// sessionIteration out of maxIterations, or "" if none applies.
+func escalationHint(hints []EscalationHint, sessionIteration, maxIterations int) string {
+	if maxIterations <= 0 {
+		return ""
+	}
+	sorted := append([]EscalationHint(nil), hints...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].At < sorted[j].At })
+
+	used := float64(sessionIteration) / float64(maxIterations)
+	message := ""
+	for _, hint := range sorted {
+		if used >= hint.At {
+			message = hint.Message
+		}
+	}
+	return message
+}
+
+// tailChars keeps the last maxChars characters of output, prefixing a marker
+// when anything was dropped. maxChars <= 0 means unlimited.
+func tailChars(output string, maxChars int) string {
+	runes := []rune(output)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return output
+	}
+	return fmt.Sprintf("[output truncated: kept last %d of %d characters]\n", maxChars, len(runes)) + string(runes[len(runes)-maxChars:])
+}
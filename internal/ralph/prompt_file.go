@@ -0,0 +1,37 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+)
+
+// promptFileMessage is the short prompt sent in place of the real one when
+// the prompt travels as an attached file.
+const promptFileMessage = "Your full instructions are in the attached file %s. Read it and follow it exactly."
+
+// promptFileRunner hands the prompt to opencode as an attached file instead of
+// an argument, keeping argv small for very large prompts. The temporary file
+// is removed once the call returns, whether or not it succeeded.
+type promptFileRunner struct {
+	inner OpencodeRunner
+}
+
+func (r promptFileRunner) Run(args OpencodeRunArgs) (string, error) {
+	f, err := os.CreateTemp("", "ralph-prompt-*.md")
+	if err != nil {
+		return "", fmt.Errorf("creating prompt file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(args.Prompt); err != nil {
+		f.Close()
+		return "", fmt.Errorf("writing prompt file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("writing prompt file: %w", err)
+	}
+
+	args.Files = append(append([]string{}, args.Files...), f.Name())
+	args.Prompt = fmt.Sprintf(promptFileMessage, f.Name())
+	return r.inner.Run(args)
+}
@@ -0,0 +1,60 @@
+package ralph
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPromptViaFile(t *testing.T) {
+	for _, runErr := range []error{nil, errors.New("opencode failed")} {
+		name := "success"
+		if runErr != nil {
+			name = "error"
+		}
+		t.Run(name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			var promptPath, content string
+			var sent OpencodeRunArgs
+			runner := &fakeRunner{
+				runFunc: func(args OpencodeRunArgs) (string, error) {
+					sent = args
+					promptPath = args.Files[len(args.Files)-1]
+					data, err := os.ReadFile(promptPath)
+					if err != nil {
+						t.Fatalf("prompt file missing during run: %v", err)
+					}
+					content = string(data)
+					return "", runErr
+				},
+			}
+
+			opts := RunOptions{MaxIterations: 1, Quiet: true, PromptViaFile: true, Files: []string{"extra.txt"}}
+			if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+
+			if !strings.Contains(content, "You are operating in Ralph Wiggum mode.") {
+				t.Fatalf("prompt file content: got %q", content)
+			}
+			if len(sent.Files) != 2 || sent.Files[0] != "extra.txt" {
+				t.Fatalf("expected prompt file appended to --file list, got %v", sent.Files)
+			}
+			if !strings.Contains(sent.Prompt, promptPath) || strings.Contains(sent.Prompt, "Ralph Wiggum") {
+				t.Fatalf("expected short prompt referencing %s, got %q", promptPath, sent.Prompt)
+			}
+			argv := strings.Join(buildOpencodeArgs(sent), " ")
+			if !strings.Contains(argv, "--file "+promptPath) {
+				t.Fatalf("expected --file %s in argv, got %q", promptPath, argv)
+			}
+			if _, err := os.Stat(promptPath); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("expected prompt file removed, stat err %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdinPromptFile is the --prompt value that reads the prompt from stdin.
+const stdinPromptFile = "-"
+
+// promptStdin is where --prompt - reads from; tests replace it.
+var promptStdin io.Reader = os.Stdin
+
+// loadStdinPrompt reads the prompt for --prompt - into opts once, so every
+// iteration (and every --phases phase) gets the same buffered content rather
+// than reading stdin again.
+func loadStdinPrompt(opts *RunOptions) error {
+	if opts.stdinPrompt != "" {
+		return nil
+	}
+	data, err := io.ReadAll(promptStdin)
+	if err != nil {
+		return fmt.Errorf("reading prompt from stdin: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return errors.New("--prompt -: no prompt on stdin")
+	}
+	opts.stdinPrompt = string(data)
+	return nil
+}
+
+// readPrompt returns the prompt for an iteration: the buffered stdin prompt
+// for --prompt -, otherwise the prompt file's current contents.
+func readPrompt(cfg Config, opts RunOptions) (string, error) {
+	if cfg.PromptFile == stdinPromptFile {
+		return opts.stdinPrompt, nil
+	}
+	promptMD, err := readFile(cfg.PromptFile)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", cfg.PromptFile, err)
+	}
+	return promptMD, nil
+}
@@ -0,0 +1,62 @@
+package ralph
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func useStdinPrompt(t *testing.T, r io.Reader) {
+	t.Helper()
+	orig := promptStdin
+	promptStdin = r
+	t.Cleanup(func() { promptStdin = orig })
+}
+
+func TestPromptFromStdinIsReadOnce(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.Remove(cfg.PromptFile); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PromptFile = stdinPromptFile
+	useStdinPrompt(t, strings.NewReader("piped prompt body\n"))
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 2, Quiet: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 iterations, got %d", len(prompts))
+	}
+	for i, prompt := range prompts {
+		if !strings.Contains(prompt, "<prompt>\npiped prompt body\n") {
+			t.Fatalf("iteration %d: expected the stdin prompt, got:\n%s", i+1, prompt)
+		}
+	}
+}
+
+func TestPromptFromEmptyStdin(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	cfg.PromptFile = stdinPromptFile
+	useStdinPrompt(t, strings.NewReader("  \n"))
+
+	_, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, Quiet: true}, completeRunner())
+	if err == nil || !strings.Contains(err.Error(), "no prompt on stdin") {
+		t.Fatalf("expected an empty stdin error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package ralph
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// loadPromptTemplate parses the prompt_template file at path; "" means the
+// built-in layout. The template is also executed once against empty data so
+// references to unknown fields fail before the run starts.
+func loadPromptTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading prompt_template %s: %w", path, err)
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing prompt_template %s: %w (fields available: %s)", path, err, promptTemplateFields)
+	}
+	if err := tmpl.Execute(io.Discard, PromptData{}); err != nil {
+		return nil, fmt.Errorf("checking prompt_template %s: %w (fields available: %s)", path, err, promptTemplateFields)
+	}
+	return tmpl, nil
+}
+
+const promptTemplateFields = ".Prompt, .Conventions, .Specs, .Notes, .Iteration, .MaxIterations"
+
+// executePromptTemplate renders data through tmpl.
+func executePromptTemplate(tmpl *template.Template, data PromptData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("executing prompt_template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,258 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEscalationHintThresholds(t *testing.T) {
+	hints := defaultEscalationHints()
+
+	tests := []struct {
+		iteration int
+		max       int
+		want      string
+	}{
+		{iteration: 1, max: 10, want: ""},
+		{iteration: 4, max: 10, want: ""},
+		{iteration: 5, max: 10, want: "half of your iteration budget"},
+		{iteration: 7, max: 10, want: "half of your iteration budget"},
+		{iteration: 8, max: 10, want: "80% of your iteration budget"},
+		{iteration: 9, max: 10, want: "80% of your iteration budget"},
+		{iteration: 10, max: 10, want: "final iteration"},
+		{iteration: 1, max: 1, want: "final iteration"},
+	}
+
+	for _, tt := range tests {
+		got := escalationHint(hints, tt.iteration, tt.max)
+		if tt.want == "" {
+			if got != "" {
+				t.Fatalf("iteration %d/%d: expected no hint, got %q", tt.iteration, tt.max, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Fatalf("iteration %d/%d: got %q want substring %q", tt.iteration, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestEscalationHintCustomUnsorted(t *testing.T) {
+	hints := []EscalationHint{{At: 0.9, Message: "late"}, {At: 0.2, Message: "early"}}
+	if got := escalationHint(hints, 3, 10); got != "early" {
+		t.Fatalf("got %q want %q", got, "early")
+	}
+	if got := escalationHint(hints, 9, 10); got != "late" {
+		t.Fatalf("got %q want %q", got, "late")
+	}
+}
+
+func TestRenderPromptIncludesFeedback(t *testing.T) {
+	data := PromptData{Prompt: "P", Iteration: 8, MaxIterations: 10}
+	if strings.Contains(renderPrompt(data), "## Guidance") {
+		t.Fatalf("did not expect guidance section without feedback")
+	}
+
+	data.Feedback = []string{"finish up"}
+	out := renderPrompt(data)
+	if !strings.Contains(out, "Iteration: 8 of 10\n\n## Guidance\nfinish up\n") {
+		t.Fatalf("expected guidance after iteration line, got %q", out)
+	}
+}
+
+func TestEscalationHintsInjectedIntoRun(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	cfg.EscalationHints = []EscalationHint{{At: 0.5, Message: "HALFWAY"}}
+	writeContextFiles(t, cfg)
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return "", nil
+		},
+	}
+
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 4, Quiet: true, EscalationHints: true}, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	for i, prompt := range prompts {
+		want := i+1 >= 2
+		if got := strings.Contains(prompt, "HALFWAY"); got != want {
+			t.Fatalf("iteration %d: hint present=%v want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestTailChars(t *testing.T) {
+	if got := tailChars("abcdef", 0); got != "abcdef" {
+		t.Fatalf("unlimited: got %q", got)
+	}
+	if got := tailChars("abcdef", 2); got != "[output truncated: kept last 2 of 6 characters]\nef" {
+		t.Fatalf("truncated: got %q", got)
+	}
+}
+
+func TestIncludeLastOutput(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return "output of call " + string(rune('0'+len(prompts))), nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, IncludeLastOutput: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if strings.Contains(prompts[0], "<previous_output>") {
+		t.Fatalf("first iteration should not include previous output, got %q", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "<previous_output>\noutput of call 1\n</previous_output>") {
+		t.Fatalf("expected previous output section, got %q", prompts[1])
+	}
+}
+
+func TestRenderPromptDefaultOrderUnchanged(t *testing.T) {
+	got := constructPrompt("P", "C", "S", "N", 2, 5)
+	want := `You are operating in Ralph Wiggum mode.
+
+## Context Files
+
+<prompt>
+P
+</prompt>
+
+<conventions>
+C
+</conventions>
+
+NOTE: The full, current contents of the specs are included below in <specs>.
+Do not re-read SPECS.md unless you have modified it and need to confirm your updates.
+
+<specs>
+S
+</specs>
+
+<ralph_notes_history>
+N
+</ralph_notes_history>
+
+## Current Iteration
+Iteration: 2 of 5
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPromptOrder(t *testing.T) {
+	data := PromptData{Prompt: "P", Conventions: "C", Specs: "S", Notes: "N", Iteration: 1, MaxIterations: 1,
+		Order: []string{"notes", "specs", "prompt", "conventions"}}
+	got := renderPrompt(data)
+
+	positions := []int{
+		strings.Index(got, "<ralph_notes_history>"),
+		strings.Index(got, "<specs>"),
+		strings.Index(got, "<prompt>"),
+		strings.Index(got, "<conventions>"),
+		strings.Index(got, "## Current Iteration"),
+	}
+	for i := 1; i < len(positions); i++ {
+		if positions[i-1] < 0 || positions[i-1] >= positions[i] {
+			t.Fatalf("sections out of order %v:\n%s", positions, got)
+		}
+	}
+}
+
+func TestValidatePromptOrder(t *testing.T) {
+	tests := []struct {
+		order   []string
+		wantErr string
+	}{
+		{order: nil},
+		{order: []string{"prompt", "specs", "conventions", "notes"}},
+		{order: []string{"prompt", "specs", "conventions"}, wantErr: `missing section "notes"`},
+		{order: []string{"prompt", "specs", "conventions", "notes", "extra"}, wantErr: `unknown section "extra"`},
+		{order: []string{"prompt", "prompt", "conventions", "notes"}, wantErr: `"prompt" listed twice`},
+	}
+	for _, tt := range tests {
+		err := validatePromptOrder(tt.order)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%v: unexpected error %v", tt.order, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%v: got %v want error containing %q", tt.order, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPromptTemplateReplacesBuiltInLayout(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	tmpl := "TASK {{.Iteration}}/{{.MaxIterations}}\n[{{.Prompt}}|{{.Conventions}}|{{.Specs}}|{{.Notes}}]\n"
+	if err := os.WriteFile("prompt.tmpl", []byte(tmpl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PromptTemplate = "prompt.tmpl"
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return "", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 1, Quiet: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	want := "TASK 1/1\n[PROMPT|CONVENTIONS|SPECS|No notes yet.]\n"
+	if len(prompts) != 1 || prompts[0] != want {
+		t.Fatalf("prompt: got %q want %q", prompts, want)
+	}
+}
+
+func TestPromptTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "parse error", content: "{{.Prompt", want: "parsing prompt_template"},
+		{name: "unknown field", content: "{{.Tasks}}", want: "checking prompt_template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+			if err := os.WriteFile("prompt.tmpl", []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg.PromptTemplate = "prompt.tmpl"
+
+			_, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, Quiet: true}, completeRunner())
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), ".MaxIterations") {
+				t.Fatalf("expected %q error listing the fields, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -2,18 +2,19 @@ package ralph
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 //go:embed templates/*
@@ -23,6 +24,7 @@ var templates embed.FS
 type RunOptions struct {
 	MaxIterations   int
 	MaxPerHour      int
+	MaxPerMinute    int
 	MaxPerDay       int
 	Prompt          string
 	Conventions     string
@@ -31,16 +33,152 @@ type RunOptions struct {
 	Format          string
 	ContinueSession bool
 	Session         string
-	Files           []string
-	Title           string
-	Variant         string
-	Attach          string
-	Port            int
-	Quiet           bool
-	Model           string
-	Verbose         bool
-	DryRun          bool
-	Delay           float64
+	// SessionFile holds a session ID carried between runs: read for the
+	// first iteration and rewritten after each successful one.
+	SessionFile string
+	Files       []string
+	Title       string
+	Variant     string
+	Attach      string
+	Port        int
+	Quiet       bool
+	Model       string
+	Verbose     bool
+	DryRun      bool
+	Delay       float64
+	// DelayJitter adds up to this many seconds, at random, to each delay.
+	DelayJitter       float64
+	SummaryLog        string
+	PromptArgStyle    string
+	RunID             string
+	MaxNotesChars     int
+	RetryOnTruncation bool
+	// ModelInherited marks Model as a flag default (from RALPH_MODEL) rather
+	// than an explicit --model.
+	ModelInherited bool
+	// FormatInherited marks Format as a flag default rather than an explicit --format.
+	FormatInherited bool
+	// PreferConfigModel lets the config file's model beat an inherited Model.
+	PreferConfigModel bool
+	OutputDir         string
+	Color             string
+	OutputFilter      string
+	MaxRuntime        time.Duration
+	IterationsFile    string
+	// ModelEscalation is a --model-escalation schedule such as
+	// "ollama/small:3,anthropic/big"; it is expanded into Overlays.
+	ModelEscalation   string
+	Overlays          []IterationOverlay
+	AllowUnsafeCWD    bool
+	Nice              int
+	IOClass           string
+	EscalationHints   bool
+	RequireNotes      bool
+	StrictNotes       bool
+	DelayRatio        float64
+	DelayMin          time.Duration
+	DelayMax          time.Duration
+	IncludeLastOutput bool
+	LastOutputChars   int
+	Resume            bool
+	// ResumeLast continues the previous run's session whenever it ended,
+	// failing when there is no previous run (the resume command).
+	ResumeLast         bool
+	PrettyJSONLogs     bool
+	MaxPromptChars     int
+	SummarizeNotes     bool
+	StrictFormat       bool
+	PromptViaFile      bool
+	AutoExtend         int
+	AutoExtendCap      int
+	OnMaxIterationsCmd string
+	// OnComplete runs once the run completes; OnFailure once it ends
+	// failed or at max iterations. Both default to the config keys.
+	OnComplete string
+	OnFailure  string
+	// Webhook receives a WebhookPayload POST when the run ends with a
+	// terminal status; it defaults to the webhook_url config key.
+	Webhook string
+	// OpencodeBin is the opencode binary to run; it defaults to the
+	// opencode_bin config key, then "opencode" on PATH.
+	OpencodeBin     string
+	Strict          bool
+	DedupeNotes     bool
+	PromptOrder     []string
+	BackupSpecs     bool
+	BackupSpecsKeep int
+	// QuietSummary suppresses status output like Quiet, without streaming
+	// opencode output, and prints only the final status on stdout.
+	QuietSummary bool
+	// TokenBudget stops the run once the tokens reported by --format json
+	// output exceed it (0 = unlimited).
+	TokenBudget int
+	// EditSpecs opens the specs file in $EDITOR before the first iteration;
+	// EditIfTTY skips the editor instead of failing without a terminal.
+	EditSpecs bool
+	EditIfTTY bool
+	// SlowIterationWarning flags opencode calls that take longer than this
+	// (0 = never).
+	SlowIterationWarning time.Duration
+	// Timeout kills an opencode call, and its children, after this many
+	// seconds (0 = no limit).
+	Timeout int
+	// Phases runs the loop against each specs file in turn, starting the
+	// next only once the previous one completes.
+	Phases []string
+	// Retries re-runs a failed opencode call up to this many times within
+	// the same iteration, waiting RetryBackoff seconds, doubled each time.
+	Retries      int
+	RetryBackoff float64
+	// MaxConsecutiveFailures stops the run with status "failed" after this
+	// many iterations in a row whose opencode call failed, or, under
+	// RequireNotes, that produced no notes (0 = never).
+	MaxConsecutiveFailures int
+	// Commit commits the working tree after each successful iteration.
+	Commit bool
+	// CheckCommand runs before each iteration; its output and exit code go
+	// into the prompt, trimmed to the last CheckOutputBytes bytes.
+	CheckCommand     string
+	CheckOutputBytes int
+	// NotesTail limits the notes in the prompt to the last N iteration
+	// entries (0 = all).
+	NotesTail int
+	// WaitOnLimit sleeps until a full rate window has room again instead of
+	// stopping the run as rate_limited.
+	WaitOnLimit bool
+	// LogFormat "json" replaces the human output with one JSON object per
+	// iteration and a final summary object on stdout.
+	LogFormat string
+	// Plan prints what each iteration would target, then exits without
+	// running opencode or touching state.
+	Plan bool
+	// MergeStreams sets how streamed opencode stdout and stderr combine:
+	// "ordered" or "separate" (the default).
+	MergeStreams string
+	// DrainNotesTo copies the notes file to this path once the run ends with
+	// a terminal status; DrainNotesMove removes the original as well.
+	DrainNotesTo   string
+	DrainNotesMove bool
+	// Meta is free-form key/value metadata recorded with the run.
+	Meta map[string]string
+	// DryRunAll is a --dry-run that prints the prompt for every iteration
+	// up to MaxIterations, re-reading notes and specs each time.
+	DryRunAll bool
+	// PromptOut writes the first iteration's prompt to this file and exits,
+	// like a silent --dry-run.
+	PromptOut string
+	// NoLock skips .ralph/lock for isolated workspaces where no other run
+	// can share the state files.
+	NoLock bool
+	// StopWhenSpecsComplete ends the run as complete once every checkbox
+	// task in the specs is checked, without waiting for <ralph_status>.
+	StopWhenSpecsComplete bool
+	// TrackSpecs prints how each iteration changed the specs file and adds
+	// the checklist progress to the summary.
+	TrackSpecs bool
+
+	// stdinPrompt is the prompt read once for --prompt -.
+	stdinPrompt string
 }
 
 const (
@@ -51,13 +189,29 @@ const (
 	lockFile   = ".ralph/lock"
 )
 
+// InitOptions customizes Init.
+type InitOptions struct {
+	// SpecsFrom is an http(s) URL whose plaintext or markdown body seeds the
+	// specs file instead of the stub template.
+	SpecsFrom string
+}
+
 // Init creates .ralph/ and initial files from templates.
 func Init() error {
+	return InitWithOptions(InitOptions{})
+}
+
+// InitWithOptions creates .ralph/ and initial files, seeding the specs file
+// from opts.SpecsFrom when set.
+func InitWithOptions(opts InitOptions) error {
 	if err := os.MkdirAll(ralphDir, 0755); err != nil {
 		return fmt.Errorf("creating %s directory: %w", ralphDir, err)
 	}
 
-	cfg := LoadConfig()
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
 
 	if err := createFromTemplate(cfg.PromptFile, "templates/PROMPT.md"); err != nil {
 		return err
@@ -65,12 +219,17 @@ func Init() error {
 	if err := createFromTemplate(cfg.ConventionsFile, "templates/CONVENTIONS.md"); err != nil {
 		return err
 	}
-	if err := createFromTemplate(cfg.SpecsFile, "templates/SPECS.md"); err != nil {
+	if opts.SpecsFrom != "" {
+		if err := seedSpecs(httpClient, opts.SpecsFrom, cfg.SpecsFile); err != nil {
+			return err
+		}
+	} else if err := createFromTemplate(cfg.SpecsFile, "templates/SPECS.md"); err != nil {
 		return err
 	}
 
-	if _, err := os.Stat(configFile); errors.Is(err, os.ErrNotExist) {
-		if err := SaveConfig(cfg); err != nil {
+	if !configExists() {
+		// Write plain defaults: RALPH_* overrides belong to this shell only.
+		if err := SaveConfig(DefaultConfig()); err != nil {
 			return err
 		}
 		fmt.Println("Created .ralph/config.json")
@@ -103,7 +262,23 @@ func createFromTemplate(destPath, templatePath string) error {
 
 // RunWithOptions executes iterations using opts, falling back to defaults.
 func RunWithOptions(opts RunOptions, defaultMaxIterations, defaultMaxPerHour, defaultMaxPerDay int) error {
-	cfg := LoadConfig()
+	_, err := runWithOptions(context.Background(), opts, defaultMaxIterations, defaultMaxPerHour, defaultMaxPerDay)
+	return err
+}
+
+// runWithOptions resolves opts against config and defaults, then runs the
+// loop until it finishes or ctx is canceled.
+func runWithOptions(ctx context.Context, opts RunOptions, defaultMaxIterations, defaultMaxPerHour, defaultMaxPerDay int) (RunResult, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return RunResult{}, err
+	}
+
+	if !opts.AllowUnsafeCWD {
+		if err := checkSafeWorkingDir(cfg.BlockedDirs); err != nil {
+			return RunResult{}, err
+		}
+	}
 
 	maxIterations := opts.MaxIterations
 	if maxIterations == 0 {
@@ -130,32 +305,248 @@ func RunWithOptions(opts RunOptions, defaultMaxIterations, defaultMaxPerHour, de
 		cfg.SpecsFile = opts.Specs
 	}
 
-	modelToUse := opts.Model
-	if modelToUse == "" {
-		modelToUse = cfg.Model
+	configModel := cfg.Model
+	if opts.PreferConfigModel {
+		// RALPH_MODEL already arrives as the inherited --model, so only the
+		// file's own model may take precedence over it.
+		configModel = loadConfigFile().Model
 	}
+	modelToUse := resolveModel(opts.Model, !opts.ModelInherited, configModel, opts.PreferConfigModel)
 
-	if opts.Format != "" && opts.Format != "default" && opts.Format != "json" {
-		return fmt.Errorf("invalid --format value: %s (expected default or json)", opts.Format)
+	if err := validateFormat(cfg.Format); err != nil {
+		return RunResult{}, fmt.Errorf("invalid format in %s: %s (expected default or json)", activeConfigFormat().Path, cfg.Format)
+	}
+	opts.Format = resolveFormat(opts.Format, !opts.FormatInherited, cfg.Format)
+	if err := validateFormat(opts.Format); err != nil {
+		return RunResult{}, fmt.Errorf("invalid --format value: %s (expected default or json)", opts.Format)
+	}
+	if err := validateSessionFile(&opts); err != nil {
+		return RunResult{}, err
+	}
+	if opts.ResumeLast {
+		continueSession, session, err := lastSession(loadState())
+		if err != nil {
+			return RunResult{}, err
+		}
+		if !opts.ContinueSession && opts.Session == "" {
+			opts.ContinueSession, opts.Session = continueSession, session
+		}
+	}
+	if opts.Resume && !opts.ContinueSession && opts.Session == "" {
+		window, err := parseResumeWindow(cfg.ResumeWindow)
+		if err != nil {
+			return RunResult{}, err
+		}
+		opts.ContinueSession, opts.Session = resumeSession(loadState(), window, now())
 	}
 	if opts.ContinueSession && opts.Session != "" {
-		return fmt.Errorf("invalid flags: --continue and --session are mutually exclusive")
+		return RunResult{}, fmt.Errorf("invalid flags: --continue and --session are mutually exclusive")
+	}
+
+	if opts.PromptArgStyle == "" {
+		opts.PromptArgStyle = cfg.PromptArgStyle
+	}
+	if err := validatePromptArgStyle(opts.PromptArgStyle); err != nil {
+		return RunResult{}, err
+	}
+	if err := validateColorMode(opts.Color); err != nil {
+		return RunResult{}, err
+	}
+	if err := validatePriority(opts.Nice, opts.IOClass); err != nil {
+		return RunResult{}, err
+	}
+	if err := validatePromptOrder(opts.PromptOrder); err != nil {
+		return RunResult{}, err
+	}
+	if err := checkExitCodePolicy(cfg.ExitCodePolicy); err != nil {
+		return RunResult{}, err
+	}
+	if opts.MaxConsecutiveFailures < 0 {
+		return RunResult{}, fmt.Errorf("invalid --max-consecutive-failures %d: must not be negative", opts.MaxConsecutiveFailures)
+	}
+	if opts.Retries < 0 {
+		return RunResult{}, fmt.Errorf("invalid --retries %d: must not be negative", opts.Retries)
+	}
+	if opts.RetryBackoff < 0 {
+		return RunResult{}, fmt.Errorf("invalid --retry-backoff %g: must not be negative", opts.RetryBackoff)
+	}
+	if opts.Timeout < 0 {
+		return RunResult{}, fmt.Errorf("invalid --timeout %d: must not be negative", opts.Timeout)
+	}
+	if opts.SlowIterationWarning < 0 {
+		return RunResult{}, fmt.Errorf("invalid --warn-on-slow-iteration %s: must not be negative", opts.SlowIterationWarning)
+	}
+	if opts.TokenBudget < 0 {
+		return RunResult{}, fmt.Errorf("invalid --token-budget %d: must not be negative", opts.TokenBudget)
+	}
+	if opts.TokenBudget > 0 && opts.Format != "json" {
+		return RunResult{}, fmt.Errorf("--token-budget requires --format json, which reports token usage")
+	}
+	if opts.NotesTail < 0 {
+		return RunResult{}, fmt.Errorf("invalid --notes-tail %d: must not be negative", opts.NotesTail)
+	}
+	if opts.AutoExtend < 0 {
+		return RunResult{}, fmt.Errorf("invalid --auto-extend %d: must not be negative", opts.AutoExtend)
+	}
+	if opts.AutoExtend > 0 && opts.AutoExtendCap <= 0 {
+		return RunResult{}, fmt.Errorf("--auto-extend requires --auto-extend-cap")
+	}
+	if opts.DelayJitter < 0 {
+		return RunResult{}, fmt.Errorf("invalid --delay-jitter %v: must not be negative", opts.DelayJitter)
+	}
+	if opts.DelayRatio < 0 {
+		return RunResult{}, fmt.Errorf("invalid --delay-ratio %v: must not be negative", opts.DelayRatio)
+	}
+	if opts.DelayMax > 0 && opts.DelayMin > opts.DelayMax {
+		return RunResult{}, fmt.Errorf("--delay-min %s exceeds --delay-max %s", opts.DelayMin, opts.DelayMax)
+	}
+	if opts.PromptOut != "" {
+		// --prompt-out is a dry run that writes only the prompt.
+		opts.DryRun = true
+	}
+	if opts.DryRunAll {
+		opts.DryRun = true
+	}
+	if !opts.DryRun {
+		_, warnings := priorityPrefix(opts.Nice, opts.IOClass, exec.LookPath)
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+	}
+	if err := validateOpencodeSubcommand(cfg.OpencodeSubcommand); err != nil {
+		return RunResult{}, err
+	}
+
+	opts.Commit = opts.Commit || cfg.CommitEachIteration
+
+	if err := validateMergeStreams(opts.MergeStreams); err != nil {
+		return RunResult{}, err
+	}
+	if err := validateLogFormat(opts.LogFormat); err != nil {
+		return RunResult{}, err
+	}
+	if opts.LogFormat == logFormatJSON && opts.QuietSummary {
+		return RunResult{}, fmt.Errorf("--log-format json cannot be combined with --quiet-summary")
+	}
+	if opts.DrainNotesMove && opts.DrainNotesTo == "" {
+		return RunResult{}, fmt.Errorf("--move requires --drain-notes-to")
+	}
+
+	if err := validatePhases(opts.Phases, opts.Specs); err != nil {
+		return RunResult{}, err
+	}
+
+	if opts.IterationsFile != "" {
+		overlays, err := loadIterationOverlays(opts.IterationsFile)
+		if err != nil {
+			return RunResult{}, err
+		}
+		opts.Overlays = overlays
+	}
+	if opts.ModelEscalation != "" {
+		if opts.IterationsFile != "" {
+			return RunResult{}, fmt.Errorf("--model-escalation cannot be combined with --iterations-file; set model in the overlays instead")
+		}
+		overlays, err := parseModelEscalation(opts.ModelEscalation)
+		if err != nil {
+			return RunResult{}, err
+		}
+		opts.Overlays = overlays
+	}
+
+	if opts.OpencodeBin == "" {
+		opts.OpencodeBin = opencodeBinary(cfg.OpencodeBin)
+	}
+	if !opts.DryRun && !opts.Plan {
+		if err := checkOpencodeInstalled(exec.LookPath, opts.OpencodeBin); err != nil {
+			return RunResult{}, err
+		}
+		warnOldOpencode(opts.OpencodeBin, cfg.MinOpencodeVersion)
+	}
+
+	opts.MaxIterations = maxIterations
+	opts.MaxPerHour = maxPerHour
+	opts.MaxPerDay = maxPerDay
+	if opts.MaxPerMinute == 0 {
+		opts.MaxPerMinute = cfg.MaxPerMinute
+	}
+	if opts.OnComplete == "" {
+		opts.OnComplete = cfg.OnComplete
+	}
+	if opts.OnFailure == "" {
+		opts.OnFailure = cfg.OnFailure
+	}
+	if opts.Webhook == "" {
+		opts.Webhook = cfg.WebhookURL
+	}
+	if err := validateWebhookURL(opts.Webhook); err != nil {
+		return RunResult{}, err
+	}
+	opts.Model = modelToUse
+
+	if opts.Plan {
+		fmt.Println(renderPlan(buildPlan(cfg, opts)))
+		return RunResult{}, nil
 	}
 
-	quiet := opts.Quiet
 	if opts.DryRun {
-		quiet = false
+		opts.Quiet = opts.PromptOut != ""
+		opts.QuietSummary = false
 	}
 
-	verbose := opts.Verbose || quiet
+	opts.Verbose = opts.Verbose || opts.Quiet
 	if opts.DryRun {
-		verbose = false
+		opts.Verbose = false
 	}
+	if opts.LogFormat == logFormatJSON {
+		// Keep stdout pure JSON: opencode's own output is not streamed.
+		opts.Quiet, opts.Verbose = false, false
+	}
+
+	if len(opts.Phases) > 0 {
+		_, result, err := runPhases(ctx, cfg, opts, execOpencodeRunner{})
+		return result, err
+	}
+	return runLoop(ctx, cfg, opts, execOpencodeRunner{})
+}
+
+// resolveModel picks the model for a run. By default any --model value beats
+// config; with preferConfig, config beats a flag value that was only inherited.
+func resolveModel(flagModel string, flagExplicit bool, configModel string, preferConfig bool) string {
+	if preferConfig && !flagExplicit && configModel != "" {
+		return configModel
+	}
+	if flagModel != "" {
+		return flagModel
+	}
+	return configModel
+}
+
+// resolveFormat picks the opencode output format: an explicit --format beats
+// the format config key, which beats the flag default.
+func resolveFormat(flagFormat string, flagExplicit bool, configFormat string) string {
+	if flagExplicit && flagFormat != "" {
+		return flagFormat
+	}
+	if configFormat != "" {
+		return configFormat
+	}
+	return flagFormat
+}
 
-	return runIterations(cfg, maxIterations, maxPerHour, maxPerDay, modelToUse, opts.Agent, opts.Format, opts.Variant, opts.Attach, opts.Port, opts.ContinueSession, opts.Session, opts.Files, opts.Title, quiet, verbose, opts.DryRun, opts.Delay)
+func validateFormat(format string) error {
+	switch format {
+	case "", "default", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid format: %s (expected default or json)", format)
+	}
 }
 
 type OpencodeRunArgs struct {
+	// Binary is the opencode executable; empty runs "opencode" from PATH.
+	Binary          string
+	Subcommand      string
 	Prompt          string
 	Model           string
 	Agent           string
@@ -167,8 +558,17 @@ type OpencodeRunArgs struct {
 	Session         string
 	Files           []string
 	Title           string
+	PromptArgStyle  string
+	Nice            int
+	IOClass         string
 	Quiet           bool
 	Verbose         bool
+	Timeout         time.Duration
+	// MergeStreams is the --merge-streams mode used while streaming output.
+	MergeStreams string
+	// LinePrefix is written before each streamed output line (see
+	// streamPrefix); empty streams the output as is.
+	LinePrefix string
 }
 
 type OpencodeRunner interface {
@@ -181,161 +581,773 @@ func (execOpencodeRunner) Run(args OpencodeRunArgs) (string, error) {
 	return runOpencode(args)
 }
 
-func runIterations(cfg Config, maxIterations, maxPerHour, maxPerDay int, model string, agent string, format string, variant string, attach string, port int, continueSession bool, session string, files []string, title string, quiet bool, verbose, dryRun bool, delay float64) (err error) {
-	return runIterationsWithRunner(cfg, maxIterations, maxPerHour, maxPerDay, model, agent, format, variant, attach, port, continueSession, session, files, title, quiet, verbose, dryRun, delay, execOpencodeRunner{})
+// runIterationsWithRunner runs the loop with opts already resolved against
+// config defaults and returns the run's outcome.
+func runIterationsWithRunner(cfg Config, opts RunOptions, runner OpencodeRunner) (RunResult, error) {
+	return runLoop(context.Background(), cfg, opts, runner)
 }
 
-func runIterationsWithRunner(cfg Config, maxIterations, maxPerHour, maxPerDay int, model string, agent string, format string, variant string, attach string, port int, continueSession bool, session string, files []string, title string, quiet bool, verbose, dryRun bool, delay float64, runner OpencodeRunner) (err error) {
-	startTime := time.Now()
-	showSummary := !quiet && !dryRun
-	useColor := shouldUseColor(quiet)
+// runLoop is runIterationsWithRunner, stopping early once ctx is canceled.
+func runLoop(ctx context.Context, cfg Config, opts RunOptions, runner OpencodeRunner) (result RunResult, err error) {
+	if opts.PromptViaFile {
+		runner = promptFileRunner{inner: runner}
+	}
+	promptTemplate, err := loadPromptTemplate(cfg.PromptTemplate)
+	if err != nil {
+		return RunResult{Status: "unknown"}, err
+	}
+	if cfg.PromptFile == stdinPromptFile {
+		if err := loadStdinPrompt(&opts); err != nil {
+			return RunResult{Status: "unknown"}, err
+		}
+	}
+	fileSession := ""
+	if opts.SessionFile != "" {
+		if fileSession, err = readSessionFile(opts.SessionFile); err != nil {
+			return RunResult{Status: "unknown"}, err
+		}
+	}
+	startTime := now()
+	runID := opts.RunID
+	if runID == "" {
+		runID = newRunID(startTime, runIDRand)
+	}
+	wroteRunHeader := false
+	truncatedCount := 0
+	toolCounts := map[toolActivity]int{}
+	notesMissing := false
+	lastOutput := ""
+	notesTrack := &notesTracker{path: notesFile}
+	notesCap := notesLimit{MaxBytes: cfg.MaxNotesBytes, Mode: cfg.NotesRotation}
+	lastNote, lastNoteIteration := "", 0
+	restoreDeletedNotes := func() {
+		if restored, err := notesTrack.restoreIfDeleted(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s was deleted during the run and could not be restored: %v\n", notesFile, err)
+		} else if restored {
+			fmt.Fprintf(os.Stderr, "Warning: %s was deleted during the run; restored it from the copy read earlier in this run\n", notesFile)
+		}
+	}
+	missingNotesCount := 0
+	sessionTokens := 0
+	timedOutCount := 0
+	retryCount := 0
+	failureCount := 0
+	consecutiveFailures := 0
+	slowIterations := 0
+	var gitStart gitSnapshot
+	inGit := false
+	jsonLog := opts.LogFormat == logFormatJSON
+	quiet := opts.Quiet || opts.QuietSummary || jsonLog
+	showSummary := !quiet && !opts.DryRun
+	useColor := resolveColor(opts.Color, quiet)
 	finalStatus := "unknown"
+	started := false
 	sessionIterations := 0
+	var state State
+	var specsTasks TaskCounts
 	defer func() {
-		if err != nil || !showSummary {
+		if err != nil && !errors.Is(err, ErrDryRun) && started {
+			finalStatus = "error"
+		}
+		result = RunResult{
+			RunID:          runID,
+			Status:         finalStatus,
+			Iterations:     sessionIterations,
+			Duration:       now().Sub(startTime).Truncate(time.Millisecond),
+			Tokens:         sessionTokens,
+			MinuteCount:    countRecentMinute(state.Timestamps, now()),
+			Truncated:      truncatedCount,
+			MissingNotes:   missingNotesCount,
+			Failures:       failureCount,
+			Retries:        retryCount,
+			TimedOut:       timedOutCount,
+			SlowIterations: slowIterations,
+			Activity:       formatToolCounts(toolCounts),
+			Meta:           opts.Meta,
+		}
+		result.HourCount, result.DayCount = countRecentIterations(state.Timestamps, now())
+		if opts.TrackSpecs {
+			result.Tasks = &specsTasks
+		}
+		if len(opts.Phases) > 0 {
+			result.Phase = cfg.SpecsFile
+		}
+		// A strict dry run still reports its summary. Other errors do once
+		// the loop has started, so the failure reaches the logs; errors
+		// during setup have nothing to summarize.
+		if err != nil && !errors.Is(err, ErrDryRun) && !started {
+			return
+		}
+		if inGit {
+			files, gitErr := changedFiles(git, gitStart, []string{ralphDir, opts.SummaryLog, opts.OutputDir})
+			if gitErr != nil && !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: failed to list changed files: %v\n", gitErr)
+			}
+			result.InGit, result.FilesChanged = true, files
+		}
+		record := result.summaryRecord(startTime, opts.Model)
+		if opts.SummaryLog != "" && !opts.DryRun {
+			if logErr := appendSummaryLog(opts.SummaryLog, record); logErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to append summary log: %v\n", logErr)
+			}
+		}
+		if jsonLog {
+			printJSONLine(summaryEvent{Type: "summary", RunSummary: record})
 			return
 		}
-		duration := time.Since(startTime).Truncate(time.Millisecond)
-		fmt.Println("\n--- Summary ---")
-		fmt.Printf("Iterations: %d\n", sessionIterations)
-		fmt.Printf("Duration: %s\n", duration)
-		label, codes := statusStyle(finalStatus)
-		fmt.Printf("Status: %s\n", styleIf(useColor, label, codes...))
+		if opts.QuietSummary {
+			fmt.Println(finalStatus)
+			return
+		}
+		if showSummary {
+			fmt.Print(renderRunResult(result, useColor))
+		}
 	}()
 
 	if err := os.MkdirAll(ralphDir, 0755); err != nil {
-		return fmt.Errorf("creating %s directory: %w", ralphDir, err)
+		return result, fmt.Errorf("creating %s directory: %w", ralphDir, err)
 	}
 
-	locked, err := acquireLock(lockFile)
-	if err != nil {
-		return fmt.Errorf("acquiring lock: %w", err)
+	if !opts.NoLock {
+		locked, err := acquireLock(lockFile)
+		if err != nil {
+			return result, fmt.Errorf("acquiring lock: %w", err)
+		}
+		if locked {
+			defer func() {
+				if err := releaseLock(lockFile); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to release lock: %v\n", err)
+				}
+			}()
+		}
 	}
-	if locked {
-		stopSignalHandler := installLockSignalHandler(lockFile)
-		defer stopSignalHandler()
 
+	// opencode runs in its own process group, so a terminal's Ctrl-C does
+	// not reach it or anything it spawned; stop them when ctx is canceled so
+	// the call in progress returns and the run can wind down normally.
+	groups := opencodeGroups
+	stopKilling := context.AfterFunc(ctx, func() {
+		if err := groups.killAll(syscall.SIGTERM); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	})
+	defer stopKilling()
+
+	if opts.EditSpecs {
+		if err := editSpecsBeforeRun(editorRunner, cfg.SpecsFile, opts.EditIfTTY); err != nil {
+			return result, err
+		}
+	}
+
+	state = loadState()
+	if !opts.DryRun {
+		// Registered after the lock so the record is written before it is released.
+		defer func() {
+			status := finalStatus
+			if err != nil {
+				status = "error"
+			}
+			state.Runs = appendRunOutcome(state.Runs, RunOutcome{
+				RunID:           runID,
+				Started:         startTime,
+				Status:          status,
+				Iterations:      sessionIterations,
+				DurationSeconds: now().Sub(startTime).Seconds(),
+			})
+			saveState(state)
+		}()
+		// Also under the lock, so no other run appends to the notes mid-drain.
 		defer func() {
-			if err := releaseLock(lockFile); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to release lock: %v\n", err)
+			if err == nil {
+				drainNotesOnFinish(opts, finalStatus)
+				runFinishHook(opts, finalStatus, map[string]string{
+					"RALPH_RUN_ID":     runID,
+					"RALPH_ITERATIONS": strconv.Itoa(sessionIterations),
+				})
+				notifyWebhook(opts, WebhookPayload{
+					RunID:           runID,
+					Status:          finalStatus,
+					Iterations:      sessionIterations,
+					DurationSeconds: now().Sub(startTime).Seconds(),
+					Tokens:          sessionTokens,
+					Model:           opts.Model,
+					Meta:            opts.Meta,
+				})
 			}
 		}()
 	}
 
-	state := loadState()
+	if !opts.DryRun {
+		gitStart, inGit = takeGitSnapshot(git)
+	}
 
 	if !quiet {
 		fmt.Print(banner)
 	}
 
-	for i := 0; i < maxIterations; i++ {
+	maxIterations := opts.MaxIterations
+	maxPerMinute := opts.MaxPerMinute
+	maxPerHour := opts.MaxPerHour
+	maxPerDay := opts.MaxPerDay
+
+	budget := newRunBudget(startTime, opts.MaxRuntime)
+	budget.ctx = ctx
+	stopEarly := func() {
+		if budget.canceled() {
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, "Run canceled", ansiYellow, ansiBold))
+			}
+			finalStatus = "canceled"
+		} else {
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Time limit reached: run exceeded --max-runtime %s", opts.MaxRuntime), ansiYellow, ansiBold))
+			}
+			finalStatus = "time_limit"
+		}
+		saveState(state)
+	}
+
+	extend := func() bool {
+		if opts.DryRun {
+			return false
+		}
+		next := extendedLimit(maxIterations, opts.AutoExtend, opts.AutoExtendCap)
+		if next <= maxIterations {
+			return false
+		}
+		if !quiet {
+			fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Reached %d iterations without completion; extending to %d", maxIterations, next), ansiYellow, ansiBold))
+		}
+		maxIterations = next
+		return true
+	}
+
+	started = true
+	for i := 0; i < maxIterations || extend(); i++ {
+		if budget.expired() {
+			stopEarly()
+			return result, nil
+		}
+
 		sessionIterations++
 		state.TotalIterations++
 		iteration := state.TotalIterations
+		iterCfg, iterOpts := applyOverlay(cfg, opts, overlayFor(opts.Overlays, i))
 
 		if !quiet {
 			header := fmt.Sprintf("=== Iteration %d (session: %d/%d) ===", iteration, i+1, maxIterations)
 			fmt.Printf("\n%s\n", styleIf(useColor, header, ansiCyan, ansiBold))
 		}
 
-		if maxPerHour > 0 || maxPerDay > 0 {
-			hourCount, dayCount := countRecentIterations(state.Timestamps)
-			if maxPerHour > 0 && hourCount >= maxPerHour {
-				if !quiet {
-					fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Rate limit reached: %d iterations in the past hour (max: %d)", hourCount, maxPerHour), ansiYellow, ansiBold))
+		if maxPerMinute > 0 || maxPerHour > 0 || maxPerDay > 0 {
+			windows := activeRateWindows(maxPerMinute, maxPerHour, maxPerDay)
+			for {
+				window, count, wait, full := fullRateWindow(state.Timestamps, windows, now())
+				if !full {
+					break
+				}
+				limit := fmt.Sprintf("Rate limit reached: %d iterations in the past %s (max: %d)", count, window.Name, window.Max)
+				if !opts.WaitOnLimit {
+					if !quiet {
+						fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("%s; try again in %s", limit, wait.Round(time.Second)), ansiYellow, ansiBold))
+					}
+					finalStatus = "rate_limited"
+					saveState(state)
+					return result, nil
 				}
-				finalStatus = "rate_limited"
-				saveState(state)
-				return nil
-			}
-			if maxPerDay > 0 && dayCount >= maxPerDay {
 				if !quiet {
-					fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Rate limit reached: %d iterations in the past day (max: %d)", dayCount, maxPerDay), ansiYellow, ansiBold))
+					fmt.Printf("%s\n", styleIf(useColor, limit+"; waiting for capacity", ansiYellow, ansiBold))
+				}
+				// Canceling ctx (Ctrl-C in the CLI) cuts the wait short.
+				waited := budget.sleepWithCountdown(wait, func(left time.Duration) {
+					if !quiet {
+						fmt.Printf("Waiting %s...\n", left.Round(time.Second))
+					}
+				})
+				if !waited {
+					stopEarly()
+					return result, nil
 				}
-				finalStatus = "rate_limited"
-				saveState(state)
-				return nil
 			}
+			minuteCount := countRecentMinute(state.Timestamps, now())
+			hourCount, dayCount := countRecentIterations(state.Timestamps, now())
 			if !quiet {
-				fmt.Printf("Rate: %d/hour, %d/day\n", hourCount, dayCount)
+				if maxPerMinute > 0 {
+					fmt.Printf("Rate: %d/minute, %d/hour, %d/day\n", minuteCount, hourCount, dayCount)
+				} else {
+					fmt.Printf("Rate: %d/hour, %d/day\n", hourCount, dayCount)
+				}
+				if opts.Verbose {
+					headroom := computeHeadroom(state.Timestamps, activeRateWindows(maxPerMinute, maxPerHour, maxPerDay), now())
+					fmt.Printf("Headroom: %s\n", formatHeadroom(headroom, useColor))
+				}
 			}
 		}
 
-		promptMD, err := readFile(cfg.PromptFile)
+		promptMD, err := readPrompt(cfg, opts)
 		if err != nil {
-			return fmt.Errorf("reading %s: %w", cfg.PromptFile, err)
+			return result, err
 		}
 		conventionsMD, err := readFile(cfg.ConventionsFile)
 		if err != nil {
-			return fmt.Errorf("reading %s: %w", cfg.ConventionsFile, err)
+			return result, fmt.Errorf("reading %s: %w", cfg.ConventionsFile, err)
 		}
-		specsMD, err := readFile(cfg.SpecsFile)
+		specsMD, err := readSpecs(iterCfg.SpecsFile)
 		if err != nil {
-			return fmt.Errorf("reading %s: %w", cfg.SpecsFile, err)
+			return result, fmt.Errorf("reading %s: %w", iterCfg.SpecsFile, err)
 		}
+		restoreDeletedNotes()
 		notesMD := readFileOrDefault(notesFile, "No notes yet.")
+		notesTrack.observe()
+		notesMD = tailNotes(notesMD, opts.NotesTail)
+
+		if opts.TrackSpecs {
+			specsTasks = parseTasks(specsMD)
+		}
+		if tasks := parseTasks(specsMD); !quiet && tasks.Total() > 0 {
+			fmt.Printf("Tasks: %s\n", tasks)
+		}
 
-		prompt := constructPrompt(promptMD, conventionsMD, specsMD, notesMD, iteration, maxIterations)
-		if dryRun {
+		promptData := PromptData{
+			Prompt:        promptMD,
+			Conventions:   conventionsMD,
+			Specs:         specsMD,
+			Notes:         notesMD,
+			Iteration:     iteration,
+			MaxIterations: maxIterations,
+			Order:         opts.PromptOrder,
+			Template:      promptTemplate,
+		}
+		if opts.CheckCommand != "" {
+			check, err := runCheckCommand(opts.CheckCommand, opts.CheckOutputBytes)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v; leaving check results out of the prompt\n", err)
+			} else {
+				promptData.Check = &check
+				if !quiet {
+					fmt.Printf("Check: %s exited %d\n", opts.CheckCommand, check.ExitCode)
+				}
+			}
+		}
+		if opts.IncludeLastOutput {
+			promptData.PreviousOutput = tailChars(lastOutput, opts.LastOutputChars)
+		}
+		if notesMissing && opts.RequireNotes {
+			promptData.Feedback = append(promptData.Feedback, missingNotesFeedback(cfg.NotesTag))
+		}
+		if opts.EscalationHints {
+			if hint := escalationHint(cfg.EscalationHints, i+1, maxIterations); hint != "" {
+				promptData.Feedback = append(promptData.Feedback, hint)
+			}
+		}
+		runArgs := OpencodeRunArgs{
+			Binary:          opts.OpencodeBin,
+			Subcommand:      cfg.OpencodeSubcommand,
+			Model:           iterOpts.Model,
+			Agent:           iterOpts.Agent,
+			Format:          opts.Format,
+			Variant:         iterOpts.Variant,
+			Attach:          opts.Attach,
+			Port:            opts.Port,
+			ContinueSession: opts.ContinueSession,
+			Session:         opts.Session,
+			Files:           opts.Files,
+			Title:           opts.Title,
+			PromptArgStyle:  opts.PromptArgStyle,
+			Nice:            opts.Nice,
+			IOClass:         opts.IOClass,
+			Quiet:           opts.Quiet,
+			Verbose:         opts.Verbose,
+			Timeout:         time.Duration(opts.Timeout) * time.Second,
+			MergeStreams:    opts.MergeStreams,
+			LinePrefix:      streamPrefix(opts.Verbose, opts.Quiet, useColor),
+		}
+		if i == 0 && fileSession != "" {
+			runArgs.Session = fileSession
+		}
+		prompt := renderPrompt(promptData)
+		if opts.MaxPromptChars > 0 && utf8.RuneCountInString(prompt) > opts.MaxPromptChars {
+			var summarize notesSummarizer
+			if opts.SummarizeNotes && !opts.DryRun {
+				summarize = runnerSummarizer(runner, runArgs)
+			}
+			notes, summarized, fitErr := fitPromptBudget(promptData, opts.MaxPromptChars, summarize)
+			if fitErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v; keeping only the most recent notes\n", fitErr)
+			}
+			if summarized {
+				if err := compactNotesFile(promptData.Notes, notes); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to compact notes: %v\n", err)
+				}
+				if !quiet {
+					fmt.Println("Notes summarized to fit --max-prompt-chars")
+				}
+			}
+			promptData.Notes = notes
+			prompt = renderPrompt(promptData)
+		}
+		runArgs.Prompt = prompt
+		if opts.PromptOut != "" {
+			finalStatus = "dry_run"
+			if err := os.WriteFile(opts.PromptOut, []byte(prompt), 0644); err != nil {
+				return result, fmt.Errorf("writing prompt to %s: %w", opts.PromptOut, err)
+			}
+			return result, nil
+		}
+		if opts.DryRun && jsonLog {
+			printJSONLine(dryRunEvent{Type: "dry_run", RunID: runID, Iteration: iteration, Prompt: prompt})
+			if opts.DryRunAll {
+				continue
+			}
+			finalStatus = "dry_run"
+			if opts.Strict {
+				return result, ErrDryRun
+			}
+			return result, nil
+		}
+		if opts.DryRun && opts.DryRunAll {
+			fmt.Printf("\n--- DRY RUN: Prompt for iteration %d ---\n", iteration)
+			fmt.Println(prompt)
+			fmt.Printf("--- END DRY RUN: iteration %d ---\n", iteration)
+			continue
+		}
+		if opts.DryRun {
 			fmt.Println("\n--- DRY RUN: Constructed Prompt ---")
 			fmt.Println(prompt)
 			fmt.Println("--- END DRY RUN ---")
+			fmt.Println(dryRunMarker)
 			finalStatus = "dry_run"
-			return nil
+			if opts.Strict {
+				return result, ErrDryRun
+			}
+			return result, nil
 		}
 
-		output, runErr := runner.Run(OpencodeRunArgs{
-			Prompt:          prompt,
-			Model:           model,
-			Agent:           agent,
-			Format:          format,
-			Variant:         variant,
-			Attach:          attach,
-			Port:            port,
-			ContinueSession: continueSession,
-			Session:         session,
-			Files:           files,
-			Title:           title,
-			Quiet:           quiet,
-			Verbose:         verbose,
-		})
+		if opts.BackupSpecs {
+			if err := backupSpecs(specsBackupDir, iteration, specsMD, opts.BackupSpecsKeep); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to back up specs: %v\n", err)
+			}
+		}
+
+		callStart := now()
+		output, runErr := runner.Run(runArgs)
+		callDuration := now().Sub(callStart)
+		if budget.canceled() {
+			// opencode was stopped mid-call, so its output is incomplete.
+			stopEarly()
+			return result, nil
+		}
+		if opts.SlowIterationWarning > 0 && callDuration > opts.SlowIterationWarning {
+			slowIterations++
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Warning: iteration %d took %s (over --warn-on-slow-iteration %s)", iteration, callDuration.Truncate(time.Millisecond), opts.SlowIterationWarning), ansiYellow, ansiBold))
+			}
+		}
+		if looksTruncated(output, cfg.NotesTag, cfg.StatusTag) {
+			truncatedCount++
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, "Warning: opencode output looks truncated", ansiYellow, ansiBold))
+			}
+			if opts.RetryOnTruncation {
+				if !quiet {
+					fmt.Println("Retrying iteration after truncated output")
+				}
+				output, runErr = runner.Run(runArgs)
+				if looksTruncated(output, cfg.NotesTag, cfg.StatusTag) {
+					truncatedCount++
+				}
+			}
+		}
+		for attempt := 0; runErr != nil && attempt < opts.Retries; attempt++ {
+			if _, action := exitCodeAction(cfg.ExitCodePolicy, runErr); action == exitActionFatal {
+				break
+			}
+			backoff := retryBackoff(time.Duration(opts.RetryBackoff*float64(time.Second)), attempt)
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("opencode failed (%v); retry %d/%d in %s", runErr, attempt+1, opts.Retries, backoff), ansiYellow))
+			}
+			if !budget.sleep(backoff) {
+				stopEarly()
+				return result, nil
+			}
+			retryCount++
+			output, runErr = runner.Run(runArgs)
+		}
+		timedOut := errors.Is(runErr, ErrIterationTimeout)
+		if timedOut {
+			timedOutCount++
+		}
+		// Filled in below once the output is parsed; recordIteration reports
+		// them as of the point the iteration ended.
+		iterationTokens := 0
+		notesMissing = true
+
+		// recordIteration appends the iteration to the history log and, with
+		// --log-format json, prints its event.
+		recordIteration := func(complete bool) {
+			status := iterationStatus(complete, timedOut, runErr)
+			if err := appendIterationRecord(IterationRecord{
+				Timestamp:       now(),
+				RunID:           runID,
+				Iteration:       iteration,
+				Status:          status,
+				DurationSeconds: callDuration.Seconds(),
+				Model:           iterOpts.Model,
+				Tokens:          iterationTokens,
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record iteration history: %v\n", err)
+			}
+			if !jsonLog {
+				return
+			}
+			hourCount, dayCount := countRecentIterations(state.Timestamps, now())
+			event := iterationEvent{
+				Type:             "iteration",
+				RunID:            runID,
+				Iteration:        iteration,
+				SessionIteration: i + 1,
+				MaxIterations:    maxIterations,
+				Status:           status,
+				DurationSeconds:  callDuration.Seconds(),
+				RateHour:         hourCount,
+				RateDay:          dayCount,
+				NotesExtracted:   !notesMissing,
+				Tokens:           iterationTokens,
+			}
+			if runErr != nil {
+				event.Error = runErr.Error()
+			}
+			printJSONLine(event)
+		}
+
+		if runErr != nil {
+			code, action := exitCodeAction(cfg.ExitCodePolicy, runErr)
+			if action == exitActionRetry && opts.Retries == 0 {
+				if !quiet {
+					fmt.Printf("Retrying iteration after opencode exit code %d\n", code)
+				}
+				output, runErr = runner.Run(runArgs)
+				code, action = exitCodeAction(cfg.ExitCodePolicy, runErr)
+			}
+			if runErr != nil && action == exitActionFatal {
+				recordIteration(false)
+				return result, fmt.Errorf("iteration %d: opencode exited with code %d, which exit_code_policy treats as fatal", iteration, code)
+			}
+		}
 		if runErr != nil {
 			if !quiet {
 				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Warning: opencode exited with error: %v", runErr), ansiYellow, ansiBold))
 			}
 		}
+		if opts.TrackSpecs {
+			if after, err := readSpecs(iterCfg.SpecsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to re-read specs: %v\n", err)
+			} else {
+				specsTasks = parseTasks(after)
+				if !quiet {
+					fmt.Print(renderSpecsChange(specsMD, after, useColor))
+				}
+			}
+		}
+
+		if opts.OutputDir != "" {
+			logged := output
+			if opts.PrettyJSONLogs && opts.Format == "json" {
+				if pretty, err := prettyJSON(output); err == nil {
+					logged = pretty
+				}
+			}
+			if err := writeIterationArtifacts(opts.OutputDir, runID, iteration, runArgs.Prompt, logged); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save iteration output: %v\n", err)
+			}
+		}
+
+		if opts.OutputFilter != "" {
+			filtered, err := applyOutputFilter(opts.OutputFilter, output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v; using unfiltered output\n", err)
+			} else {
+				output = filtered
+			}
+		}
 
-		if notes := extractNotes(output); notes != "" {
-			if err := appendNotes(notes, iteration); err != nil {
+		// Saved logs above keep the raw bytes; everything below, from the
+		// prompt's previous output to notes.md, sees sanitized text.
+		output = sanitizeOutput(output)
+		lastOutput = strings.TrimSpace(output)
+
+		// Notes and status come from the whole output, or with parsed
+		// --format json only from the assistant's answer text.
+		answer := output
+		activity := ""
+		if opts.Format == "json" {
+			if parsed, err := parseOpencodeJSON(output); err == nil {
+				answer = sanitizeOutput(parsed.answerText())
+				if id := parsed.sessionID(); id != "" {
+					state.SessionID = id
+					if opts.SessionFile != "" && runErr == nil {
+						if err := writeSessionFile(opts.SessionFile, id); err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: failed to save session ID: %v\n", err)
+						}
+					}
+				}
+				for kind, n := range countToolCalls(parsed) {
+					toolCounts[kind] += n
+				}
+				activity = summarizeToolCalls(parsed)
+				iterationTokens = parsed.tokens()
+				sessionTokens += iterationTokens
+				state.TotalTokens += iterationTokens
+			} else if opts.StrictFormat {
+				// Fail the iteration, not the run, so --max-consecutive-failures
+				// decides when to give up.
+				fmt.Fprintf(os.Stderr, "Warning: iteration %d failed: opencode did not honor --format json (%v)\n", iteration, err)
+				if runErr == nil {
+					runErr = fmt.Errorf("opencode did not honor --format json: %w", err)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: opencode did not honor --format json (%v); treating output as text\n", err)
+			}
+		}
+		notes := extractNotes(answer, cfg.NotesTag)
+		notesMissing = notes == ""
+		if notesMissing && (opts.RequireNotes || opts.StrictNotes) {
+			missingNotesCount++
+			if opts.StrictNotes {
+				notesErr := fmt.Errorf("iteration %d produced no <%s>", iteration, cfg.NotesTag)
+				if runErr == nil {
+					runErr = notesErr
+				}
+				recordIteration(false)
+				return result, notesErr
+			}
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, "Warning: iteration produced no notes", ansiYellow, ansiBold))
+			}
+		}
+		switch {
+		case runErr != nil:
+			failureCount++
+			consecutiveFailures++
+		case notesMissing && opts.RequireNotes:
+			// An agent that keeps reporting nothing has stalled, so let
+			// --max-consecutive-failures stop it.
+			consecutiveFailures++
+		default:
+			consecutiveFailures = 0
+		}
+		if activity != "" {
+			notes = strings.TrimSpace(notes + "\n\nActivity: " + activity)
+		}
+		if notes != "" {
+			notes = truncateNotes(notes, opts.MaxNotesChars)
+			restoreDeletedNotes()
+			if !wroteRunHeader {
+				if err := appendRunHeader(runID, opts.Meta, notesCap); err != nil && !quiet {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save notes: %v\n", err)
+				}
+				wroteRunHeader = true
+			}
+			entry := notes
+			if opts.DedupeNotes && lastNoteIteration > 0 && strings.TrimSpace(notes) == lastNote {
+				entry = fmt.Sprintf("(same as iteration %d)", lastNoteIteration)
+			} else {
+				lastNote, lastNoteIteration = strings.TrimSpace(notes), iteration
+			}
+			if err := appendNotes(entry, iteration, notesCap, cfg.NotesTag, cfg.StatusTag); err != nil {
 				if !quiet {
 					fmt.Fprintf(os.Stderr, "Warning: failed to save notes: %v\n", err)
 				}
 			}
+			notesTrack.observe()
+		}
+
+		if opts.Commit && runErr == nil {
+			if err := commitIteration(git, iteration, notes); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not commit iteration %d: %v\n", iteration, err)
+			}
 		}
 
-		if isComplete(output) {
+		// A timed-out call is a failure even if its partial output claims completion.
+		if !timedOut && isComplete(answer, cfg.CompletionSignal, cfg.StatusTag) {
+			recordIteration(true)
 			finalStatus = "complete"
 			if !quiet {
 				fmt.Println(styleIf(useColor, "Received COMPLETE signal from opencode!", ansiGreen, ansiBold))
 			}
-			return nil
+			return result, nil
+		}
+		if !timedOut && opts.StopWhenSpecsComplete {
+			if specsAfter, err := readSpecs(iterCfg.SpecsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to re-read specs: %v\n", err)
+			} else if done, total := countTasks(specsAfter); total > 0 && done == total {
+				recordIteration(true)
+				finalStatus = "complete"
+				if !quiet {
+					fmt.Println(styleIf(useColor, fmt.Sprintf("All %d spec tasks are checked off", total), ansiGreen, ansiBold))
+				}
+				return result, nil
+			}
 		}
 
-		state.Timestamps = append(state.Timestamps, time.Now().Unix())
-		state.LastRun = time.Now()
+		state.Timestamps = append(state.Timestamps, now().Unix())
+		state.LastRun = now()
 		pruneOldTimestamps(&state)
 		saveState(state)
+		recordIteration(false)
 
-		if delay > 0 {
-			time.Sleep(time.Duration(delay) * time.Second)
+		if opts.MaxConsecutiveFailures > 0 && consecutiveFailures >= opts.MaxConsecutiveFailures {
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Stopping: %d iterations in a row failed (--max-consecutive-failures %d)", consecutiveFailures, opts.MaxConsecutiveFailures), ansiRed, ansiBold))
+			}
+			finalStatus = "failed"
+			return result, nil
 		}
+
+		if opts.TokenBudget > 0 && sessionTokens > opts.TokenBudget {
+			if !quiet {
+				fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Token budget reached: %d tokens used (--token-budget %d)", sessionTokens, opts.TokenBudget), ansiYellow, ansiBold))
+			}
+			finalStatus = "token_budget"
+			return result, nil
+		}
+
+		delay := iterationDelay(time.Duration(opts.Delay*float64(time.Second)), opts.DelayRatio, callDuration, opts.DelayMin, opts.DelayMax)
+		delay = jitteredDelay(delay, time.Duration(opts.DelayJitter*float64(time.Second)), jitterRand)
+		if delay > 0 && !budget.sleep(delay) {
+			stopEarly()
+			return result, nil
+		}
+	}
+
+	if opts.DryRun {
+		// Only --dry-run-all gets here, after printing every prompt.
+		if !jsonLog {
+			fmt.Println(dryRunMarker)
+		}
+		finalStatus = "dry_run"
+		if opts.Strict {
+			return result, ErrDryRun
+		}
+		return result, nil
 	}
 
 	if !quiet {
 		fmt.Printf("%s\n", styleIf(useColor, fmt.Sprintf("Reached maximum iterations (%d)", maxIterations), ansiYellow, ansiBold))
 	}
 	finalStatus = "max_iterations"
-	return nil
+	if opts.OnMaxIterationsCmd != "" {
+		env := map[string]string{
+			"RALPH_RUN_ID":         runID,
+			"RALPH_STATUS":         finalStatus,
+			"RALPH_ITERATIONS":     strconv.Itoa(sessionIterations),
+			"RALPH_MAX_ITERATIONS": strconv.Itoa(maxIterations),
+		}
+		if err := runHook(opts.OnMaxIterationsCmd, env); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return result, nil
 }
 
 func readFile(path string) (string, error) {
@@ -354,37 +1366,34 @@ func readFileOrDefault(path, defaultValue string) string {
 	return string(data)
 }
 
-func constructPrompt(promptMD, conventionsMD, specsMD, notesMD string, iteration, maxIterations int) string {
-	return fmt.Sprintf(`You are operating in Ralph Wiggum mode.
-
-## Context Files
-
-<prompt>
-%s
-</prompt>
-
-<conventions>
-%s
-</conventions>
-
-NOTE: The full, current contents of the specs are included below in <specs>.
-Do not re-read SPECS.md unless you have modified it and need to confirm your updates.
-
-<specs>
-%s
-</specs>
+const (
+	promptArgStylePositional = "positional"
+	promptArgStyleFlag       = "flag"
+)
 
-<ralph_notes_history>
-%s
-</ralph_notes_history>
+func validatePromptArgStyle(style string) error {
+	switch style {
+	case "", promptArgStylePositional, promptArgStyleFlag:
+		return nil
+	default:
+		return fmt.Errorf("invalid prompt arg style: %s (expected positional or flag)", style)
+	}
+}
 
-## Current Iteration
-Iteration: %d of %d
-`, promptMD, conventionsMD, specsMD, notesMD, iteration, maxIterations)
+func validateOpencodeSubcommand(subcommand string) error {
+	if strings.ContainsAny(subcommand, " \t\r\n") {
+		return fmt.Errorf("invalid opencode subcommand %q: must not contain whitespace", subcommand)
+	}
+	return nil
 }
 
-func runOpencode(runArgs OpencodeRunArgs) (string, error) {
-	args := []string{"run"}
+// buildOpencodeArgs returns the argv (excluding the binary) for an opencode run.
+// An empty Subcommand passes the flags directly to the binary.
+func buildOpencodeArgs(runArgs OpencodeRunArgs) []string {
+	var args []string
+	if runArgs.Subcommand != "" {
+		args = append(args, runArgs.Subcommand)
+	}
 	if runArgs.Model != "" {
 		args = append(args, "-m", runArgs.Model)
 	}
@@ -417,49 +1426,260 @@ func runOpencode(runArgs OpencodeRunArgs) (string, error) {
 	if runArgs.Title != "" {
 		args = append(args, "--title", runArgs.Title)
 	}
-	args = append(args, runArgs.Prompt)
-	cmd := exec.Command("opencode", args...)
+	if runArgs.PromptArgStyle == promptArgStyleFlag {
+		args = append(args, "--prompt", runArgs.Prompt)
+	} else {
+		args = append(args, runArgs.Prompt)
+	}
+	return args
+}
+
+// dryRunMarker is printed on its own line after a dry run so wrapping
+// scripts can detect that nothing was executed.
+const dryRunMarker = "RALPH_STATUS=DRY_RUN"
+
+// ErrDryRun is returned by a --dry-run under --strict so callers can exit
+// with a distinct code.
+var ErrDryRun = errors.New("dry run: no iterations were executed")
+
+// ErrIterationTimeout is returned by runOpencode when a call exceeds its
+// --timeout; the output captured up to that point is still returned.
+var ErrIterationTimeout = errors.New("opencode timed out")
+
+// ErrOpencodeNotFound is returned before a run starts when the opencode
+// binary cannot be found on PATH.
+var ErrOpencodeNotFound = errors.New("opencode not found on PATH; install it from https://opencode.ai and make sure it is on your PATH")
+
+// defaultOpencodeBinary is run when neither --opencode-bin nor opencode_bin is set.
+const defaultOpencodeBinary = "opencode"
+
+// opencodeBinary returns binary, or the default when it is empty.
+func opencodeBinary(binary string) string {
+	if binary == "" {
+		return defaultOpencodeBinary
+	}
+	return binary
+}
+
+// checkOpencodeInstalled fails fast, before the banner and lock, when the
+// opencode binary is missing or not executable rather than failing on the
+// first iteration. lookPath also handles paths containing a slash, checking
+// the file itself instead of searching PATH.
+func checkOpencodeInstalled(lookPath func(string) (string, error), binary string) error {
+	binary = opencodeBinary(binary)
+	if _, err := lookPath(binary); err != nil {
+		return opencodeNotFound(binary, err)
+	}
+	return nil
+}
+
+// opencodeNotFound describes a failed lookPath of binary.
+func opencodeNotFound(binary string, err error) error {
+	if binary == defaultOpencodeBinary {
+		return ErrOpencodeNotFound
+	}
+	return opencodeBinaryError{binary: binary, err: err}
+}
+
+// opencodeBinaryError reports a configured opencode binary that cannot be
+// run; it matches ErrOpencodeNotFound so the CLI exits the same way.
+type opencodeBinaryError struct {
+	binary string
+	err    error
+}
+
+func (e opencodeBinaryError) Error() string {
+	return fmt.Sprintf("opencode binary %q (from --opencode-bin or opencode_bin) is not an executable file: %v", e.binary, e.err)
+}
+
+func (e opencodeBinaryError) Unwrap() []error {
+	return []error{e.err, ErrOpencodeNotFound}
+}
+
+func runOpencode(runArgs OpencodeRunArgs) (string, error) {
+	ctx := context.Background()
+	if runArgs.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runArgs.Timeout)
+		defer cancel()
+	}
+	cmd := opencodeCommand(ctx, runArgs)
 
 	var output bytes.Buffer
 
-	if runArgs.Verbose || runArgs.Quiet {
-		cmd.Stdout = io.MultiWriter(os.Stdout, &output)
-		cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	if (runArgs.Verbose || runArgs.Quiet) && runArgs.MergeStreams == mergeStreamsOrdered {
+		// One writer for both streams: exec then shares a single pipe, so
+		// stdout and stderr arrive in the order opencode wrote them.
+		terminal := newSanitizingWriter(newLinePrefixWriter(os.Stdout, runArgs.LinePrefix))
+		defer terminal.Flush()
+		merged := &syncWriter{w: io.MultiWriter(terminal, &output)}
+		cmd.Stdout = merged
+		cmd.Stderr = merged
+	} else if runArgs.Verbose || runArgs.Quiet {
+		// The terminal sees sanitized output; the captured copy stays raw.
+		// Prefixing comes after sanitizing so the prefix keeps its color.
+		stdout := newSanitizingWriter(newLinePrefixWriter(os.Stdout, runArgs.LinePrefix))
+		stderr := newSanitizingWriter(newLinePrefixWriter(os.Stderr, runArgs.LinePrefix))
+		defer stdout.Flush()
+		defer stderr.Flush()
+		captured := &syncWriter{w: &output}
+		cmd.Stdout = io.MultiWriter(stdout, captured)
+		cmd.Stderr = io.MultiWriter(stderr, captured)
 	} else {
 		cmd.Stdout = &output
 		cmd.Stderr = &output
 	}
 
-	err := cmd.Run()
+	err := runInProcessGroup(cmd, opencodeGroups)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output.String(), fmt.Errorf("%w after %s", ErrIterationTimeout, runArgs.Timeout)
+	}
 	if err != nil {
 		return output.String(), err
 	}
 	return output.String(), nil
 }
 
-func extractNotes(output string) string {
-	re := regexp.MustCompile(`(?s)<ralph_notes>(.*?)</ralph_notes>`)
-	matches := re.FindStringSubmatch(output)
-	if len(matches) > 1 {
-		return strings.TrimSpace(matches[1])
+// missingNotesFeedback is added to the next prompt under --require-notes when
+// an iteration ends without a notes block in tag.
+func missingNotesFeedback(tag string) string {
+	if tag == "" {
+		tag = defaultNotesTag
+	}
+	return fmt.Sprintf("Your previous iteration did not include a <%[1]s> block. Always finish with <%[1]s>...</%[1]s> describing what you did and what remains.", tag)
+}
+
+// extractNotes returns the trimmed contents of every <tag> notes block in
+// output (tag defaults to ralph_notes), in order and separated by blank
+// lines; empty blocks are skipped.
+func extractNotes(output, tag string) string {
+	if tag == "" {
+		tag = defaultNotesTag
+	}
+	quoted := regexp.QuoteMeta(tag)
+	re := regexp.MustCompile(`(?s)<` + quoted + `>(.*?)</` + quoted + `>`)
+	var blocks []string
+	for _, match := range re.FindAllStringSubmatch(output, -1) {
+		if block := strings.TrimSpace(match[1]); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// truncateNotes caps notes at maxChars runes (0 = unlimited), marking any cut.
+func truncateNotes(notes string, maxChars int) string {
+	runes := []rune(notes)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return notes
+	}
+	return fmt.Sprintf("%s\n[notes truncated: kept %d of %d characters]", string(runes[:maxChars]), maxChars, len(runes))
+}
+
+// tagAlternation returns a regexp group matching any of the non-empty tag
+// names, or "" when there are none.
+func tagAlternation(tags []string) string {
+	var quoted []string
+	for _, tag := range tags {
+		if tag != "" {
+			quoted = append(quoted, regexp.QuoteMeta(tag))
+		}
+	}
+	if len(quoted) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(quoted, "|") + ")"
+}
+
+// looksTruncated reports whether output appears cut off mid-response: one of
+// tags (the notes and status tags) opened but never closed (or vice versa), or
+// text ending inside one of them.
+func looksTruncated(output string, tags ...string) bool {
+	names := tagAlternation(tags)
+	if names == "" {
+		return false
+	}
+	trimmed := strings.TrimRight(output, " \t\r\n")
+	if i := strings.LastIndex(trimmed, "<"); i >= 0 {
+		partial := strings.TrimPrefix(trimmed[i+1:], "/")
+		for _, tag := range tags {
+			if partial != "" && tag != "" && strings.HasPrefix(tag, partial) {
+				return true
+			}
+		}
+	}
+
+	counts := map[string]int{}
+	for _, m := range regexp.MustCompile(`<`+names+`>`).FindAllStringSubmatch(output, -1) {
+		counts[m[1]]++
+	}
+	for _, m := range regexp.MustCompile(`</`+names+`>`).FindAllStringSubmatch(output, -1) {
+		counts[m[1]]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isComplete reports whether output carries the completion signal. A bare
+// word such as COMPLETE must appear inside <tag> status tags (tag defaults to
+// ralph_status); a value that
+// starts with "<" (e.g. <task_finished/>) is matched on its own. Matching is
+// case-insensitive and tolerant of whitespace.
+func isComplete(output, signal, tag string) bool {
+	return completionPattern(signal, tag).MatchString(output)
+}
+
+func completionPattern(signal, tag string) *regexp.Regexp {
+	signal = strings.TrimSpace(signal)
+	if signal == "" {
+		signal = defaultCompletionSignal
+	}
+	words := strings.Fields(signal)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	quoted := strings.Join(words, `\s+`)
+	if strings.HasPrefix(signal, "<") {
+		return regexp.MustCompile(`(?si)` + quoted)
 	}
-	return ""
+	if tag == "" {
+		tag = defaultStatusTag
+	}
+	tag = regexp.QuoteMeta(tag)
+	return regexp.MustCompile(`(?si)<` + tag + `>\s*` + quoted + `\s*</` + tag + `>`)
+}
+
+// appendNotes adds an iteration's notes to the notes file, stripping any of
+// tags nested inside them.
+func appendNotes(notes string, iteration int, limit notesLimit, tags ...string) error {
+	timestamp := time.Now().Format(notesTimestampLayout)
+	return appendNotesEntry(fmt.Sprintf("\n## Iteration %d (%s)\n%s\n", iteration, timestamp, sanitizeNotes(notes, tags...)), limit)
 }
 
-func isComplete(output string) bool {
-	re := regexp.MustCompile(`(?si)<ralph_status>\s*COMPLETE\s*</ralph_status>`)
-	return re.MatchString(output)
+// appendRunHeader marks the start of a run's notes so entries can be correlated by run ID.
+func appendRunHeader(runID string, meta map[string]string, limit notesLimit) error {
+	timestamp := time.Now().Format(notesTimestampLayout)
+	header := fmt.Sprintf("\n# Run %s (%s)\n", runID, timestamp)
+	if len(meta) > 0 {
+		header += fmt.Sprintf("Meta: %s\n", formatMeta(meta))
+	}
+	return appendNotesEntry(header, limit)
 }
 
-func appendNotes(notes string, iteration int) error {
+func appendNotesEntry(entry string, limit notesLimit) error {
+	if err := limit.makeRoom(notesFile, len(entry)); err != nil {
+		return err
+	}
 	f, err := os.OpenFile(notesFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("opening notes file: %w", err)
 	}
 	defer f.Close()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	entry := fmt.Sprintf("\n## Iteration %d (%s)\n%s\n", iteration, timestamp, notes)
 	if _, err := f.WriteString(entry); err != nil {
 		return fmt.Errorf("writing notes: %w", err)
 	}
@@ -488,7 +1708,7 @@ func acquireLock(path string) (bool, error) {
 			return false, fmt.Errorf("lock file %s exists; another run may be active", path)
 		}
 
-		if isProcessRunning(pid) {
+		if processAlive(pid) {
 			return false, fmt.Errorf("lock file %s exists (pid %d); another run may be active", path, pid)
 		}
 
@@ -500,6 +1720,10 @@ func acquireLock(path string) (bool, error) {
 	return false, fmt.Errorf("unable to acquire lock %s", path)
 }
 
+// processAlive reports whether the process holding a lock is still running;
+// tests replace it to simulate live and dead PIDs.
+var processAlive = isProcessRunning
+
 func readLockPID(path string) (int, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -517,28 +1741,6 @@ func readLockPID(path string) (int, error) {
 	return pid, nil
 }
 
-func isProcessRunning(pid int) bool {
-	if pid <= 0 {
-		return false
-	}
-
-	err := syscall.Kill(pid, 0)
-	if err == nil {
-		return true
-	}
-
-	if errno, ok := err.(syscall.Errno); ok {
-		switch errno {
-		case syscall.ESRCH:
-			return false
-		case syscall.EPERM:
-			return true
-		}
-	}
-
-	return true
-}
-
 func releaseLock(path string) error {
 	if err := os.Remove(path); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -548,41 +1750,3 @@ func releaseLock(path string) error {
 	}
 	return nil
 }
-
-func installLockSignalHandler(lockPath string) func() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	done := make(chan struct{})
-	go func() {
-		select {
-		case sig := <-c:
-			signal.Stop(c)
-			close(done)
-
-			if err := releaseLock(lockPath); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to release lock: %v\n", err)
-			}
-
-			exitCode := 1
-			switch sig {
-			case syscall.SIGINT:
-				exitCode = 130
-			case syscall.SIGTERM:
-				exitCode = 143
-			}
-			os.Exit(exitCode)
-		case <-done:
-			signal.Stop(c)
-			return
-		}
-	}()
-
-	var once sync.Once
-	return func() {
-		once.Do(func() {
-			signal.Stop(c)
-			close(done)
-		})
-	}
-}
@@ -1,8 +1,12 @@
 package ralph
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -26,6 +30,30 @@ func withTempCWD(t *testing.T) {
 	})
 }
 
+func captureOutput(t *testing.T, target **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	orig := *target
+	*target = w
+	defer func() {
+		*target = orig
+	}()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	_ = w.Close()
+	return <-done
+}
+
 func TestConfigRoundTrip(t *testing.T) {
 	withTempCWD(t)
 
@@ -37,7 +65,7 @@ func TestConfigRoundTrip(t *testing.T) {
 		t.Fatalf("SaveConfig: %v", err)
 	}
 
-	loaded := LoadConfig()
+	loaded := loadConfigFile()
 	if loaded.PromptFile != cfg.PromptFile {
 		t.Fatalf("PromptFile: got %q want %q", loaded.PromptFile, cfg.PromptFile)
 	}
@@ -52,7 +80,7 @@ func TestConfigSet(t *testing.T) {
 	if err := ConfigSet("prompt_file", "PROMPT2.md"); err != nil {
 		t.Fatalf("ConfigSet prompt_file: %v", err)
 	}
-	cfg := LoadConfig()
+	cfg := loadConfigFile()
 	if cfg.PromptFile != "PROMPT2.md" {
 		t.Fatalf("PromptFile: got %q want %q", cfg.PromptFile, "PROMPT2.md")
 	}
@@ -60,7 +88,7 @@ func TestConfigSet(t *testing.T) {
 	if err := ConfigSet("max_iterations", "5"); err != nil {
 		t.Fatalf("ConfigSet max_iterations: %v", err)
 	}
-	cfg = LoadConfig()
+	cfg = loadConfigFile()
 	if cfg.MaxIterations != 5 {
 		t.Fatalf("MaxIterations: got %d want %d", cfg.MaxIterations, 5)
 	}
@@ -101,11 +129,16 @@ func TestExtractNotes(t *testing.T) {
 		{name: "missing", in: "no notes", want: ""},
 		{name: "present", in: "<ralph_notes>\nhello\n</ralph_notes>", want: "hello"},
 		{name: "malformed", in: "<ralph_notes>oops", want: ""},
+		{name: "two blocks", in: "<ralph_notes>first</ralph_notes><ralph_notes>\n second \n</ralph_notes>", want: "first\n\nsecond"},
+		{name: "empty block", in: "<ralph_notes> \n </ralph_notes>", want: ""},
+		{name: "empty block skipped", in: "<ralph_notes></ralph_notes>text<ralph_notes>kept</ralph_notes>", want: "kept"},
+		{name: "interleaved text", in: "Working.\n<ralph_notes>\nstep one\n</ralph_notes>\nMore output.\n<ralph_notes>step two</ralph_notes>\nDone.", want: "step one\n\nstep two"},
+		{name: "unclosed second block", in: "<ralph_notes>one</ralph_notes><ralph_notes>two", want: "one"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := extractNotes(tt.in)
+			got := extractNotes(tt.in, defaultNotesTag)
 			if got != tt.want {
 				t.Fatalf("got %q want %q", got, tt.want)
 			}
@@ -114,14 +147,158 @@ func TestExtractNotes(t *testing.T) {
 }
 
 func TestIsComplete(t *testing.T) {
-	if isComplete("<ralph_status>COMPLETE</ralph_status>") != true {
+	if isComplete("<ralph_status>COMPLETE</ralph_status>", defaultCompletionSignal, defaultStatusTag) != true {
 		t.Fatalf("expected COMPLETE to be detected")
 	}
-	if isComplete("<ralph_status>INCOMPLETE</ralph_status>") != false {
+	if isComplete("<ralph_status>INCOMPLETE</ralph_status>", defaultCompletionSignal, defaultStatusTag) != false {
 		t.Fatalf("did not expect INCOMPLETE to be detected")
 	}
 }
 
+func TestCustomNotesAndStatusTags(t *testing.T) {
+	output := "<ralph_notes>ignored</ralph_notes>\n<agent_log>kept</agent_log>\n<loop_state>COMPLETE</loop_state>"
+	if got := extractNotes(output, "agent_log"); got != "kept" {
+		t.Fatalf("extractNotes with custom tag: got %q", got)
+	}
+	if !isComplete(output, defaultCompletionSignal, "loop_state") {
+		t.Fatalf("expected completion in the custom status tag")
+	}
+	if isComplete("<ralph_status>COMPLETE</ralph_status>", defaultCompletionSignal, "loop_state") {
+		t.Fatalf("did not expect the default status tag to match a custom one")
+	}
+}
+
+func TestCustomTagsDetectTruncationAndAreStripped(t *testing.T) {
+	tags := []string{"agent_log", "loop_state"}
+	truncated := []string{"<agent_log>half of the", "<agent_log>n</agent_log><loop_state>COMPL", "done </loop_st"}
+	for _, in := range truncated {
+		if !looksTruncated(in, tags...) {
+			t.Fatalf("expected %q to look truncated with custom tags", in)
+		}
+	}
+	if looksTruncated("<agent_log>n</agent_log><loop_state>COMPLETE</loop_state>", tags...) {
+		t.Fatalf("balanced custom tags should not look truncated")
+	}
+	if looksTruncated("<ralph_notes>left open", tags...) {
+		t.Fatalf("default tags should not count once custom tags are configured")
+	}
+
+	got := sanitizeNotes("start <agent_log>inner</agent_log> <loop_state>COMPLETE <agent_logger>", tags...)
+	if want := "start inner COMPLETE <agent_logger>"; got != want {
+		t.Fatalf("sanitizeNotes with custom tags: got %q want %q", got, want)
+	}
+}
+
+func TestRetryOnTruncationWithCustomTags(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	cfg.NotesTag, cfg.StatusTag = "agent_log", "loop_state"
+	writeContextFiles(t, cfg)
+
+	outputs := []string{
+		"<agent_log>n</agent_log><loop_state>COMPL",
+		"<agent_log>nested <loop_state>x</loop_state></agent_log><loop_state>COMPLETE</loop_state>",
+	}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			out := outputs[calls]
+			calls++
+			return out, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, RetryOnTruncation: true}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if calls != 2 || result.Truncated != 1 || result.Status != "complete" {
+		t.Fatalf("expected one truncation retried to completion, got %d calls and %+v", calls, result)
+	}
+	if notes := readNotes(t, notesFile); strings.Contains(notes, "<loop_state>") || !strings.Contains(notes, "nested x") {
+		t.Fatalf("expected the nested status tag stripped from notes, got %q", notes)
+	}
+}
+
+func TestValidateTagName(t *testing.T) {
+	for _, tag := range []string{"ralph_notes", "Notes2", "x"} {
+		if err := validateTagName(tag); err != nil {
+			t.Fatalf("validateTagName(%q): %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "my-notes", "a.b", "notes>", "two words"} {
+		if err := validateTagName(tag); err == nil {
+			t.Fatalf("expected validateTagName(%q) to fail", tag)
+		}
+	}
+}
+
+func TestConfiguredTagsDriveTheLoop(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	cfg.NotesTag, cfg.StatusTag = "agent_log", "loop_state"
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<agent_log>custom note</agent_log><loop_state>COMPLETE</loop_state>", nil
+		},
+	}
+	result, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 3, Quiet: true}, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if result.Status != "complete" || result.Iterations != 1 {
+		t.Fatalf("expected completion on the first iteration, got %+v", result)
+	}
+	if notes := readNotes(t, notesFile); !strings.Contains(notes, "custom note") {
+		t.Fatalf("expected the custom-tag note to be saved, got %q", notes)
+	}
+}
+
+func TestIsCompleteCustomSignal(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal string
+		output string
+		want   bool
+	}{
+		{"custom word", "DONE", "<ralph_status> done </ralph_status>", true},
+		{"custom word ignores default", "DONE", "<ralph_status>COMPLETE</ralph_status>", false},
+		{"word must be tagged", "DONE", "DONE", false},
+		{"full tag", "<task_finished/>", "all good\n<TASK_FINISHED/>\n", true},
+		{"full tag missing", "<task_finished/>", "<task_started/>", false},
+		{"metacharacters are literal", "ALL.DONE+", "<ralph_status>ALL.DONE+</ralph_status>", true},
+		{"metacharacters do not match as regex", "ALL.DONE+", "<ralph_status>ALLxDONEE</ralph_status>", false},
+		{"internal whitespace is tolerant", "ALL DONE", "<ralph_status>ALL\n  DONE</ralph_status>", true},
+		{"empty falls back to default", "", "<ralph_status>COMPLETE</ralph_status>", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isComplete(tt.output, tt.signal, defaultStatusTag); got != tt.want {
+				t.Fatalf("isComplete(%q, %q) = %v, want %v", tt.output, tt.signal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSetCompletionSignal(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("completion_signal", "<task_finished/>"); err != nil {
+		t.Fatalf("ConfigSet: %v", err)
+	}
+	if got := loadConfigFile().CompletionSignal; got != "<task_finished/>" {
+		t.Fatalf("completion_signal = %q", got)
+	}
+	if err := ConfigSet("completion_signal", "  "); err == nil {
+		t.Fatalf("expected empty completion_signal to be rejected")
+	}
+}
+
 func TestAppendNotesCreatesEntry(t *testing.T) {
 	withTempCWD(t)
 
@@ -129,7 +306,7 @@ func TestAppendNotesCreatesEntry(t *testing.T) {
 		t.Fatalf("mkdir %s: %v", ralphDir, err)
 	}
 
-	if err := appendNotes("some notes", 7); err != nil {
+	if err := appendNotes("some notes", 7, notesLimit{}); err != nil {
 		t.Fatalf("appendNotes: %v", err)
 	}
 
@@ -199,15 +376,42 @@ func TestAcquireLockFailsWhenHeld(t *testing.T) {
 	}
 }
 
+func TestNoLockIgnoresHeldLock(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	held := fmt.Sprintf("%d\n", os.Getpid())
+	if err := os.WriteFile(lockFile, []byte(held), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, Quiet: true}, completeRunner()); err == nil {
+		t.Fatalf("expected a held lock to block a normal run")
+	}
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, Quiet: true, NoLock: true}, completeRunner()); err != nil {
+		t.Fatalf("--no-lock run: %v", err)
+	}
+
+	data, err := os.ReadFile(lockFile)
+	if err != nil || string(data) != held {
+		t.Fatalf("expected --no-lock to leave the other run's lock alone, got %q, %v", data, err)
+	}
+}
+
 func TestCountRecentIterations(t *testing.T) {
-	now := time.Now().Unix()
+	current := time.Now()
+	now := current.Unix()
 	timestamps := []int64{
 		now - int64(30*time.Minute.Seconds()),
 		now - int64(2*time.Hour.Seconds()),
 		now - int64(25*time.Hour.Seconds()),
 	}
 
-	hourCount, dayCount := countRecentIterations(timestamps)
+	hourCount, dayCount := countRecentIterations(timestamps, current)
 	if hourCount != 1 {
 		t.Fatalf("hourCount: got %d want %d", hourCount, 1)
 	}
@@ -216,6 +420,203 @@ func TestCountRecentIterations(t *testing.T) {
 	}
 }
 
+func TestCountRecentMinute(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	timestamps := []int64{
+		now.Add(-45 * time.Second).Unix(),
+		now.Add(-10 * time.Second).Unix(),
+		now.Add(-2 * time.Minute).Unix(),
+	}
+
+	if got := countRecentMinute(timestamps, now); got != 2 {
+		t.Fatalf("count: got %d want %d", got, 2)
+	}
+}
+
+func TestFullRateWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	ago := func(d time.Duration) int64 { return now.Add(-d).Unix() }
+
+	tests := []struct {
+		name       string
+		timestamps []int64
+		windows    []rateWindow
+		wantFull   bool
+		wantWindow string
+		wantCount  int
+		wantWait   time.Duration
+	}{
+		{
+			name:       "room left",
+			timestamps: []int64{ago(10 * time.Second)},
+			windows:    activeRateWindows(2, 0, 0),
+		},
+		{
+			name:       "minute full waits for oldest",
+			timestamps: []int64{ago(45 * time.Second), ago(10 * time.Second), ago(2 * time.Minute)},
+			windows:    activeRateWindows(2, 0, 0),
+			wantFull:   true, wantWindow: "minute", wantCount: 2, wantWait: 15 * time.Second,
+		},
+		{
+			name:       "over the cap waits until enough expire",
+			timestamps: []int64{ago(50 * time.Minute), ago(40 * time.Minute), ago(5 * time.Minute)},
+			windows:    activeRateWindows(0, 2, 0),
+			wantFull:   true, wantWindow: "hour", wantCount: 3, wantWait: 20 * time.Minute,
+		},
+		{
+			name:       "day checked after hour",
+			timestamps: []int64{ago(3 * time.Hour), ago(2 * time.Hour)},
+			windows:    activeRateWindows(0, 5, 2),
+			wantFull:   true, wantWindow: "day", wantCount: 2, wantWait: 21 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, count, wait, full := fullRateWindow(tt.timestamps, tt.windows, now)
+			if full != tt.wantFull || window.Name != tt.wantWindow || count != tt.wantCount || wait != tt.wantWait {
+				t.Fatalf("got (%q, %d, %s, %v) want (%q, %d, %s, %v)", window.Name, count, wait, full, tt.wantWindow, tt.wantCount, tt.wantWait, tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestWaitOnLimitSleepsThenContinues(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	start := clock.current
+	saveState(State{Timestamps: []int64{
+		start.Add(-59 * time.Minute).Unix(),
+		start.Add(-30 * time.Minute).Unix(),
+	}})
+
+	calls := 0
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 1, MaxPerHour: 2, WaitOnLimit: true}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if calls != 1 {
+		t.Fatalf("expected the iteration to run after waiting, calls = %d", calls)
+	}
+	if waited := clock.current.Sub(start); waited != time.Minute {
+		t.Fatalf("waited %s, want %s", waited, time.Minute)
+	}
+	if !strings.Contains(out, "waiting for capacity") || !strings.Contains(out, "Waiting 1m0s...") {
+		t.Fatalf("expected wait messages, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Status: COMPLETE") {
+		t.Fatalf("expected run to complete, got:\n%s", out)
+	}
+}
+
+func TestWaitOnLimitRespectsMaxRuntime(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saveState(State{Timestamps: []int64{clock.current.Add(-10 * time.Minute).Unix()}})
+
+	opts := RunOptions{MaxIterations: 1, MaxPerHour: 1, WaitOnLimit: true, MaxRuntime: 5 * time.Minute, Quiet: true, SummaryLog: "runs.log"}
+	if _, err := runIterationsWithRunner(cfg, opts, completeRunner()); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":"time_limit"`) {
+		t.Fatalf("expected time_limit after waiting out the runtime, got %s", data)
+	}
+}
+
+func TestRateCountsFollowThePackageClock(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	saveState(State{Timestamps: []int64{
+		clock.current.Add(-30 * time.Second).Unix(),
+		clock.current.Add(-30 * time.Minute).Unix(),
+		clock.current.Add(-2 * time.Hour).Unix(),
+	}})
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 1, MaxPerMinute: 10, MaxPerHour: 10, Verbose: true}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Rate: 1/minute, 2/hour, 3/day") {
+		t.Fatalf("expected counts against the package clock, got:\n%s", out)
+	}
+	if state := loadState(); len(state.Timestamps) != 4 || state.Timestamps[3] != clock.current.Unix() {
+		t.Fatalf("expected the iteration recorded at the package clock, got %v", state.Timestamps)
+	}
+}
+
+func TestMaxPerMinuteStopsRateLimited(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	calls := 0
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 5, MaxPerMinute: 2, SummaryLog: "runs.log"}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if calls != 2 {
+		t.Fatalf("calls: got %d want %d", calls, 2)
+	}
+	if !strings.Contains(out, "2 iterations in the past minute (max: 2); try again in") {
+		t.Fatalf("expected per-minute rate message, got:\n%s", out)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":"rate_limited"`) {
+		t.Fatalf("expected rate_limited status, got %s", data)
+	}
+}
+
 func TestPruneOldTimestamps(t *testing.T) {
 	now := time.Now().Unix()
 	state := State{
@@ -231,6 +632,48 @@ func TestPruneOldTimestamps(t *testing.T) {
 	}
 }
 
+func TestLoadStateBacksUpCorruptFile(t *testing.T) {
+	withTempCWD(t)
+
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", ralphDir, err)
+	}
+	corrupt := []byte("{not json")
+	if err := os.WriteFile(stateFile, corrupt, 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	var state State
+	warning := captureOutput(t, &os.Stderr, func() {
+		state = loadState()
+	})
+
+	if state.TotalIterations != 0 || len(state.Timestamps) != 0 {
+		t.Fatalf("expected empty state, got %+v", state)
+	}
+	if !strings.Contains(warning, "Warning:") || !strings.Contains(warning, "corrupt") {
+		t.Fatalf("expected corrupt state warning, got %q", warning)
+	}
+
+	backups, err := filepath.Glob(stateFile + ".corrupt.*")
+	if err != nil {
+		t.Fatalf("glob backups: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups: got %d want %d", len(backups), 1)
+	}
+	data, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if string(data) != string(corrupt) {
+		t.Fatalf("backup content: got %q want %q", data, corrupt)
+	}
+	if !strings.Contains(warning, backups[0]) {
+		t.Fatalf("expected warning to name backup %q, got %q", backups[0], warning)
+	}
+}
+
 func TestOrchestratorUsesRunnerAndStopsOnComplete(t *testing.T) {
 	withTempCWD(t)
 
@@ -260,7 +703,7 @@ func TestOrchestratorUsesRunnerAndStopsOnComplete(t *testing.T) {
 		},
 	}
 
-	if err := runIterationsWithRunner(cfg, 3, 0, 0, "", "", "", "", "", 0, false, "", nil, "", true, false, false, 0, runner); err != nil {
+	if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: 3, Quiet: true}, runner); err != nil {
 		t.Fatalf("runIterationsWithRunner: %v", err)
 	}
 	if calls != 1 {
@@ -268,6 +711,29 @@ func TestOrchestratorUsesRunnerAndStopsOnComplete(t *testing.T) {
 	}
 }
 
+func writeContextFiles(t *testing.T, cfg Config) {
+	t.Helper()
+
+	files := map[string]string{
+		cfg.PromptFile:      "PROMPT",
+		cfg.ConventionsFile: "CONVENTIONS",
+		cfg.SpecsFile:       "SPECS",
+	}
+	for path, body := range files {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func completeRunner() *fakeRunner {
+	return &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			return "<ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+}
+
 type fakeRunner struct {
 	runFunc func(OpencodeRunArgs) (string, error)
 }
@@ -278,3 +744,645 @@ func (r *fakeRunner) Run(args OpencodeRunArgs) (string, error) {
 	}
 	return r.runFunc(args)
 }
+
+func TestBuildOpencodeArgsPromptStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  []string
+	}{
+		{name: "default", style: "", want: []string{"run", "-m", "m", "do it"}},
+		{name: "positional", style: "positional", want: []string{"run", "-m", "m", "do it"}},
+		{name: "flag", style: "flag", want: []string{"run", "-m", "m", "--prompt", "do it"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildOpencodeArgs(OpencodeRunArgs{Subcommand: "run", Prompt: "do it", Model: "m", PromptArgStyle: tt.style})
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSetPromptArgStyle(t *testing.T) {
+	withTempCWD(t)
+
+	if got := loadConfigFile().PromptArgStyle; got != "positional" {
+		t.Fatalf("default PromptArgStyle: got %q want %q", got, "positional")
+	}
+	if err := ConfigSet("prompt_arg_style", "flag"); err != nil {
+		t.Fatalf("ConfigSet prompt_arg_style: %v", err)
+	}
+	if got := loadConfigFile().PromptArgStyle; got != "flag" {
+		t.Fatalf("PromptArgStyle: got %q want %q", got, "flag")
+	}
+	if err := ConfigSet("prompt_arg_style", "stdin"); err == nil {
+		t.Fatalf("expected error for invalid prompt_arg_style")
+	}
+}
+
+func TestTruncateNotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		notes    string
+		maxChars int
+		want     string
+	}{
+		{name: "unlimited", notes: "abcdef", maxChars: 0, want: "abcdef"},
+		{name: "fits", notes: "abc", maxChars: 3, want: "abc"},
+		{name: "truncated", notes: "abcdef", maxChars: 2, want: "ab\n[notes truncated: kept 2 of 6 characters]"},
+		{name: "multibyte", notes: "héllo", maxChars: 2, want: "hé\n[notes truncated: kept 2 of 5 characters]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateNotes(tt.notes, tt.maxChars); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxNotesPerIterationTruncatesOnAppend(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	outputs := []string{
+		"<ralph_notes>short</ralph_notes>",
+		"<ralph_notes>" + strings.Repeat("x", 50) + "</ralph_notes>",
+	}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			out := outputs[calls]
+			calls++
+			return out, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, MaxNotesChars: 10}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	text := string(data)
+	if !strings.Contains(text, "short\n") {
+		t.Fatalf("expected small notes untouched, got %q", text)
+	}
+	if strings.Contains(text, strings.Repeat("x", 11)) {
+		t.Fatalf("expected oversized notes to be truncated, got %q", text)
+	}
+	if !strings.Contains(text, strings.Repeat("x", 10)+"\n[notes truncated: kept 10 of 50 characters]") {
+		t.Fatalf("expected truncation marker, got %q", text)
+	}
+}
+
+func TestBuildOpencodeArgsSubcommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		subcommand string
+		want       []string
+	}{
+		{name: "default", subcommand: DefaultConfig().OpencodeSubcommand, want: []string{"run", "--agent", "a", "go"}},
+		{name: "custom", subcommand: "exec", want: []string{"exec", "--agent", "a", "go"}},
+		{name: "empty", subcommand: "", want: []string{"--agent", "a", "go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildOpencodeArgs(OpencodeRunArgs{Subcommand: tt.subcommand, Prompt: "go", Agent: "a"})
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSetOpencodeSubcommand(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("opencode_subcommand", ""); err != nil {
+		t.Fatalf("ConfigSet opencode_subcommand empty: %v", err)
+	}
+	if got := loadConfigFile().OpencodeSubcommand; got != "" {
+		t.Fatalf("OpencodeSubcommand: got %q want empty", got)
+	}
+	if err := ConfigSet("opencode_subcommand", "run now"); err == nil {
+		t.Fatalf("expected error for subcommand containing spaces")
+	}
+}
+
+func TestLooksTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{name: "empty", in: "", want: false},
+		{name: "plain", in: "did some work", want: false},
+		{name: "balanced", in: "<ralph_notes>n</ralph_notes>\n<ralph_status>COMPLETE</ralph_status>\n", want: false},
+		{name: "unclosed notes", in: "<ralph_notes>half of the", want: true},
+		{name: "unclosed status", in: "<ralph_notes>n</ralph_notes><ralph_status>COMPL", want: true},
+		{name: "stray closer", in: "n</ralph_notes>", want: true},
+		{name: "ends inside tag", in: "all done\n</ralph_", want: true},
+		{name: "ends inside opener", in: "all done <ralph_stat", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := looksTruncated(tt.in, defaultNotesTag, defaultStatusTag); got != tt.want {
+				t.Fatalf("looksTruncated(%q): got %v want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOnTruncation(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	outputs := []string{
+		"<ralph_status>COMPL",
+		"<ralph_status>COMPLETE</ralph_status>",
+	}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			out := outputs[calls]
+			calls++
+			return out, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, RetryOnTruncation: true, SummaryLog: "runs.log"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("runner calls: got %d want %d", calls, 2)
+	}
+
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":"complete"`) || !strings.Contains(string(data), `"truncated":1`) {
+		t.Fatalf("expected complete status with one truncation, got %s", data)
+	}
+}
+
+func TestResolveModelPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		flagModel    string
+		flagExplicit bool
+		configModel  string
+		preferConfig bool
+		want         string
+	}{
+		{name: "explicit flag beats config", flagModel: "flag/m", flagExplicit: true, configModel: "cfg/m", want: "flag/m"},
+		{name: "inherited flag beats config", flagModel: "flag/m", configModel: "cfg/m", want: "flag/m"},
+		{name: "config when flag unset", configModel: "cfg/m", want: "cfg/m"},
+		{name: "nothing set", want: ""},
+		{name: "prefer config over inherited flag", flagModel: "flag/m", configModel: "cfg/m", preferConfig: true, want: "cfg/m"},
+		{name: "prefer config keeps explicit flag", flagModel: "flag/m", flagExplicit: true, configModel: "cfg/m", preferConfig: true, want: "flag/m"},
+		{name: "prefer config with config unset", flagModel: "flag/m", preferConfig: true, want: "flag/m"},
+		{name: "prefer config with flag unset", configModel: "cfg/m", preferConfig: true, want: "cfg/m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveModel(tt.flagModel, tt.flagExplicit, tt.configModel, tt.preferConfig)
+			if got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigUnset(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("model", "ollama/x"); err != nil {
+		t.Fatalf("ConfigSet model: %v", err)
+	}
+	if err := ConfigSet("max_iterations", "7"); err != nil {
+		t.Fatalf("ConfigSet max_iterations: %v", err)
+	}
+
+	if err := ConfigUnset("model"); err != nil {
+		t.Fatalf("ConfigUnset model: %v", err)
+	}
+	cfg := loadConfigFile()
+	if cfg.Model != "" {
+		t.Fatalf("Model: got %q want empty", cfg.Model)
+	}
+	if cfg.MaxIterations != 7 {
+		t.Fatalf("MaxIterations: got %d want %d (unset must not touch other keys)", cfg.MaxIterations, 7)
+	}
+
+	if err := ConfigUnset("max_iterations"); err != nil {
+		t.Fatalf("ConfigUnset max_iterations: %v", err)
+	}
+	if got := loadConfigFile().MaxIterations; got != 50 {
+		t.Fatalf("MaxIterations: got %d want %d", got, 50)
+	}
+
+	if err := ConfigUnset("unknown_key"); err == nil {
+		t.Fatalf("expected error for unknown_key")
+	}
+}
+
+func TestConfigFieldsMatchConfigStruct(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, field := range configFields() {
+		if _, ok := configValue(&cfg, field.Key); !ok {
+			t.Fatalf("config field %q has no matching Config struct tag", field.Key)
+		}
+	}
+}
+
+func TestConfigSetListAndJSONValues(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("blocked_dirs", "/srv, /opt ,"); err != nil {
+		t.Fatalf("ConfigSet blocked_dirs: %v", err)
+	}
+	if got := loadConfigFile().BlockedDirs; strings.Join(got, "|") != "/srv|/opt" {
+		t.Fatalf("BlockedDirs: got %q", got)
+	}
+
+	if err := ConfigSet("escalation_hints", `[{"at":0.9,"message":"hurry"}]`); err != nil {
+		t.Fatalf("ConfigSet escalation_hints: %v", err)
+	}
+	if got := loadConfigFile().EscalationHints; len(got) != 1 || got[0].Message != "hurry" {
+		t.Fatalf("EscalationHints: got %+v", got)
+	}
+	if err := ConfigSet("escalation_hints", "not json"); err == nil {
+		t.Fatalf("expected error for invalid escalation_hints JSON")
+	}
+}
+
+func TestRequireNotesAddsFeedback(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			if len(prompts) == 1 {
+				return "no notes here", nil
+			}
+			return "<ralph_notes>done</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, RequireNotes: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if len(prompts) != 3 {
+		t.Fatalf("expected 3 iterations, got %d", len(prompts))
+	}
+	if strings.Contains(prompts[0], missingNotesFeedback(defaultNotesTag)) {
+		t.Fatalf("first prompt should not carry feedback")
+	}
+	if !strings.Contains(prompts[1], missingNotesFeedback(defaultNotesTag)) {
+		t.Fatalf("expected feedback after missing notes, got %q", prompts[1])
+	}
+	if strings.Contains(prompts[2], missingNotesFeedback(defaultNotesTag)) {
+		t.Fatalf("feedback should clear once notes are produced")
+	}
+}
+
+func TestRequireNotesCountsTowardBreaker(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	calls := 0
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			return "no notes here", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 5, Quiet: true, RequireNotes: true, MaxConsecutiveFailures: 2}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the breaker to stop after 2 iterations, got %d", calls)
+	}
+	if result.Status != "failed" {
+		t.Fatalf("expected status failed, got %q", result.Status)
+	}
+}
+
+func TestStrictNotesFailsOnMissingNotes(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "no notes here", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, StrictNotes: true}
+	_, err := runIterationsWithRunner(cfg, opts, runner)
+	if err == nil || !strings.Contains(err.Error(), "produced no <ralph_notes>") {
+		t.Fatalf("expected missing notes error, got %v", err)
+	}
+}
+
+func TestRunFailsFastWhenOpencodeMissing(t *testing.T) {
+	withTempCWD(t)
+	t.Setenv("PATH", t.TempDir())
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		err = RunWithOptions(RunOptions{MaxIterations: 1}, 1, 0, 0)
+	})
+	if !errors.Is(err, ErrOpencodeNotFound) {
+		t.Fatalf("expected ErrOpencodeNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "opencode not found on PATH") {
+		t.Fatalf("expected friendly message, got %q", err.Error())
+	}
+	if out != "" {
+		t.Fatalf("expected no banner before the check, got %q", out)
+	}
+}
+
+func TestCheckOpencodeInstalledWithBinary(t *testing.T) {
+	dir := t.TempDir()
+	wrapper := filepath.Join(dir, "opencode-wrapper")
+	if err := os.WriteFile(wrapper, []byte("#!/bin/sh\nexec opencode \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := filepath.Join(dir, "opencode.txt")
+	if err := os.WriteFile(notExecutable, []byte("not a program"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		binary  string
+		wantErr string
+	}{
+		{name: "wrapper script", binary: wrapper},
+		{name: "missing path", binary: filepath.Join(dir, "missing"), wantErr: "is not an executable file"},
+		{name: "not executable", binary: notExecutable, wantErr: "is not an executable file"},
+		{name: "default not on PATH", binary: "", wantErr: "opencode not found on PATH"},
+	}
+	t.Setenv("PATH", t.TempDir())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOpencodeInstalled(exec.LookPath, tt.binary)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrOpencodeNotFound) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected ErrOpencodeNotFound containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOpencodeCommandUsesBinary(t *testing.T) {
+	cmd := opencodeCommand(context.Background(), OpencodeRunArgs{Binary: "/opt/opencode/bin/opencode", Subcommand: "run", Prompt: "p"})
+	if cmd.Args[0] != "/opt/opencode/bin/opencode" {
+		t.Fatalf("argv[0]: got %q", cmd.Args[0])
+	}
+	cmd = opencodeCommand(context.Background(), OpencodeRunArgs{Subcommand: "run", Prompt: "p"})
+	if cmd.Args[0] != "opencode" {
+		t.Fatalf("default argv[0]: got %q", cmd.Args[0])
+	}
+}
+
+func TestPromptOutWritesOnlyThePrompt(t *testing.T) {
+	withTempCWD(t)
+	t.Setenv("PATH", t.TempDir())
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		err = RunWithOptions(RunOptions{MaxIterations: 3, PromptOut: "prompt.txt", Strict: true}, 3, 0, 0)
+	})
+	if err != nil {
+		t.Fatalf("RunWithOptions: %v", err)
+	}
+	if out != "" {
+		t.Fatalf("expected nothing on stdout, got %q", out)
+	}
+	data, err := os.ReadFile("prompt.txt")
+	if err != nil {
+		t.Fatalf("read prompt: %v", err)
+	}
+	if want := constructPrompt("PROMPT", "CONVENTIONS", "SPECS", "No notes yet.", 1, 3); string(data) != want {
+		t.Fatalf("prompt file:\n%s\nwant:\n%s", data, want)
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no state to be written, stat err: %v", err)
+	}
+}
+
+func TestDryRunAllPrintsEveryPrompt(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	writeNotes(t, "## Iteration 1 - 2026-01-01 00:00:00\nfirst\n\n## Iteration 2 - 2026-01-01 00:01:00\nsecond\n")
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			t.Errorf("--dry-run-all must not call opencode")
+			return "", nil
+		},
+	}
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		_, err = runIterationsWithRunner(cfg, RunOptions{MaxIterations: 3, DryRun: true, DryRunAll: true, NotesTail: 1, AutoExtend: 2, AutoExtendCap: 10}, runner)
+	})
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	for n := 1; n <= 3; n++ {
+		if !strings.Contains(out, fmt.Sprintf("--- DRY RUN: Prompt for iteration %d ---\n", n)) || !strings.Contains(out, fmt.Sprintf("Iteration: %d of 3\n", n)) {
+			t.Fatalf("expected the prompt for iteration %d, got:\n%s", n, out)
+		}
+	}
+	if strings.Contains(out, "iteration 4") || strings.Count(out, dryRunMarker) != 1 || strings.Contains(out, "Reached maximum iterations") {
+		t.Fatalf("expected exactly 3 prompts and one marker, got:\n%s", out)
+	}
+	if strings.Contains(out, "first") {
+		t.Fatalf("expected --notes-tail to apply to each prompt, got:\n%s", out)
+	}
+}
+
+func TestDryRunMarkerAndStrict(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			var err error
+			out := captureOutput(t, &os.Stdout, func() {
+				_, err = runIterationsWithRunner(cfg, RunOptions{MaxIterations: 1, DryRun: true, Strict: strict}, completeRunner())
+			})
+			if !strings.Contains(out, "--- END DRY RUN ---\n"+dryRunMarker+"\n") {
+				t.Fatalf("expected dry run marker line, got %q", out)
+			}
+			if strict && !errors.Is(err, ErrDryRun) {
+				t.Fatalf("expected ErrDryRun under --strict, got %v", err)
+			}
+			if !strict && err != nil {
+				t.Fatalf("expected success without --strict, got %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveFormatPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		flagFormat   string
+		flagExplicit bool
+		configFormat string
+		want         string
+	}{
+		{name: "config only", configFormat: "json", want: "json"},
+		{name: "flag only", flagFormat: "json", flagExplicit: true, want: "json"},
+		{name: "flag beats config", flagFormat: "default", flagExplicit: true, configFormat: "json", want: "default"},
+		{name: "neither set", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveFormat(tt.flagFormat, tt.flagExplicit, tt.configFormat)
+			if got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRejectsInvalidConfigFormat(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	cfg.Format = "yaml"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+
+	err := RunWithOptions(RunOptions{DryRun: true, FormatInherited: true}, 1, 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "invalid .ralph/config.json: invalid format: yaml") {
+		t.Fatalf("expected config format error, got %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "defaults", mutate: func(*Config) {}},
+		{name: "model with tag", mutate: func(c *Config) { c.Model = "ollama/qwen3-coder:30b" }},
+		{name: "negative max_iterations", mutate: func(c *Config) { c.MaxIterations = -1 }, wantErr: "max_iterations must be at least 1"},
+		{name: "negative max_per_hour", mutate: func(c *Config) { c.MaxPerHour = -5 }, wantErr: "max_per_hour must be at least 0"},
+		{name: "negative max_per_minute", mutate: func(c *Config) { c.MaxPerMinute = -1 }, wantErr: "max_per_minute must be at least 0"},
+		{name: "empty prompt_file", mutate: func(c *Config) { c.PromptFile = "" }, wantErr: "prompt_file must not be empty"},
+		{name: "blank specs_file", mutate: func(c *Config) { c.SpecsFile = "  " }, wantErr: "specs_file must not be empty"},
+		{name: "model without provider", mutate: func(c *Config) { c.Model = "gpt-4" }, wantErr: "expected provider/model"},
+		{name: "model with empty name", mutate: func(c *Config) { c.Model = "anthropic/" }, wantErr: "expected provider/model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := ValidateConfig(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigSetRejectsBeforeSaving(t *testing.T) {
+	withTempCWD(t)
+
+	for key, value := range map[string]string{"max_iterations": "-3", "prompt_file": "", "model": "no-provider"} {
+		if err := ConfigSet(key, value); err == nil {
+			t.Fatalf("expected ConfigSet %s %q to fail", key, value)
+		}
+	}
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no config file to be written, stat err: %v", err)
+	}
+}
+
+func TestLoadConfigRejectsInvalidFile(t *testing.T) {
+	withTempCWD(t)
+
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, []byte(`{"max_iterations": -1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "max_iterations must be at least 1") {
+		t.Fatalf("expected max_iterations error, got %v", err)
+	}
+}
+
+func TestConfigSetFormatValidates(t *testing.T) {
+	withTempCWD(t)
+
+	if err := ConfigSet("format", "json"); err != nil {
+		t.Fatalf("set format: %v", err)
+	}
+	if got := loadConfigFile().Format; got != "json" {
+		t.Fatalf("format: got %q want %q", got, "json")
+	}
+	if err := ConfigSet("format", "xml"); err == nil {
+		t.Fatalf("expected error for invalid format")
+	}
+}
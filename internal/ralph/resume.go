@@ -0,0 +1,60 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultResumeWindow = "1h"
+
+func validateResumeWindow(value string) error {
+	if _, err := parseResumeWindow(value); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseResumeWindow(value string) (time.Duration, error) {
+	if value == "" {
+		value = defaultResumeWindow
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resume_window %q: %w", value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid resume_window %q: must not be negative", value)
+	}
+	return d, nil
+}
+
+// resumeSession decides how --resume should pick up work. A run that ended
+// within window continues the stored session, or the most recent one when no
+// session ID was recorded; anything older starts fresh.
+func resumeSession(state State, window time.Duration, at time.Time) (continueSession bool, session string) {
+	if state.LastRun.IsZero() || at.Sub(state.LastRun) > window {
+		return false, ""
+	}
+	if state.SessionID != "" {
+		return false, state.SessionID
+	}
+	return true, ""
+}
+
+// ErrNothingToResume is returned by the resume command when no earlier run
+// has been recorded in .ralph/state.json.
+var ErrNothingToResume = errors.New("nothing to resume: no previous run recorded in " + stateFile + " (start one with run)")
+
+// lastSession picks how the resume command continues the previous run,
+// regardless of how long ago it ended: the stored session when known,
+// otherwise opencode's most recent one.
+func lastSession(state State) (continueSession bool, session string, err error) {
+	if state.TotalIterations == 0 {
+		return false, "", ErrNothingToResume
+	}
+	if state.SessionID != "" {
+		return false, state.SessionID, nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,186 @@
+package ralph
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeSession(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		state        State
+		wantContinue bool
+		wantSession  string
+	}{
+		{name: "never ran", state: State{}},
+		{name: "stale", state: State{LastRun: at.Add(-2 * time.Hour), SessionID: "ses_1"}},
+		{name: "recent with session", state: State{LastRun: at.Add(-10 * time.Minute), SessionID: "ses_1"}, wantSession: "ses_1"},
+		{name: "recent without session", state: State{LastRun: at.Add(-10 * time.Minute)}, wantContinue: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotContinue, gotSession := resumeSession(tt.state, time.Hour, at)
+			if gotContinue != tt.wantContinue || gotSession != tt.wantSession {
+				t.Fatalf("got (%v, %q) want (%v, %q)", gotContinue, gotSession, tt.wantContinue, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestParseResumeWindow(t *testing.T) {
+	if got, err := parseResumeWindow(""); err != nil || got != time.Hour {
+		t.Fatalf("default: got %s, %v", got, err)
+	}
+	if got, err := parseResumeWindow("30m"); err != nil || got != 30*time.Minute {
+		t.Fatalf("30m: got %s, %v", got, err)
+	}
+	if _, err := parseResumeWindow("soon"); err == nil {
+		t.Fatalf("expected error for invalid window")
+	}
+	if err := ConfigSet("resume_window", "-1h"); err == nil {
+		t.Fatalf("expected ConfigSet to reject a negative window")
+	}
+}
+
+func TestJSONFormatStoresSessionID(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return sampleOpencodeJSON, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, Format: "json"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if got := loadState().SessionID; got != "ses_1" {
+		t.Fatalf("SessionID: got %q want ses_1", got)
+	}
+}
+
+func TestLastSession(t *testing.T) {
+	longAgo := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		state        State
+		wantContinue bool
+		wantSession  string
+		wantErr      bool
+	}{
+		{name: "never ran", state: State{}, wantErr: true},
+		{name: "stored session however old", state: State{TotalIterations: 4, LastRun: longAgo, SessionID: "ses_1"}, wantSession: "ses_1"},
+		{name: "no stored session", state: State{TotalIterations: 4, LastRun: longAgo}, wantContinue: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotContinue, gotSession, err := lastSession(tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotContinue != tt.wantContinue || gotSession != tt.wantSession {
+				t.Fatalf("got (%v, %q) want (%v, %q)", gotContinue, gotSession, tt.wantContinue, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestResumeLastRequiresPriorRun(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	err := RunWithOptions(RunOptions{ResumeLast: true, DryRun: true}, 1, 0, 0)
+	if !errors.Is(err, ErrNothingToResume) {
+		t.Fatalf("expected ErrNothingToResume, got %v", err)
+	}
+}
+
+func TestResumeLastKeepsIterationCount(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+	if err := os.MkdirAll(ralphDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saveState(State{TotalIterations: 5, Timestamps: []int64{}})
+
+	out := captureOutput(t, &os.Stdout, func() {
+		if err := RunWithOptions(RunOptions{ResumeLast: true, DryRun: true, MaxIterations: 1}, 1, 0, 0); err != nil {
+			t.Fatalf("RunWithOptions: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Iteration 6 ") {
+		t.Fatalf("expected iteration numbering to carry on from state, got:\n%s", out)
+	}
+}
+
+func TestSessionFile(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.WriteFile("session.txt", []byte("ses_prev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			sessions = append(sessions, args.Session)
+			return sampleOpencodeJSON, nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 2, Quiet: true, Format: "json", SessionFile: "session.txt"}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if len(sessions) != 2 || sessions[0] != "ses_prev" || sessions[1] != "" {
+		t.Fatalf("expected the saved session for the first iteration only, got %q", sessions)
+	}
+	data, err := os.ReadFile("session.txt")
+	if err != nil {
+		t.Fatalf("read session file: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "ses_1" {
+		t.Fatalf("session file: got %q want ses_1", got)
+	}
+}
+
+func TestValidateSessionFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       RunOptions
+		wantErr    bool
+		wantFormat string
+	}{
+		{name: "unset", opts: RunOptions{Format: "default"}, wantFormat: "default"},
+		{name: "implies json", opts: RunOptions{SessionFile: "s", Format: "default", FormatInherited: true}, wantFormat: "json"},
+		{name: "explicit default format", opts: RunOptions{SessionFile: "s", Format: "default"}, wantErr: true},
+		{name: "with --session", opts: RunOptions{SessionFile: "s", Session: "ses_1", FormatInherited: true}, wantErr: true},
+		{name: "with --resume", opts: RunOptions{SessionFile: "s", Resume: true, FormatInherited: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := validateSessionFile(&opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && opts.Format != tt.wantFormat {
+				t.Fatalf("format: got %q want %q", opts.Format, tt.wantFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ralph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunResult is the outcome of a run, as printed in the summary block.
+type RunResult struct {
+	RunID string `json:"run_id"`
+	// Status is the final status: complete, max_iterations, rate_limited,
+	// time_limit, token_budget, failed, dry_run, canceled, or unknown.
+	Status     string        `json:"status"`
+	Iterations int           `json:"iterations"`
+	Duration   time.Duration `json:"duration"`
+	// Tokens is the token usage reported by --format json, or 0.
+	Tokens int `json:"tokens,omitempty"`
+	// MinuteCount, HourCount, and DayCount are the iterations recorded in
+	// state within the past minute, hour, and day when the run ended.
+	MinuteCount    int               `json:"minute_count"`
+	HourCount      int               `json:"hour_count"`
+	DayCount       int               `json:"day_count"`
+	Truncated      int               `json:"truncated,omitempty"`
+	MissingNotes   int               `json:"missing_notes,omitempty"`
+	Failures       int               `json:"failures,omitempty"`
+	Retries        int               `json:"retries,omitempty"`
+	TimedOut       int               `json:"timed_out,omitempty"`
+	SlowIterations int               `json:"slow_iterations,omitempty"`
+	Activity       string            `json:"activity,omitempty"`
+	Meta           map[string]string `json:"meta,omitempty"`
+	// InGit reports whether the run started in a git work tree, in which
+	// case FilesChanged lists the files it changed.
+	InGit        bool     `json:"in_git"`
+	FilesChanged []string `json:"files_changed,omitempty"`
+	// Tasks is the specs checklist after the last iteration; only set with
+	// --track-specs.
+	Tasks *TaskCounts `json:"tasks,omitempty"`
+	// Phase is the --phases specs file this run covered, if any.
+	Phase string `json:"phase,omitempty"`
+}
+
+// summaryRecord converts r to its --summary-log record.
+func (r RunResult) summaryRecord(start time.Time, model string) RunSummary {
+	return RunSummary{
+		RunID:          r.RunID,
+		Timestamp:      start.Format(time.RFC3339),
+		Status:         r.Status,
+		Iterations:     r.Iterations,
+		Duration:       r.Duration.String(),
+		Model:          model,
+		Truncated:      r.Truncated,
+		FilesChanged:   r.FilesChanged,
+		Activity:       r.Activity,
+		MissingNotes:   r.MissingNotes,
+		Meta:           r.Meta,
+		Tokens:         r.Tokens,
+		SlowIterations: r.SlowIterations,
+		TimedOut:       r.TimedOut,
+		Retries:        r.Retries,
+		Failures:       r.Failures,
+		Phase:          r.Phase,
+	}
+}
+
+// renderRunResult formats r as the summary block printed at the end of a run.
+func renderRunResult(r RunResult, useColor bool) string {
+	var b strings.Builder
+	b.WriteString("\n--- Summary ---\n")
+	fmt.Fprintf(&b, "Run ID: %s\n", r.RunID)
+	if len(r.Meta) > 0 {
+		fmt.Fprintf(&b, "Meta: %s\n", formatMeta(r.Meta))
+	}
+	fmt.Fprintf(&b, "Iterations: %d\n", r.Iterations)
+	fmt.Fprintf(&b, "Duration: %s\n", r.Duration)
+	if r.Truncated > 0 {
+		fmt.Fprintf(&b, "Truncated outputs: %d\n", r.Truncated)
+	}
+	if r.MissingNotes > 0 {
+		fmt.Fprintf(&b, "Iterations without notes: %d\n", r.MissingNotes)
+	}
+	if r.Failures > 0 {
+		fmt.Fprintf(&b, "Failed iterations: %d\n", r.Failures)
+	}
+	if r.Retries > 0 {
+		fmt.Fprintf(&b, "Retries: %d\n", r.Retries)
+	}
+	if r.TimedOut > 0 {
+		fmt.Fprintf(&b, "Timed out iterations: %d\n", r.TimedOut)
+	}
+	if r.SlowIterations > 0 {
+		fmt.Fprintf(&b, "Slow iterations: %d\n", r.SlowIterations)
+	}
+	if r.Tokens > 0 {
+		fmt.Fprintf(&b, "Tokens: %d\n", r.Tokens)
+	}
+	if r.Activity != "" {
+		fmt.Fprintf(&b, "Activity: %s\n", r.Activity)
+	}
+	if r.Tasks != nil && r.Tasks.Total() > 0 {
+		fmt.Fprintf(&b, "Tasks: %d/%d complete\n", r.Tasks.Done, r.Tasks.Total())
+	}
+	if r.InGit {
+		fmt.Fprintf(&b, "Files changed: %d\n", len(r.FilesChanged))
+		for _, file := range r.FilesChanged {
+			fmt.Fprintf(&b, "  %s\n", file)
+		}
+	}
+	label, codes := statusStyle(r.Status)
+	fmt.Fprintf(&b, "Status: %s\n", styleIf(useColor, label, codes...))
+	return b.String()
+}
+
+// Run loads the project config from the current directory and runs the loop
+// with opts, as the run command does. Cancelling ctx stops the opencode call
+// in progress and any delay or rate-limit wait; the result then has status
+// canceled and the error is ctx.Err(). Run installs no signal handlers.
+func Run(ctx context.Context, opts RunOptions) (RunResult, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return RunResult{}, err
+	}
+	result, err := runWithOptions(ctx, opts, cfg.MaxIterations, cfg.MaxPerHour, cfg.MaxPerDay)
+	if err == nil && result.Status == "canceled" {
+		err = ctx.Err()
+	}
+	return result, err
+}
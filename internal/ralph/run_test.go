@@ -0,0 +1,195 @@
+package ralph
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunIterationsReturnsResult(t *testing.T) {
+	notes := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) { return "<ralph_notes>n</ralph_notes>", nil },
+	}
+	tests := []struct {
+		name       string
+		runner     OpencodeRunner
+		prior      int
+		opts       RunOptions
+		status     string
+		iterations int
+		hourCount  int
+	}{
+		{name: "complete", runner: completeRunner(), prior: 1, opts: RunOptions{MaxIterations: 3}, status: "complete", iterations: 1, hourCount: 1},
+		{name: "max iterations", runner: notes, opts: RunOptions{MaxIterations: 2}, status: "max_iterations", iterations: 2, hourCount: 2},
+		{name: "rate limited", runner: notes, prior: 2, opts: RunOptions{MaxIterations: 5, MaxPerHour: 3}, status: "rate_limited", iterations: 2, hourCount: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			useFakeClock(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+			if err := os.MkdirAll(ralphDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			var timestamps []int64
+			for range tt.prior {
+				timestamps = append(timestamps, time.Now().Unix())
+			}
+			saveState(State{TotalIterations: tt.prior, Timestamps: timestamps})
+
+			opts := tt.opts
+			opts.Quiet = true
+			result, err := runIterationsWithRunner(cfg, opts, tt.runner)
+			if err != nil {
+				t.Fatalf("runIterationsWithRunner: %v", err)
+			}
+			if result.Status != tt.status || result.Iterations != tt.iterations {
+				t.Fatalf("got status %q after %d iterations, want %q after %d", result.Status, result.Iterations, tt.status, tt.iterations)
+			}
+			if result.HourCount != tt.hourCount || result.DayCount != tt.hourCount {
+				t.Fatalf("rate counts: got %d/hour, %d/day want %d", result.HourCount, result.DayCount, tt.hourCount)
+			}
+			if result.RunID == "" {
+				t.Fatalf("expected a run ID in %+v", result)
+			}
+		})
+	}
+}
+
+func TestRenderRunResult(t *testing.T) {
+	result := RunResult{
+		RunID:        "run-1",
+		Status:       "complete",
+		Iterations:   3,
+		Duration:     90 * time.Second,
+		Tokens:       1200,
+		Failures:     1,
+		InGit:        true,
+		FilesChanged: []string{"main.go"},
+	}
+	want := "\n--- Summary ---\nRun ID: run-1\nIterations: 3\nDuration: 1m30s\nFailed iterations: 1\nTokens: 1200\nFiles changed: 1\n  main.go\nStatus: COMPLETE\n"
+	if got := renderRunResult(result, false); got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestRunLoopStopsWhenCanceledDuringDelay(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	start := time.Now()
+	opts := RunOptions{MaxIterations: 3, Quiet: true, Delay: 3600}
+	result, err := runLoop(ctx, cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runLoop: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected cancellation to cut the delay short, took %s", elapsed)
+	}
+	if calls != 1 || result.Status != "canceled" || result.Iterations != 1 {
+		t.Fatalf("expected one iteration then canceled, got %d calls and %+v", calls, result)
+	}
+	if got := loadState().Runs; len(got) != 1 || got[0].Status != "canceled" {
+		t.Fatalf("expected canceled run recorded in state, got %+v", got)
+	}
+}
+
+// signalKiller reports each process group it is asked to kill.
+type signalKiller struct {
+	killed chan int
+}
+
+func (k signalKiller) Kill(pgid int, sig syscall.Signal) error {
+	k.killed <- pgid
+	return nil
+}
+
+func TestCancelStopsOpencodeAndReleasesLock(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	killer := signalKiller{killed: make(chan int, 1)}
+	orig := opencodeGroups
+	opencodeGroups = newProcessGroups(killer)
+	t.Cleanup(func() { opencodeGroups = orig })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			opencodeGroups.add(42)
+			defer opencodeGroups.remove(42)
+			if _, err := os.Stat(lockFile); err != nil {
+				t.Errorf("expected the lock to be held during the call: %v", err)
+			}
+			// Ctrl-C while opencode is running.
+			cancel()
+			select {
+			case pgid := <-killer.killed:
+				if pgid != 42 {
+					t.Errorf("killed group %d, want 42", pgid)
+				}
+				return "partial", errors.New("signal: terminated")
+			case <-time.After(5 * time.Second):
+				t.Error("opencode was not stopped after cancellation")
+				return "", nil
+			}
+		},
+	}
+
+	result, err := runLoop(ctx, cfg, RunOptions{MaxIterations: 3, Quiet: true}, runner)
+	if err != nil {
+		t.Fatalf("runLoop: %v", err)
+	}
+	if calls != 1 || result.Status != "canceled" {
+		t.Fatalf("expected the run to stop after the interrupted call, got %d calls and %+v", calls, result)
+	}
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock to be released, stat err: %v", err)
+	}
+	if got := loadState().Runs; len(got) != 1 || got[0].Status != "canceled" {
+		t.Fatalf("expected canceled run recorded in state, got %+v", got)
+	}
+}
+
+func TestRunReturnsContextError(t *testing.T) {
+	withTempCWD(t)
+	writeContextFiles(t, DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var result RunResult
+	var err error
+	captureOutput(t, &os.Stdout, func() {
+		result, err = Run(ctx, RunOptions{MaxIterations: 2, DryRun: true})
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if result.Status != "canceled" || result.Iterations != 0 {
+		t.Fatalf("result: got %+v", result)
+	}
+}
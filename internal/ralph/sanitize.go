@@ -0,0 +1,108 @@
+package ralph
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ansiEscapePattern matches CSI sequences such as colour codes, so they are
+// dropped whole rather than leaving "[31m" behind once ESC is stripped.
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]`)
+
+// sanitizeOutput makes model output safe for the terminal and notes.md: ANSI
+// escape sequences and control characters other than newline and tab are
+// removed (CRLF becomes LF), and invalid UTF-8 is replaced with U+FFFD.
+func sanitizeOutput(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	s = ansiEscapePattern.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// maxHeldEscape bounds how much of an unterminated escape sequence
+// sanitizingWriter waits for before giving up on it.
+const maxHeldEscape = 64
+
+// sanitizingWriter applies sanitizeOutput to a stream. A write that ends in
+// the middle of a UTF-8 character or escape sequence is held back until the
+// rest arrives, so chunk boundaries do not produce replacement characters.
+type sanitizingWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func newSanitizingWriter(w io.Writer) *sanitizingWriter {
+	return &sanitizingWriter{w: w}
+}
+
+func (s *sanitizingWriter) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	cut := completePrefix(s.pending)
+	if cut > 0 {
+		if _, err := io.WriteString(s.w, sanitizeOutput(string(s.pending[:cut]))); err != nil {
+			return 0, err
+		}
+		s.pending = append(s.pending[:0], s.pending[cut:]...)
+	}
+	return len(p), nil
+}
+
+// Flush writes anything still held back, then flushes w if it buffers too.
+func (s *sanitizingWriter) Flush() error {
+	if len(s.pending) > 0 {
+		_, err := io.WriteString(s.w, sanitizeOutput(string(s.pending)))
+		s.pending = s.pending[:0]
+		if err != nil {
+			return err
+		}
+	}
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// completePrefix returns how much of b can be sanitized now, excluding a
+// trailing partial UTF-8 character or unterminated escape sequence.
+func completePrefix(b []byte) int {
+	cut := len(b)
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if b[i] >= utf8.RuneSelf && !utf8.FullRune(b[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	if esc := bytes.LastIndexByte(b[:cut], 0x1b); esc >= 0 && cut-esc <= maxHeldEscape {
+		if !ansiEscapePattern.Match(b[esc:cut]) && !escapeAbandoned(b[esc:cut]) {
+			cut = esc
+		}
+	}
+	return cut
+}
+
+// escapeAbandoned reports whether seq (starting at ESC, with no final byte
+// yet) can no longer become a CSI sequence, e.g. ESC followed by plain text.
+func escapeAbandoned(seq []byte) bool {
+	if len(seq) < 2 {
+		return false
+	}
+	if seq[1] != '[' {
+		return true
+	}
+	for _, c := range seq[2:] {
+		if c < 0x20 || c > 0x3f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package ralph
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "hello\n\tworld", want: "hello\n\tworld"},
+		{name: "control chars", in: "a\x00b\x07c\x08d\x7fe", want: "abcde"},
+		{name: "crlf", in: "line1\r\nline2\r", want: "line1\nline2"},
+		{name: "ansi colours", in: "\x1b[31mred\x1b[0m and \x1b[1;32mgreen\x1b[m", want: "red and green"},
+		{name: "lone escape", in: "a\x1bb", want: "ab"},
+		{name: "c1 control", in: "a\u0085b", want: "ab"},
+		{name: "invalid utf8", in: "ok \xff\xfe bytes", want: "ok � bytes"},
+		{name: "truncated rune", in: "caf\xc3", want: "caf�"},
+		{name: "valid unicode kept", in: "naïve ✓ 日本", want: "naïve ✓ 日本"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeOutput(tt.in); got != tt.want {
+				t.Fatalf("sanitizeOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizingWriterHandlesSplitSequences(t *testing.T) {
+	input := "caf\xc3\xa9 \x1b[31mred\x1b[0m\x00 done\n"
+	for split := 0; split <= len(input); split++ {
+		var out bytes.Buffer
+		w := newSanitizingWriter(&out)
+		if _, err := w.Write([]byte(input[:split])); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, err := w.Write([]byte(input[split:])); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		if got, want := out.String(), "café red done\n"; got != want {
+			t.Fatalf("split at %d: got %q, want %q", split, got, want)
+		}
+	}
+}
+
+func TestNotesAreSanitizedButLogsKeepRawOutput(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	raw := "<ralph_notes>fixed \x1b[1mbold\x1b[0m bug\x07 in caf\xff</ralph_notes>"
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return raw, nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, OutputDir: "logs"}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	notes, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if !strings.Contains(string(notes), "fixed bold bug in caf�") {
+		t.Fatalf("expected sanitized notes, got %q", notes)
+	}
+	logged, err := os.ReadFile(iterationArtifactPath("logs", result.RunID, 1, "log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if string(logged) != raw {
+		t.Fatalf("expected raw output in saved log, got %q", logged)
+	}
+}
@@ -0,0 +1,109 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const selftestIterations = 2
+
+// echoRunner is an in-process OpencodeRunner that never calls a model. It
+// reports the iteration line of each prompt with a note, and signals
+// COMPLETE once it has been called completeAfter times.
+type echoRunner struct {
+	completeAfter int
+	calls         int
+}
+
+var iterationLineRe = regexp.MustCompile(`Iteration: \d+ of \d+`)
+
+func (r *echoRunner) Run(args OpencodeRunArgs) (string, error) {
+	r.calls++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "echo: received %d byte prompt (%s)\n", len(args.Prompt), iterationLineRe.FindString(args.Prompt))
+	fmt.Fprintf(&b, "<ralph_notes>selftest call %d</ralph_notes>\n", r.calls)
+	if r.calls >= r.completeAfter {
+		b.WriteString("<ralph_status>COMPLETE</ralph_status>\n")
+	}
+	return b.String(), nil
+}
+
+// selectRunner returns the runner registered under name ("" and "opencode"
+// select the real binary).
+func selectRunner(name string) (OpencodeRunner, error) {
+	switch name {
+	case "", "opencode":
+		return execOpencodeRunner{}, nil
+	case "echo":
+		return &echoRunner{completeAfter: selftestIterations}, nil
+	default:
+		return nil, fmt.Errorf("unknown runner: %s", name)
+	}
+}
+
+// SelfTest runs the loop end to end in a scratch directory using the echo
+// runner, then checks that state, notes, and the lock were handled.
+func SelfTest() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "opencode-ralph-selftest-")
+	if err != nil {
+		return fmt.Errorf("creating selftest directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("entering selftest directory: %w", err)
+	}
+	defer os.Chdir(cwd)
+
+	return runSelfTest()
+}
+
+func runSelfTest() error {
+	cfg := DefaultConfig()
+	for dest, src := range map[string]string{
+		cfg.PromptFile:      "templates/PROMPT.md",
+		cfg.ConventionsFile: "templates/CONVENTIONS.md",
+		cfg.SpecsFile:       "templates/SPECS.md",
+	} {
+		content, err := templates.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("reading template %s: %w", src, err)
+		}
+		if err := os.WriteFile(dest, content, 0644); err != nil {
+			return fmt.Errorf("creating %s: %w", dest, err)
+		}
+	}
+
+	runner, err := selectRunner("echo")
+	if err != nil {
+		return err
+	}
+	opts := RunOptions{MaxIterations: selftestIterations + 1, Verbose: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		return fmt.Errorf("selftest run: %w", err)
+	}
+
+	if state := loadState(); state.TotalIterations == 0 || len(state.Timestamps) == 0 {
+		return fmt.Errorf("selftest: expected iterations to be recorded in %s", stateFile)
+	}
+	notes, err := readFile(notesFile)
+	if err != nil {
+		return fmt.Errorf("selftest: reading notes: %w", err)
+	}
+	if strings.Count(notes, "selftest call") != selftestIterations {
+		return fmt.Errorf("selftest: expected %d notes entries in %s", selftestIterations, notesFile)
+	}
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		return fmt.Errorf("selftest: lock file %s was not released", lockFile)
+	}
+
+	fmt.Println("\nSelftest passed.")
+	return nil
+}
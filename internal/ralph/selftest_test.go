@@ -0,0 +1,74 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSelfTestCompletesInTempDir(t *testing.T) {
+	withTempCWD(t)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+
+	out := captureOutput(t, &os.Stdout, func() {
+		if err := SelfTest(); err != nil {
+			t.Fatalf("SelfTest: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "Selftest passed.") {
+		t.Fatalf("expected pass message, got %q", out)
+	}
+	if !strings.Contains(out, "Status: COMPLETE") {
+		t.Fatalf("expected COMPLETE status, got %q", out)
+	}
+
+	after, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd after selftest: %v", err)
+	}
+	if after != cwd {
+		t.Fatalf("working directory: got %q want %q", after, cwd)
+	}
+	if _, err := os.Stat(ralphDir); !os.IsNotExist(err) {
+		t.Fatalf("expected selftest to leave the caller's directory untouched, stat err: %v", err)
+	}
+}
+
+func TestEchoRunnerCompletesAfterN(t *testing.T) {
+	runner := &echoRunner{completeAfter: 2}
+
+	first, _ := runner.Run(OpencodeRunArgs{Prompt: "Iteration: 1 of 3"})
+	if isComplete(first, defaultCompletionSignal, defaultStatusTag) {
+		t.Fatalf("did not expect COMPLETE on first call: %q", first)
+	}
+	if extractNotes(first, defaultNotesTag) == "" {
+		t.Fatalf("expected notes on first call: %q", first)
+	}
+	if !strings.Contains(first, "Iteration: 1 of 3") {
+		t.Fatalf("expected iteration line to be echoed: %q", first)
+	}
+
+	second, _ := runner.Run(OpencodeRunArgs{Prompt: "Iteration: 2 of 3"})
+	if !isComplete(second, defaultCompletionSignal, defaultStatusTag) {
+		t.Fatalf("expected COMPLETE on second call: %q", second)
+	}
+}
+
+func TestSelectRunner(t *testing.T) {
+	if _, err := selectRunner("echo"); err != nil {
+		t.Fatalf("selectRunner echo: %v", err)
+	}
+	if r, err := selectRunner(""); err != nil {
+		t.Fatalf("selectRunner default: %v", err)
+	} else if _, ok := r.(execOpencodeRunner); !ok {
+		t.Fatalf("expected default runner to exec opencode, got %T", r)
+	}
+	if _, err := selectRunner("bogus"); err == nil {
+		t.Fatalf("expected error for unknown runner")
+	}
+}
@@ -0,0 +1,48 @@
+package ralph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readSessionFile returns the session ID stored at path, or "" when the file
+// does not exist yet.
+func readSessionFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading session file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeSessionFile stores id at path for the next run's first iteration.
+func writeSessionFile(path, id string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", dir, err)
+		}
+	}
+	return writeFileAtomic(path, []byte(id+"\n"), 0644)
+}
+
+// validateSessionFile checks --session-file against the session flags and
+// forces --format json, the only output that carries the session ID.
+func validateSessionFile(opts *RunOptions) error {
+	if opts.SessionFile == "" {
+		return nil
+	}
+	if opts.ContinueSession || opts.Session != "" || opts.Resume || opts.ResumeLast {
+		return fmt.Errorf("invalid flags: --session-file cannot be combined with --continue, --session, or --resume")
+	}
+	if opts.Format != "json" && !opts.FormatInherited {
+		return fmt.Errorf("--session-file requires --format json, which reports the session ID")
+	}
+	opts.Format = "json"
+	return nil
+}
@@ -0,0 +1,166 @@
+package ralph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// opencodeCLI runs auxiliary opencode subcommands (anything but `run`) and
+// returns their stdout; tests substitute canned output.
+type opencodeCLI interface {
+	Run(args ...string) (string, error)
+}
+
+// execOpencodeCLI runs binary, or the opencode_bin config key when it is
+// empty.
+type execOpencodeCLI struct {
+	binary string
+}
+
+func (c execOpencodeCLI) Run(args ...string) (string, error) {
+	binary := c.binary
+	if binary == "" {
+		binary = configuredOpencodeBinary()
+	}
+	out, err := exec.Command(binary, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("opencode %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+var opencodeTool opencodeCLI = execOpencodeCLI{}
+
+// configuredOpencodeBinary returns the opencode binary from the config file
+// and RALPH_OPENCODE_BIN, for commands that take no --opencode-bin flag.
+func configuredOpencodeBinary() string {
+	cfg, _ := LoadConfig()
+	return opencodeBinary(cfg.OpencodeBin)
+}
+
+// ErrSessionsUnsupported is returned when the installed opencode cannot list
+// sessions.
+var ErrSessionsUnsupported = errors.New("this opencode version cannot list sessions (needs `opencode session list`)")
+
+// SessionInfo is one entry of opencode's session history.
+type SessionInfo struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title,omitempty"`
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// opencodeSession is the JSON shape of a session, which reports timestamps
+// as Unix milliseconds either at the top level or under "time".
+type opencodeSession struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Updated int64  `json:"updated"`
+	Time    struct {
+		Created int64 `json:"created"`
+		Updated int64 `json:"updated"`
+	} `json:"time"`
+}
+
+// ListSessions asks opencode for its session history, newest first.
+func ListSessions() ([]SessionInfo, error) {
+	return listSessions(opencodeTool)
+}
+
+func listSessions(cli opencodeCLI) ([]SessionInfo, error) {
+	out, err := cli.Run("session", "list", "--format", "json")
+	if err != nil {
+		// Older releases have the subcommand but not --format.
+		out, err = cli.Run("session", "list")
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, ErrOpencodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSessionsUnsupported, err)
+	}
+	return parseSessionList(out)
+}
+
+// parseSessionList accepts either the JSON array from `session list --format
+// json` or its plain-text table, where each session line starts with its ID.
+func parseSessionList(out string) ([]SessionInfo, error) {
+	trimmed := strings.TrimSpace(out)
+	if strings.HasPrefix(trimmed, "[") {
+		var raw []opencodeSession
+		if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+			return nil, fmt.Errorf("parsing session list: %w", err)
+		}
+		sessions := make([]SessionInfo, 0, len(raw))
+		for _, s := range raw {
+			updated := s.Updated
+			if updated == 0 {
+				updated = s.Time.Updated
+			}
+			if updated == 0 {
+				updated = s.Time.Created
+			}
+			info := SessionInfo{ID: s.ID, Title: s.Title}
+			if updated > 0 {
+				info.Updated = time.UnixMilli(updated)
+			}
+			sessions = append(sessions, info)
+		}
+		sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+		return sessions, nil
+	}
+
+	var sessions []SessionInfo
+	for _, line := range strings.Split(trimmed, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "ses_") {
+			continue
+		}
+		title := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		sessions = append(sessions, SessionInfo{ID: fields[0], Title: title})
+	}
+	return sessions, nil
+}
+
+// renderSessions formats sessions as an aligned ID / updated / title table.
+func renderSessions(sessions []SessionInfo) string {
+	if len(sessions) == 0 {
+		return "No opencode sessions found"
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUPDATED\tTITLE")
+	for _, s := range sessions {
+		updated := "-"
+		if !s.Updated.IsZero() {
+			updated = s.Updated.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.ID, updated, s.Title)
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Sessions renders opencode's session history as a table, or JSON.
+func Sessions(asJSON bool) (string, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		return "", err
+	}
+	if asJSON {
+		if sessions == nil {
+			sessions = []SessionInfo{}
+		}
+		data, err := json.MarshalIndent(sessions, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("marshalling sessions: %w", err)
+		}
+		return string(data), nil
+	}
+	return renderSessions(sessions), nil
+}
@@ -0,0 +1,114 @@
+package ralph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeOpencodeCLI answers opencode subcommands from a map keyed by the joined arguments.
+type fakeOpencodeCLI struct {
+	responses map[string]string
+}
+
+func (c fakeOpencodeCLI) Run(args ...string) (string, error) {
+	out, ok := c.responses[strings.Join(args, " ")]
+	if !ok {
+		return "", errors.New("exit status 1")
+	}
+	return out, nil
+}
+
+func TestParseSessionList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []SessionInfo
+	}{
+		{
+			name: "json",
+			output: `[
+  {"id": "ses_old", "title": "Fix parser", "time": {"created": 1760000000000, "updated": 1760000100000}},
+  {"id": "ses_new", "title": "Add flags", "updated": 1760500000000}
+]`,
+			want: []SessionInfo{
+				{ID: "ses_new", Title: "Add flags", Updated: time.UnixMilli(1760500000000)},
+				{ID: "ses_old", Title: "Fix parser", Updated: time.UnixMilli(1760000100000)},
+			},
+		},
+		{
+			name:   "text table",
+			output: "Session ID                      Title              Updated\n──────────\nses_abc123  Fix the parser  2 hours ago\nses_def456  Add flags  yesterday\n",
+			want: []SessionInfo{
+				{ID: "ses_abc123", Title: "Fix the parser  2 hours ago"},
+				{ID: "ses_def456", Title: "Add flags  yesterday"},
+			},
+		},
+		{name: "empty json", output: "[]", want: []SessionInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSessionList(tt.output)
+			if err != nil {
+				t.Fatalf("parseSessionList: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i].ID != tt.want[i].ID || got[i].Title != tt.want[i].Title || !got[i].Updated.Equal(tt.want[i].Updated) {
+					t.Fatalf("session %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListSessionsFallsBack(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		wantIDs   []string
+		wantErr   error
+	}{
+		{
+			name:      "json format",
+			responses: map[string]string{"session list --format json": `[{"id":"ses_1","title":"t"}]`},
+			wantIDs:   []string{"ses_1"},
+		},
+		{
+			name:      "text only",
+			responses: map[string]string{"session list": "ses_2  title\n"},
+			wantIDs:   []string{"ses_2"},
+		},
+		{name: "unsupported", responses: map[string]string{}, wantErr: ErrSessionsUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions, err := listSessions(fakeOpencodeCLI{responses: tt.responses})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(sessions) != len(tt.wantIDs) {
+				t.Fatalf("got %v, want IDs %v", sessions, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if sessions[i].ID != id {
+					t.Fatalf("got %v, want IDs %v", sessions, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSessions(t *testing.T) {
+	if got := renderSessions(nil); got != "No opencode sessions found" {
+		t.Fatalf("unexpected empty rendering %q", got)
+	}
+	out := renderSessions([]SessionInfo{{ID: "ses_1", Title: "First"}, {ID: "ses_22", Title: "Second"}})
+	want := "ID      UPDATED  TITLE\nses_1   -        First\nses_22  -        Second"
+	if out != want {
+		t.Fatalf("renderSessions:\n%s\nwant:\n%s", out, want)
+	}
+}
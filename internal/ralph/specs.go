@@ -0,0 +1,56 @@
+package ralph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TaskCounts tallies checkbox tasks found in a specs document.
+type TaskCounts struct {
+	Open       int `json:"open"`
+	InProgress int `json:"in_progress"`
+	Done       int `json:"done"`
+}
+
+// Total returns the number of tasks in any state.
+func (c TaskCounts) Total() int {
+	return c.Open + c.InProgress + c.Done
+}
+
+func (c TaskCounts) String() string {
+	return fmt.Sprintf("%d/%d done, %d in progress, %d open", c.Done, c.Total(), c.InProgress, c.Open)
+}
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	taskLineRe    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[([ xX~])\]`)
+)
+
+// parseTasks counts `- [ ]` (open), `- [~]` (in progress), and `- [x]` (done)
+// items. HTML comments and lines that are not checkbox items are ignored.
+func parseTasks(specs string) TaskCounts {
+	var counts TaskCounts
+	for _, line := range strings.Split(htmlCommentRe.ReplaceAllString(specs, ""), "\n") {
+		m := taskLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case " ":
+			counts.Open++
+		case "~":
+			counts.InProgress++
+		default:
+			counts.Done++
+		}
+	}
+	return counts
+}
+
+// countTasks returns how many checkbox tasks in specs are checked and how
+// many there are. In-progress `- [~]` items count towards the total only.
+func countTasks(specs string) (done, total int) {
+	counts := parseTasks(specs)
+	return counts.Done, counts.Total()
+}
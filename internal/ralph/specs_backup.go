@@ -0,0 +1,59 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const specsBackupDir = ".ralph/specs-backup"
+
+// backupSpecs copies specs into dir as iteration-<n>.md and prunes all but
+// the newest keep backups (keep <= 0 keeps everything).
+func backupSpecs(dir string, iteration int, specs string, keep int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("iteration-%d.md", iteration))
+	if err := os.WriteFile(path, []byte(specs), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if keep <= 0 {
+		return nil
+	}
+	return pruneSpecsBackups(dir, keep)
+}
+
+// pruneSpecsBackups removes the oldest iteration-<n>.md backups beyond keep.
+func pruneSpecsBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	var iterations []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "iteration-") || !strings.HasSuffix(name, ".md") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "iteration-"), ".md"))
+		if err != nil {
+			continue
+		}
+		iterations = append(iterations, n)
+	}
+	if len(iterations) <= keep {
+		return nil
+	}
+	sort.Ints(iterations)
+	for _, n := range iterations[:len(iterations)-keep] {
+		path := filepath.Join(dir, fmt.Sprintf("iteration-%d.md", n))
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestBackupSpecsPerIteration(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	original, err := os.ReadFile(cfg.SpecsFile)
+	if err != nil {
+		t.Fatalf("read specs: %v", err)
+	}
+
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			calls++
+			// Simulate the agent rewriting the specs during the call.
+			if err := os.WriteFile(cfg.SpecsFile, []byte(fmt.Sprintf("edited by iteration %d", calls)), 0644); err != nil {
+				t.Fatalf("write specs: %v", err)
+			}
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, BackupSpecs: true, BackupSpecsKeep: 10}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"iteration-1.md", string(original)},
+		{"iteration-2.md", "edited by iteration 1"},
+		{"iteration-3.md", "edited by iteration 2"},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(specsBackupDir, tt.file))
+		if err != nil {
+			t.Fatalf("read %s: %v", tt.file, err)
+		}
+		if string(data) != tt.want {
+			t.Fatalf("%s = %q, want %q", tt.file, data, tt.want)
+		}
+	}
+}
+
+func TestBackupSpecsPrunesToKeep(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 1; i <= 5; i++ {
+		if err := backupSpecs(dir, i, "specs", 2); err != nil {
+			t.Fatalf("backupSpecs(%d): %v", i, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0644); err != nil {
+		t.Fatalf("write unrelated file: %v", err)
+	}
+	if err := backupSpecs(dir, 10, "specs", 2); err != nil {
+		t.Fatalf("backupSpecs(10): %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	want := []string{"iteration-10.md", "iteration-5.md", "notes.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("got %v, want %v", names, want)
+		}
+	}
+}
+
+func TestBackupSpecsKeepZeroKeepsAll(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 1; i <= 4; i++ {
+		if err := backupSpecs(dir, i, "specs", 0); err != nil {
+			t.Fatalf("backupSpecs(%d): %v", i, err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 backups, got %d", len(entries))
+	}
+}
@@ -0,0 +1,42 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readSpecs returns the specs for the prompt. A regular file is read as is;
+// a directory contributes every top-level .md file in name order, each
+// wrapped in a <spec_file> block naming its source.
+func readSpecs(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return readFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	var blocks []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			continue
+		}
+		file := filepath.Join(path, entry.Name())
+		content, err := readFile(file)
+		if err != nil {
+			return "", err
+		}
+		blocks = append(blocks, fmt.Sprintf("<spec_file path=%q>\n%s\n</spec_file>", file, strings.TrimRight(content, "\n")))
+	}
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("no .md files in specs directory %s", path)
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
@@ -0,0 +1,59 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSpecsDirectory(t *testing.T) {
+	withTempCWD(t)
+	if err := os.MkdirAll(filepath.Join("specs", "drafts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"specs/b-api.md":         "- [ ] API\n",
+		"specs/a-cli.md":         "- [x] CLI\n",
+		"specs/notes.txt":        "ignored",
+		"specs/drafts/future.md": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := readSpecs("specs")
+	if err != nil {
+		t.Fatalf("readSpecs: %v", err)
+	}
+	want := "<spec_file path=\"specs/a-cli.md\">\n- [x] CLI\n</spec_file>\n\n<spec_file path=\"specs/b-api.md\">\n- [ ] API\n</spec_file>"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if tasks := parseTasks(got); tasks.Total() != 2 || tasks.Done != 1 {
+		t.Fatalf("expected tasks from both files, got %+v", tasks)
+	}
+}
+
+func TestReadSpecsFileUnchanged(t *testing.T) {
+	withTempCWD(t)
+	if err := os.WriteFile("SPECS.md", []byte("- [ ] one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readSpecs("SPECS.md")
+	if err != nil || got != "- [ ] one\n" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestReadSpecsEmptyDirectory(t *testing.T) {
+	withTempCWD(t)
+	if err := os.Mkdir("specs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSpecs("specs"); err == nil || !strings.Contains(err.Error(), "no .md files") {
+		t.Fatalf("expected error for empty specs directory, got %v", err)
+	}
+}
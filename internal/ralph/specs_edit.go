@@ -0,0 +1,29 @@
+package ralph
+
+import (
+	"fmt"
+	"os"
+)
+
+// interactiveTerminal reports whether stdin and stdout are both a terminal,
+// i.e. whether an editor can be opened; tests replace it.
+var interactiveTerminal = func() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// editSpecsBeforeRun opens the specs file in editor so the task can be
+// adjusted right before running. Without a terminal it fails, or with
+// skipIfNotTTY warns and leaves the specs untouched.
+func editSpecsBeforeRun(editor configEditor, path string, skipIfNotTTY bool) error {
+	if !interactiveTerminal() {
+		if skipIfNotTTY {
+			fmt.Fprintf(os.Stderr, "Warning: not running in a terminal; skipping --edit of %s\n", path)
+			return nil
+		}
+		return fmt.Errorf("--edit needs an interactive terminal to open %s (use --edit-if-tty to run without editing)", path)
+	}
+	if err := editor.Edit(path); err != nil {
+		return fmt.Errorf("editing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package ralph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func useFakeTerminal(t *testing.T, interactive bool) {
+	t.Helper()
+	orig := interactiveTerminal
+	interactiveTerminal = func() bool { return interactive }
+	t.Cleanup(func() { interactiveTerminal = orig })
+}
+
+func useEditor(t *testing.T, editor configEditor) {
+	t.Helper()
+	orig := editorRunner
+	editorRunner = editor
+	t.Cleanup(func() { editorRunner = orig })
+}
+
+func TestEditSpecsUsesEditedContentInPrompt(t *testing.T) {
+	withTempCWD(t)
+	useFakeTerminal(t, true)
+	editor := &scriptedEditor{edits: []string{"- [ ] the freshly edited task\n"}}
+	useEditor(t, editor)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	var prompts []string
+	runner := &fakeRunner{
+		runFunc: func(args OpencodeRunArgs) (string, error) {
+			prompts = append(prompts, args.Prompt)
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 1, Quiet: true, EditSpecs: true}
+	if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+
+	if editor.calls != 1 {
+		t.Fatalf("expected editor to be opened once, got %d", editor.calls)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "the freshly edited task") {
+		t.Fatalf("expected edited specs in prompt, got %q", prompts)
+	}
+}
+
+func TestEditSpecsWithoutTerminal(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifTTY     bool
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "fails by default", wantErr: true},
+		{name: "skips with edit-if-tty", ifTTY: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+			useFakeTerminal(t, false)
+			editor := &scriptedEditor{edits: []string{"unused"}}
+			useEditor(t, editor)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			var calls int
+			runner := &fakeRunner{
+				runFunc: func(OpencodeRunArgs) (string, error) {
+					calls++
+					return "", nil
+				},
+			}
+
+			opts := RunOptions{MaxIterations: 1, Quiet: true, EditSpecs: true, EditIfTTY: tt.ifTTY}
+			var err error
+			captureOutput(t, &os.Stderr, func() {
+				_, err = runIterationsWithRunner(cfg, opts, runner)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if editor.calls != 0 {
+				t.Fatalf("editor must not open without a terminal")
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("runner calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
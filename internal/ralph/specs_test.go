@@ -0,0 +1,128 @@
+package ralph
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseTasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs string
+		want  TaskCounts
+	}{
+		{name: "empty", specs: "", want: TaskCounts{}},
+		{
+			name:  "states",
+			specs: "- [ ] open\n- [~] working\n- [x] done\n- [X] also done\n",
+			want:  TaskCounts{Open: 1, InProgress: 1, Done: 2},
+		},
+		{
+			name:  "prose and headings ignored",
+			specs: "# Title\nSome prose about - [ ] inline text.\n- plain bullet\n- [ ] real task\n",
+			want:  TaskCounts{Open: 1},
+		},
+		{
+			name:  "single line comment",
+			specs: "<!-- - [ ] hidden -->\n- [x] visible\n",
+			want:  TaskCounts{Done: 1},
+		},
+		{
+			name:  "multi line comment",
+			specs: "<!--\n- [ ] hidden one\n- [~] hidden two\n-->\n- [ ] visible\n",
+			want:  TaskCounts{Open: 1},
+		},
+		{
+			name:  "nested and numbered",
+			specs: "- [ ] parent\n  - [x] child\n1. [~] numbered\n* [ ] star\n",
+			want:  TaskCounts{Open: 2, InProgress: 1, Done: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTasks(tt.specs); got != tt.want {
+				t.Fatalf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskCountsString(t *testing.T) {
+	got := TaskCounts{Open: 2, InProgress: 1, Done: 3}.String()
+	if want := "3/6 done, 1 in progress, 2 open"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestCountTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		specs     string
+		wantDone  int
+		wantTotal int
+	}{
+		{name: "no tasks", specs: "# Specs\nJust prose.\n"},
+		{name: "all open", specs: "- [ ] one\n- [ ] two\n", wantTotal: 2},
+		{name: "all done", specs: "- [x] one\n- [X] two\n", wantDone: 2, wantTotal: 2},
+		{name: "in progress is not done", specs: "- [x] one\n- [~] two\n", wantDone: 1, wantTotal: 2},
+		{
+			name:      "nested lists",
+			specs:     "- [x] parent\n  - [x] child\n    - [ ] grandchild\n\t- [x] tab indented\n",
+			wantDone:  3,
+			wantTotal: 4,
+		},
+		{
+			name:      "non-task bullets",
+			specs:     "- plain bullet\n- [link](x) not a box\n-[ ] no space\n- [ ]\n* [x] star task\n+ [ ] plus task\n",
+			wantDone:  1,
+			wantTotal: 3,
+		},
+		{
+			name:      "numbered and commented",
+			specs:     "1. [x] first\n2) [ ] second\n<!-- - [ ] ignored -->\n",
+			wantDone:  1,
+			wantTotal: 2,
+		},
+		{name: "inline checkbox text", specs: "Use - [ ] to add a task.\n`- [x]` in code\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, total := countTasks(tt.specs)
+			if done != tt.wantDone || total != tt.wantTotal {
+				t.Fatalf("countTasks = (%d, %d), want (%d, %d)", done, total, tt.wantDone, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestStopWhenSpecsComplete(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.WriteFile(cfg.SpecsFile, []byte("- [ ] one\n- [ ] two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each iteration checks off one more task and never signals COMPLETE.
+	specs := []string{"- [x] one\n- [ ] two\n", "- [x] one\n- [x] two\n"}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			if err := os.WriteFile(cfg.SpecsFile, []byte(specs[calls]), 0644); err != nil {
+				t.Fatal(err)
+			}
+			calls++
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 5, Quiet: true, StopWhenSpecsComplete: true}
+	result, err := runIterationsWithRunner(cfg, opts, runner)
+	if err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if calls != 2 || result.Status != "complete" {
+		t.Fatalf("expected completion after 2 iterations, got %d calls and status %q", calls, result.Status)
+	}
+}
@@ -0,0 +1,90 @@
+package ralph
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxSpecsDiffLines caps the --track-specs diff printed per iteration.
+	maxSpecsDiffLines = 40
+	// maxSpecsDiffCells bounds the line-diff table; larger specs only get
+	// the task counts.
+	maxSpecsDiffCells = 4_000_000
+)
+
+// specsDiff returns the lines removed ("-") and added ("+") between before
+// and after, in order, using a longest-common-subsequence line diff. ok is
+// false when the specs are too large to diff.
+func specsDiff(before, after string) (lines []string, ok bool) {
+	a := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(after, "\n"), "\n")
+	if (len(a)+1)*(len(b)+1) > maxSpecsDiffCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the common subsequence length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	return lines, true
+}
+
+// renderSpecsChange describes what an iteration did to the specs for
+// --track-specs: the changed lines, then the checklist progress.
+func renderSpecsChange(before, after string, useColor bool) string {
+	var b strings.Builder
+	tasks := parseTasks(after)
+	progress := fmt.Sprintf("%d/%d tasks complete", tasks.Done, tasks.Total())
+	if delta := tasks.Done - parseTasks(before).Done; delta != 0 {
+		progress += fmt.Sprintf(" (%+d)", delta)
+	}
+	if before == after {
+		fmt.Fprintf(&b, "Specs: unchanged, %s\n", progress)
+		return b.String()
+	}
+
+	lines, ok := specsDiff(before, after)
+	if !ok {
+		fmt.Fprintf(&b, "Specs: changed (too large to diff), %s\n", progress)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Specs: %s\n", progress)
+	for n, line := range lines {
+		if n == maxSpecsDiffLines {
+			fmt.Fprintf(&b, "  ... %d more changed lines\n", len(lines)-n)
+			break
+		}
+		code := ansiGreen
+		if strings.HasPrefix(line, "-") {
+			code = ansiRed
+		}
+		fmt.Fprintf(&b, "  %s\n", styleIf(useColor, line, code))
+	}
+	return b.String()
+}
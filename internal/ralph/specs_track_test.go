@@ -0,0 +1,79 @@
+package ralph
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSpecsDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{name: "unchanged", before: "a\nb\n", after: "a\nb\n", want: nil},
+		{name: "checked off", before: "- [ ] one\n- [ ] two\n", after: "- [x] one\n- [ ] two\n", want: []string{"-- [ ] one", "+- [x] one"}},
+		{name: "appended", before: "a\n", after: "a\nb\n", want: []string{"+b"}},
+		{name: "removed", before: "a\nb\nc\n", after: "a\nc\n", want: []string{"-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := specsDiff(tt.before, tt.after)
+			if !ok || !slices.Equal(got, tt.want) {
+				t.Fatalf("specsDiff = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSpecsChange(t *testing.T) {
+	before := "- [ ] one\n- [ ] two\n- [x] three\n"
+	after := "- [x] one\n- [x] two\n- [x] three\n"
+	got := renderSpecsChange(before, after, false)
+	want := "Specs: 3/3 tasks complete (+2)\n  -- [ ] one\n  -- [ ] two\n  +- [x] one\n  +- [x] two\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := renderSpecsChange(before, before, false); got != "Specs: unchanged, 1/3 tasks complete\n" {
+		t.Fatalf("unchanged: got %q", got)
+	}
+}
+
+func TestTrackSpecsReportsProgress(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+	if err := os.WriteFile(cfg.SpecsFile, []byte("- [ ] one\n- [ ] two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			if err := os.WriteFile(cfg.SpecsFile, []byte("- [x] one\n- [ ] two\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 1, MaxPerHour: 10, TrackSpecs: true}
+	var result RunResult
+	out := captureOutput(t, &os.Stdout, func() {
+		var err error
+		if result, err = runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	for _, want := range []string{"Specs: 1/2 tasks complete (+1)\n", "  +- [x] one\n", "Tasks: 1/2 complete\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if result.Tasks == nil || result.Tasks.Done != 1 || result.Tasks.Total() != 2 {
+		t.Fatalf("result tasks: got %+v", result.Tasks)
+	}
+}
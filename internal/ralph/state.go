@@ -2,15 +2,21 @@ package ralph
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
 // State tracks iteration history for rate limiting.
 type State struct {
-	TotalIterations int       `json:"total_iterations"`
-	Timestamps      []int64   `json:"timestamps"`
-	LastRun         time.Time `json:"last_run"`
+	TotalIterations int          `json:"total_iterations"`
+	Timestamps      []int64      `json:"timestamps"`
+	LastRun         time.Time    `json:"last_run"`
+	SessionID       string       `json:"session_id,omitempty"`
+	TotalTokens     int          `json:"total_tokens,omitempty"`
+	Runs            []RunOutcome `json:"runs,omitempty"`
 }
 
 func loadState() State {
@@ -20,6 +26,12 @@ func loadState() State {
 	}
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
+		backup, backupErr := backupCorruptState()
+		if backupErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s is corrupt (%v) and could not be backed up: %v\n", stateFile, err, backupErr)
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: %s is corrupt (%v); moved to %s, starting with empty state\n", stateFile, err, backup)
+		}
 		return State{Timestamps: []int64{}}
 	}
 	if state.Timestamps == nil {
@@ -28,16 +40,27 @@ func loadState() State {
 	return state
 }
 
+// backupCorruptState moves an unreadable state file aside so it can be inspected later.
+func backupCorruptState() (string, error) {
+	backup := fmt.Sprintf("%s.corrupt.%s", stateFile, time.Now().Format("20060102T150405"))
+	if err := os.Rename(stateFile, backup); err != nil {
+		return "", fmt.Errorf("renaming %s: %w", stateFile, err)
+	}
+	return backup, nil
+}
+
+// saveState replaces the state file atomically, so a run killed mid-write
+// leaves the previous state rather than a truncated file.
 func saveState(state State) {
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return
 	}
-	_ = os.WriteFile(stateFile, data, 0644)
+	_ = writeFileAtomic(stateFile, data, 0644)
 }
 
 func pruneOldTimestamps(state *State) {
-	cutoff := time.Now().Add(-24 * time.Hour).Unix()
+	cutoff := now().Add(-24 * time.Hour).Unix()
 	var kept []int64
 	for _, ts := range state.Timestamps {
 		if ts > cutoff {
@@ -47,8 +70,8 @@ func pruneOldTimestamps(state *State) {
 	state.Timestamps = kept
 }
 
-func countRecentIterations(timestamps []int64) (hourCount, dayCount int) {
-	now := time.Now()
+// countRecentIterations counts iterations in the hour and the day before now.
+func countRecentIterations(timestamps []int64, now time.Time) (hourCount, dayCount int) {
 	hourAgo := now.Add(-time.Hour).Unix()
 	dayAgo := now.Add(-24 * time.Hour).Unix()
 
@@ -62,3 +85,115 @@ func countRecentIterations(timestamps []int64) (hourCount, dayCount int) {
 	}
 	return
 }
+
+// countRecentMinute counts iterations in the minute before now.
+func countRecentMinute(timestamps []int64, now time.Time) int {
+	minuteAgo := now.Add(-time.Minute).Unix()
+	count := 0
+	for _, ts := range timestamps {
+		if ts > minuteAgo {
+			count++
+		}
+	}
+	return count
+}
+
+// rateWindow is a sliding window with an iteration cap.
+type rateWindow struct {
+	Name string
+	Span time.Duration
+	Max  int
+}
+
+// activeRateWindows returns the windows that have a positive limit.
+func activeRateWindows(maxPerMinute, maxPerHour, maxPerDay int) []rateWindow {
+	var windows []rateWindow
+	if maxPerMinute > 0 {
+		windows = append(windows, rateWindow{Name: "minute", Span: time.Minute, Max: maxPerMinute})
+	}
+	if maxPerHour > 0 {
+		windows = append(windows, rateWindow{Name: "hour", Span: time.Hour, Max: maxPerHour})
+	}
+	if maxPerDay > 0 {
+		windows = append(windows, rateWindow{Name: "day", Span: 24 * time.Hour, Max: maxPerDay})
+	}
+	return windows
+}
+
+// fullRateWindow returns the first window with no capacity left at now,
+// how many iterations it holds, and how long until enough of them age out
+// for one more iteration to fit.
+func fullRateWindow(timestamps []int64, windows []rateWindow, now time.Time) (window rateWindow, count int, wait time.Duration, full bool) {
+	for _, w := range windows {
+		cutoff := now.Add(-w.Span).Unix()
+		var recent []int64
+		for _, ts := range timestamps {
+			if ts > cutoff {
+				recent = append(recent, ts)
+			}
+		}
+		if len(recent) < w.Max {
+			continue
+		}
+		slices.Sort(recent)
+		// Everything up to and including this timestamp has to expire.
+		expires := time.Unix(recent[len(recent)-w.Max], 0).Add(w.Span)
+		return w, len(recent), max(expires.Sub(now), 0), true
+	}
+	return rateWindow{}, 0, 0, false
+}
+
+// windowHeadroom is how many more iterations a window allows right now.
+type windowHeadroom struct {
+	Window    string `json:"window"`
+	Remaining int    `json:"remaining"`
+}
+
+func computeHeadroom(timestamps []int64, windows []rateWindow, now time.Time) []windowHeadroom {
+	headroom := make([]windowHeadroom, 0, len(windows))
+	for _, w := range windows {
+		cutoff := now.Add(-w.Span).Unix()
+		used := 0
+		for _, ts := range timestamps {
+			if ts > cutoff {
+				used++
+			}
+		}
+		remaining := w.Max - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		headroom = append(headroom, windowHeadroom{Window: w.Name, Remaining: remaining})
+	}
+	return headroom
+}
+
+// tightestWindow returns the window with the least remaining headroom.
+func tightestWindow(headroom []windowHeadroom) (windowHeadroom, bool) {
+	if len(headroom) == 0 {
+		return windowHeadroom{}, false
+	}
+	tightest := headroom[0]
+	for _, h := range headroom[1:] {
+		if h.Remaining < tightest.Remaining {
+			tightest = h
+		}
+	}
+	return tightest, true
+}
+
+func formatHeadroom(headroom []windowHeadroom, useColor bool) string {
+	tightest, ok := tightestWindow(headroom)
+	if !ok {
+		return "unlimited"
+	}
+	parts := make([]string, 0, len(headroom))
+	for _, h := range headroom {
+		part := fmt.Sprintf("%s=%d", h.Window, h.Remaining)
+		if h.Window == tightest.Window {
+			part = styleIf(useColor, part, ansiYellow, ansiBold)
+		}
+		parts = append(parts, part)
+	}
+	return fmt.Sprintf("%s (tightest: %s)", strings.Join(parts, ", "), tightest.Window)
+}
@@ -0,0 +1,126 @@
+package ralph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeHeadroom(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) int64 { return now.Add(-d).Unix() }
+
+	tests := []struct {
+		name       string
+		timestamps []int64
+		maxPerHour int
+		maxPerDay  int
+		want       []windowHeadroom
+		tightest   string
+	}{
+		{
+			name: "no limits",
+			want: []windowHeadroom{},
+		},
+		{
+			name:       "empty history",
+			maxPerHour: 5,
+			maxPerDay:  20,
+			want:       []windowHeadroom{{Window: "hour", Remaining: 5}, {Window: "day", Remaining: 20}},
+			tightest:   "hour",
+		},
+		{
+			name:       "day tighter than hour",
+			timestamps: []int64{ago(10 * time.Minute), ago(2 * time.Hour), ago(3 * time.Hour), ago(4 * time.Hour)},
+			maxPerHour: 5,
+			maxPerDay:  6,
+			want:       []windowHeadroom{{Window: "hour", Remaining: 4}, {Window: "day", Remaining: 2}},
+			tightest:   "day",
+		},
+		{
+			name:       "saturated hour clamps at zero",
+			timestamps: []int64{ago(time.Minute), ago(2 * time.Minute), ago(3 * time.Minute)},
+			maxPerHour: 2,
+			want:       []windowHeadroom{{Window: "hour", Remaining: 0}},
+			tightest:   "hour",
+		},
+		{
+			name:       "old timestamps ignored",
+			timestamps: []int64{ago(25 * time.Hour)},
+			maxPerDay:  1,
+			want:       []windowHeadroom{{Window: "day", Remaining: 1}},
+			tightest:   "day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeHeadroom(tt.timestamps, activeRateWindows(0, tt.maxPerHour, tt.maxPerDay), now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %+v want %+v", got, tt.want)
+				}
+			}
+			tightest, ok := tightestWindow(got)
+			if ok != (tt.tightest != "") || tightest.Window != tt.tightest {
+				t.Fatalf("tightest: got %+v (ok=%v) want %q", tightest, ok, tt.tightest)
+			}
+		})
+	}
+}
+
+func TestRenderStatusShowsHeadroom(t *testing.T) {
+	now := time.Now()
+	cfg := DefaultConfig()
+	cfg.MaxPerHour = 3
+	cfg.MaxPerDay = 10
+	state := State{
+		TotalIterations: 7,
+		Timestamps:      []int64{now.Add(-time.Minute).Unix()},
+		LastRun:         now,
+	}
+
+	out := renderStatus(cfg, state, now, false)
+	for _, want := range []string{"Total iterations: 7", "Rate: 1/hour, 1/day", "Headroom: hour=2, day=9 (tightest: hour)"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in status output:\n%s", want, out)
+		}
+	}
+}
+
+func TestSaveStateSurvivesInterruptedWrite(t *testing.T) {
+	withTempCWD(t)
+	if err := os.MkdirAll(ralphDir, 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", ralphDir, err)
+	}
+
+	saved := State{TotalIterations: 7, Timestamps: []int64{100, 200}}
+	saveState(saved)
+	leftovers, err := filepath.Glob(filepath.Join(ralphDir, ".state.json.tmp-*"))
+	if err != nil || len(leftovers) != 0 {
+		t.Fatalf("expected no temporary files after saving, got %v, %v", leftovers, err)
+	}
+
+	// A save killed at any point before the rename leaves only a partial
+	// temporary file behind; state.json must still hold the last full save.
+	next, err := json.MarshalIndent(State{TotalIterations: 8, Timestamps: []int64{100, 200, 300}}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 1, len(next) / 2, len(next) - 1} {
+		partial := filepath.Join(ralphDir, ".state.json.tmp-crashed")
+		if err := os.WriteFile(partial, next[:n], 0o644); err != nil {
+			t.Fatalf("write partial: %v", err)
+		}
+		got := loadState()
+		if got.TotalIterations != saved.TotalIterations || len(got.Timestamps) != len(saved.Timestamps) {
+			t.Fatalf("after a %d-byte partial write: got %+v want %+v", n, got, saved)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package ralph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxRunOutcomes caps how many run records are kept in state.
+const maxRunOutcomes = 200
+
+// RunOutcome is the persisted record of one finished run.
+type RunOutcome struct {
+	RunID           string    `json:"run_id"`
+	Started         time.Time `json:"started"`
+	Status          string    `json:"status"`
+	Iterations      int       `json:"iterations"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// appendRunOutcome adds outcome to runs, dropping the oldest records beyond
+// maxRunOutcomes.
+func appendRunOutcome(runs []RunOutcome, outcome RunOutcome) []RunOutcome {
+	runs = append(runs, outcome)
+	if len(runs) > maxRunOutcomes {
+		runs = append([]RunOutcome(nil), runs[len(runs)-maxRunOutcomes:]...)
+	}
+	return runs
+}
+
+// RunStats aggregates the run outcomes recorded in state, plus activity
+// figures from the iteration history.
+type RunStats struct {
+	TotalIterations   int            `json:"total_iterations"`
+	Runs              int            `json:"runs"`
+	Outcomes          map[string]int `json:"outcomes"`
+	AverageDuration   string         `json:"average_duration"`
+	AverageIterations float64        `json:"average_iterations"`
+	// Source is "history" when the activity figures below come from
+	// .ralph/history.jsonl, or "state" when only the past day's timestamps
+	// kept for rate limiting were available.
+	Source        string     `json:"source"`
+	LastRun       *time.Time `json:"last_run,omitempty"`
+	AveragePerDay float64    `json:"average_per_day"`
+	// BusiestHour is the local hour of day, as "15:00", in which the most
+	// iterations started.
+	BusiestHour           string `json:"busiest_hour,omitempty"`
+	BusiestHourIterations int    `json:"busiest_hour_iterations,omitempty"`
+}
+
+// Stats sources.
+const (
+	statsFromHistory = "history"
+	statsFromState   = "state"
+)
+
+func computeStats(state State, history []IterationRecord) RunStats {
+	stats := RunStats{
+		TotalIterations: state.TotalIterations,
+		Runs:            len(state.Runs),
+		Outcomes:        map[string]int{},
+	}
+	computeActivity(&stats, state, history)
+	if len(state.Runs) == 0 {
+		stats.AverageDuration = time.Duration(0).String()
+		return stats
+	}
+
+	var seconds float64
+	var iterations int
+	for _, run := range state.Runs {
+		stats.Outcomes[run.Status]++
+		seconds += run.DurationSeconds
+		iterations += run.Iterations
+	}
+	n := float64(len(state.Runs))
+	stats.AverageDuration = time.Duration(seconds / n * float64(time.Second)).Truncate(time.Millisecond).String()
+	stats.AverageIterations = float64(iterations) / n
+	return stats
+}
+
+// computeActivity fills in the last run, daily average, and busiest hour.
+// The history log covers every iteration; without it only the timestamps of
+// the past 24 hours are known, so the average is simply their count.
+func computeActivity(stats *RunStats, state State, history []IterationRecord) {
+	var starts []time.Time
+	if len(history) > 0 {
+		stats.Source = statsFromHistory
+		for _, record := range history {
+			starts = append(starts, record.Timestamp.Local())
+		}
+	} else {
+		stats.Source = statsFromState
+		for _, ts := range state.Timestamps {
+			starts = append(starts, time.Unix(ts, 0).Local())
+		}
+	}
+
+	last := state.LastRun
+	for _, run := range state.Runs {
+		if end := run.Started.Add(time.Duration(run.DurationSeconds * float64(time.Second))); end.After(last) {
+			last = end
+		}
+	}
+	for _, start := range starts {
+		if start.After(last) {
+			last = start
+		}
+	}
+	if !last.IsZero() {
+		last = last.Local()
+		stats.LastRun = &last
+	}
+
+	if len(starts) == 0 {
+		return
+	}
+	if stats.Source == statsFromState {
+		stats.AveragePerDay = float64(len(starts))
+	} else {
+		first, latest := starts[0], starts[0]
+		for _, start := range starts {
+			if start.Before(first) {
+				first = start
+			}
+			if start.After(latest) {
+				latest = start
+			}
+		}
+		stats.AveragePerDay = float64(len(starts)) / float64(calendarDays(first, latest))
+	}
+
+	var perHour [24]int
+	for _, start := range starts {
+		perHour[start.Hour()]++
+	}
+	busiest := 0
+	for hour, count := range perHour {
+		if count > perHour[busiest] {
+			busiest = hour
+		}
+	}
+	stats.BusiestHour = fmt.Sprintf("%02d:00", busiest)
+	stats.BusiestHourIterations = perHour[busiest]
+}
+
+// calendarDays counts the days from first to last inclusive, by date.
+func calendarDays(first, last time.Time) int {
+	y, m, d := first.Date()
+	firstDay := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = last.Date()
+	lastDay := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(lastDay.Sub(firstDay).Hours()/24) + 1
+}
+
+func renderStats(stats RunStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Total iterations: %d\n", stats.TotalIterations)
+	fmt.Fprintf(&b, "Runs recorded: %d\n", stats.Runs)
+
+	statuses := make([]string, 0, len(stats.Outcomes))
+	for status := range stats.Outcomes {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	outcomes := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		outcomes = append(outcomes, fmt.Sprintf("%s=%d", status, stats.Outcomes[status]))
+	}
+	if len(outcomes) == 0 {
+		outcomes = append(outcomes, "none")
+	}
+	fmt.Fprintf(&b, "Outcomes: %s\n", strings.Join(outcomes, ", "))
+	fmt.Fprintf(&b, "Average duration: %s\n", stats.AverageDuration)
+	fmt.Fprintf(&b, "Average iterations per run: %.1f\n", stats.AverageIterations)
+
+	lastRun := "never"
+	if stats.LastRun != nil {
+		lastRun = stats.LastRun.Format("2006-01-02 15:04:05")
+	}
+	fmt.Fprintf(&b, "Last run: %s\n", lastRun)
+	perDay := fmt.Sprintf("%.1f", stats.AveragePerDay)
+	if stats.Source == statsFromState {
+		perDay += " (past 24 hours only)"
+	}
+	fmt.Fprintf(&b, "Average iterations per day: %s\n", perDay)
+	busiest := "none"
+	if stats.BusiestHour != "" {
+		busiest = fmt.Sprintf("%s (%d iterations)", stats.BusiestHour, stats.BusiestHourIterations)
+	}
+	fmt.Fprintf(&b, "Busiest hour: %s", busiest)
+	return b.String()
+}
+
+// Stats renders all-time statistics from saved state, as text or JSON.
+func Stats(asJSON bool) (string, error) {
+	history, err := loadIterationHistory()
+	if err != nil {
+		return "", err
+	}
+	stats := computeStats(loadState(), history)
+	if !asJSON {
+		return renderStats(stats), nil
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshalling stats: %w", err)
+	}
+	return string(data), nil
+}
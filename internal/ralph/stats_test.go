@@ -0,0 +1,176 @@
+package ralph
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunsAccumulateOutcomes(t *testing.T) {
+	withTempCWD(t)
+	clock := useFakeClock(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	slowComplete := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			clock.current = clock.current.Add(10 * time.Second)
+			return "<ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+	slowIncomplete := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			clock.current = clock.current.Add(10 * time.Second)
+			return "", nil
+		},
+	}
+
+	runs := []struct {
+		runner OpencodeRunner
+		max    int
+	}{
+		{runner: slowComplete, max: 3},
+		{runner: slowIncomplete, max: 2},
+		{runner: slowComplete, max: 3},
+	}
+	for _, run := range runs {
+		if _, err := runIterationsWithRunner(cfg, RunOptions{MaxIterations: run.max, Quiet: true}, run.runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	}
+
+	stats := computeStats(loadState(), nil)
+	if stats.Runs != 3 || stats.TotalIterations != 4 {
+		t.Fatalf("runs/iterations: got %d/%d want 3/4", stats.Runs, stats.TotalIterations)
+	}
+	if stats.Outcomes["complete"] != 2 || stats.Outcomes["max_iterations"] != 1 {
+		t.Fatalf("outcomes: got %v", stats.Outcomes)
+	}
+	// 10s, 20s, and 10s of opencode time.
+	if stats.AverageDuration != "13.333s" {
+		t.Fatalf("average duration: got %s", stats.AverageDuration)
+	}
+
+	text := renderStats(stats)
+	for _, want := range []string{"Runs recorded: 3", "Outcomes: complete=2, max_iterations=1", "Average iterations per run: 1.3"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in stats:\n%s", want, text)
+		}
+	}
+
+	out, err := Stats(true)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	var decoded RunStats
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil || decoded.Runs != 3 {
+		t.Fatalf("json stats: %v %+v", err, decoded)
+	}
+}
+
+func TestAppendRunOutcomeCaps(t *testing.T) {
+	var runs []RunOutcome
+	for i := 0; i < maxRunOutcomes+5; i++ {
+		runs = appendRunOutcome(runs, RunOutcome{Iterations: i})
+	}
+	if len(runs) != maxRunOutcomes || runs[0].Iterations != 5 {
+		t.Fatalf("expected oldest records dropped, got len %d first %d", len(runs), runs[0].Iterations)
+	}
+}
+
+func TestComputeActivity(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 30, 0, 0, time.Local) }
+
+	tests := []struct {
+		name        string
+		state       State
+		history     []IterationRecord
+		wantSource  string
+		wantLast    time.Time
+		wantPerDay  float64
+		wantBusiest string
+		wantCount   int
+	}{
+		{
+			name:       "nothing recorded",
+			wantSource: statsFromState,
+		},
+		{
+			name: "history spans several days",
+			state: State{
+				// Pruned to the past day, so ignored in favor of the history.
+				Timestamps: []int64{at(5, 9).Unix()},
+				Runs:       []RunOutcome{{Started: at(5, 9), DurationSeconds: 60}},
+			},
+			history: []IterationRecord{
+				{Timestamp: at(1, 14)}, {Timestamp: at(1, 14)}, {Timestamp: at(2, 9)},
+				{Timestamp: at(4, 14)}, {Timestamp: at(5, 9)}, {Timestamp: at(5, 9).Add(time.Minute)},
+			},
+			wantSource:  statsFromHistory,
+			wantLast:    at(5, 9).Add(time.Minute),
+			wantPerDay:  1.2,
+			wantBusiest: "09:00",
+			wantCount:   3,
+		},
+		{
+			name: "finished run is later than the last iteration",
+			state: State{
+				Runs: []RunOutcome{{Started: at(1, 10), DurationSeconds: 7200}},
+			},
+			history:     []IterationRecord{{Timestamp: at(1, 10)}},
+			wantSource:  statsFromHistory,
+			wantLast:    at(1, 12),
+			wantPerDay:  1,
+			wantBusiest: "10:00",
+			wantCount:   1,
+		},
+		{
+			name: "state only",
+			state: State{
+				Timestamps: []int64{at(1, 22).Unix(), at(1, 23).Unix(), at(1, 23).Add(10 * time.Minute).Unix()},
+				LastRun:    at(1, 23).Add(10 * time.Minute),
+			},
+			wantSource:  statsFromState,
+			wantLast:    at(1, 23).Add(10 * time.Minute),
+			wantPerDay:  3,
+			wantBusiest: "23:00",
+			wantCount:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := computeStats(tt.state, tt.history)
+			if stats.Source != tt.wantSource {
+				t.Fatalf("source: got %q want %q", stats.Source, tt.wantSource)
+			}
+			if tt.wantLast.IsZero() != (stats.LastRun == nil) || (stats.LastRun != nil && !stats.LastRun.Equal(tt.wantLast)) {
+				t.Fatalf("last run: got %v want %v", stats.LastRun, tt.wantLast)
+			}
+			if stats.AveragePerDay != tt.wantPerDay {
+				t.Fatalf("average per day: got %v want %v", stats.AveragePerDay, tt.wantPerDay)
+			}
+			if stats.BusiestHour != tt.wantBusiest || stats.BusiestHourIterations != tt.wantCount {
+				t.Fatalf("busiest hour: got %q (%d) want %q (%d)", stats.BusiestHour, stats.BusiestHourIterations, tt.wantBusiest, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRenderStatsActivity(t *testing.T) {
+	last := time.Date(2026, 3, 5, 9, 31, 0, 0, time.Local)
+	text := renderStats(RunStats{Source: statsFromHistory, LastRun: &last, AveragePerDay: 1.2, BusiestHour: "09:00", BusiestHourIterations: 3})
+	for _, want := range []string{"Last run: 2026-03-05 09:31:00\n", "Average iterations per day: 1.2\n", "Busiest hour: 09:00 (3 iterations)"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in stats:\n%s", want, text)
+		}
+	}
+
+	text = renderStats(RunStats{Source: statsFromState})
+	for _, want := range []string{"Last run: never\n", "Average iterations per day: 0.0 (past 24 hours only)\n", "Busiest hour: none"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in stats:\n%s", want, text)
+		}
+	}
+}
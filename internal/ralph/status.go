@@ -0,0 +1,38 @@
+package ralph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status renders iteration history and rate-limit headroom from saved state.
+func Status() (string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	return renderStatus(cfg, loadState(), now(), shouldUseColor(false)), nil
+}
+
+func renderStatus(cfg Config, state State, now time.Time, useColor bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Total iterations: %d\n", state.TotalIterations)
+	if state.LastRun.IsZero() {
+		b.WriteString("Last run: never\n")
+	} else {
+		fmt.Fprintf(&b, "Last run: %s\n", state.LastRun.Format("2006-01-02 15:04:05"))
+	}
+
+	hourCount, dayCount := countRecentIterations(state.Timestamps, now)
+	if cfg.MaxPerMinute > 0 {
+		minuteCount := countRecentMinute(state.Timestamps, now)
+		fmt.Fprintf(&b, "Rate: %d/minute, %d/hour, %d/day\n", minuteCount, hourCount, dayCount)
+	} else {
+		fmt.Fprintf(&b, "Rate: %d/hour, %d/day\n", hourCount, dayCount)
+	}
+
+	headroom := computeHeadroom(state.Timestamps, activeRateWindows(cfg.MaxPerMinute, cfg.MaxPerHour, cfg.MaxPerDay), now)
+	fmt.Fprintf(&b, "Headroom: %s", formatHeadroom(headroom, useColor))
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// --merge-streams modes for opencode's stdout and stderr while streaming.
+const (
+	// mergeStreamsSeparate copies each stream through its own writer, so
+	// lines from the two can reach the terminal out of order.
+	mergeStreamsSeparate = "separate"
+	// mergeStreamsOrdered sends both streams through one synchronized writer
+	// to stdout, keeping them in roughly the order opencode wrote them.
+	mergeStreamsOrdered = "ordered"
+)
+
+func validateMergeStreams(mode string) error {
+	switch mode {
+	case "", mergeStreamsSeparate, mergeStreamsOrdered:
+		return nil
+	default:
+		return fmt.Errorf("invalid --merge-streams value: %s (expected ordered or separate)", mode)
+	}
+}
+
+// syncWriter serializes writes from several goroutines so each Write reaches
+// w whole and in the order the writes were made.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// opencodeLinePrefix marks streamed opencode lines under --verbose so they
+// stand apart from ralph's own output.
+const opencodeLinePrefix = "[opencode]"
+
+// streamPrefix returns the prefix for streamed opencode lines: a dim
+// [opencode] tag in verbose mode, nothing with --quiet.
+func streamPrefix(verbose, quiet, useColor bool) string {
+	if !verbose || quiet {
+		return ""
+	}
+	return styleIf(useColor, opencodeLinePrefix, ansiGray) + " "
+}
+
+// linePrefixWriter writes prefix before every line. Lines are buffered until
+// their newline arrives, so a line (and any escape sequence in it) reaches w
+// in one piece; Flush writes a final unterminated line. An empty prefix
+// passes writes straight through.
+type linePrefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	pending []byte
+}
+
+func newLinePrefixWriter(w io.Writer, prefix string) *linePrefixWriter {
+	return &linePrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (l *linePrefixWriter) Write(p []byte) (int, error) {
+	if len(l.prefix) == 0 {
+		return l.w.Write(p)
+	}
+	l.pending = append(l.pending, p...)
+	end := bytes.LastIndexByte(l.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(l.pending[:end+1], []byte("\n")) {
+		if len(line) > 0 {
+			out.Write(l.prefix)
+			out.Write(line)
+		}
+	}
+	if _, err := l.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	l.pending = append(l.pending[:0], l.pending[end+1:]...)
+	return len(p), nil
+}
+
+// Flush writes a trailing line that never got its newline.
+func (l *linePrefixWriter) Flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	_, err := l.w.Write(append(append([]byte(nil), l.prefix...), l.pending...))
+	l.pending = l.pending[:0]
+	return err
+}
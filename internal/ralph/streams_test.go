@@ -0,0 +1,140 @@
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// overlapDetector records writes and notices if two ever run at once.
+type overlapDetector struct {
+	active  atomic.Int32
+	overlap atomic.Bool
+	buf     bytes.Buffer
+}
+
+func (d *overlapDetector) Write(p []byte) (int, error) {
+	if d.active.Add(1) > 1 {
+		d.overlap.Store(true)
+	}
+	defer d.active.Add(-1)
+	return d.buf.Write(p)
+}
+
+func TestSyncWriterPreservesOrderFromConcurrentSources(t *testing.T) {
+	const sources, lines = 4, 200
+
+	inner := &overlapDetector{}
+	w := &syncWriter{w: inner}
+
+	var wg sync.WaitGroup
+	for src := 0; src < sources; src++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				fmt.Fprintf(w, "src%d line%03d\n", src, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if inner.overlap.Load() {
+		t.Fatalf("writes reached the inner writer concurrently")
+	}
+
+	next := make([]int, sources)
+	got := strings.Split(strings.TrimSuffix(inner.buf.String(), "\n"), "\n")
+	if len(got) != sources*lines {
+		t.Fatalf("lines: got %d want %d", len(got), sources*lines)
+	}
+	for _, line := range got {
+		var src, n int
+		if _, err := fmt.Sscanf(line, "src%d line%d", &src, &n); err != nil {
+			t.Fatalf("line %q was split or interleaved: %v", line, err)
+		}
+		if n != next[src] {
+			t.Fatalf("source %d: got line %d, want %d", src, n, next[src])
+		}
+		next[src]++
+	}
+}
+
+func TestValidateMergeStreams(t *testing.T) {
+	for _, mode := range []string{"", "ordered", "separate"} {
+		if err := validateMergeStreams(mode); err != nil {
+			t.Fatalf("%q: unexpected error %v", mode, err)
+		}
+	}
+	if err := validateMergeStreams("interleaved"); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
+
+func TestLinePrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		writes []string
+		want   string
+	}{
+		{name: "whole lines", prefix: "> ", writes: []string{"one\ntwo\n"}, want: "> one\n> two\n"},
+		{name: "split line", prefix: "> ", writes: []string{"on", "e\ntw", "o\n"}, want: "> one\n> two\n"},
+		{name: "split escape", prefix: "> ", writes: []string{"\x1b[3", "1mred\x1b[0m\n"}, want: "> \x1b[31mred\x1b[0m\n"},
+		{name: "blank line", prefix: "> ", writes: []string{"a\n\nb\n"}, want: "> a\n> \n> b\n"},
+		{name: "unterminated last line", prefix: "> ", writes: []string{"a\nb"}, want: "> a\n> b"},
+		{name: "no prefix", prefix: "", writes: []string{"a", "b\n"}, want: "ab\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newLinePrefixWriter(&out, tt.prefix)
+			for _, chunk := range tt.writes {
+				if _, err := w.Write([]byte(chunk)); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamPrefix(t *testing.T) {
+	if got := streamPrefix(true, false, false); got != "[opencode] " {
+		t.Fatalf("verbose without color: got %q", got)
+	}
+	if got := streamPrefix(true, false, true); got != ansiGray+"[opencode]"+ansiReset+" " {
+		t.Fatalf("verbose with color: got %q", got)
+	}
+	if got := streamPrefix(true, true, true); got != "" {
+		t.Fatalf("quiet: got %q", got)
+	}
+	if got := streamPrefix(false, false, true); got != "" {
+		t.Fatalf("not verbose: got %q", got)
+	}
+}
+
+func TestSanitizedPrefixedStreamKeepsPrefixColor(t *testing.T) {
+	var out bytes.Buffer
+	prefix := streamPrefix(true, false, true)
+	w := newSanitizingWriter(newLinePrefixWriter(&out, prefix))
+	for _, chunk := range []string{"\x1b[1mbold", " text\x1b[0m\nlast"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if want := prefix + "bold text\n" + prefix + "last"; out.String() != want {
+		t.Fatalf("got %q want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,87 @@
+package ralph
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runIDRand supplies the random suffix for run IDs; tests may replace it with a seeded source.
+var runIDRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// newRunID returns a sortable, unique-enough identifier: start time plus a random suffix.
+func newRunID(start time.Time, rng *rand.Rand) string {
+	return fmt.Sprintf("%s-%08x", start.UTC().Format("20060102T150405Z"), rng.Uint32())
+}
+
+// RunSummary describes the outcome of a single run; it is also one line of
+// the cumulative --summary-log ledger.
+type RunSummary struct {
+	RunID        string            `json:"run_id"`
+	Timestamp    string            `json:"timestamp"`
+	Status       string            `json:"status"`
+	Iterations   int               `json:"iterations"`
+	Duration     string            `json:"duration"`
+	Model        string            `json:"model,omitempty"`
+	Truncated    int               `json:"truncated,omitempty"`
+	FilesChanged []string          `json:"files_changed,omitempty"`
+	Activity     string            `json:"activity,omitempty"`
+	MissingNotes int               `json:"missing_notes,omitempty"`
+	Meta         map[string]string `json:"meta,omitempty"`
+	Tokens       int               `json:"tokens,omitempty"`
+	// SlowIterations counts opencode calls over --warn-on-slow-iteration.
+	SlowIterations int `json:"slow_iterations,omitempty"`
+	TimedOut       int `json:"timed_out,omitempty"`
+	Retries        int `json:"retries,omitempty"`
+	Failures       int `json:"failures,omitempty"`
+	// Phase is the --phases specs file the record covers; each phase gets
+	// its own record.
+	Phase string `json:"phase,omitempty"`
+}
+
+// formatMeta renders run metadata as space-separated key=value pairs in key order.
+func formatMeta(meta map[string]string) string {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+meta[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// appendSummaryLog appends record as a single JSON line to path, creating it if needed.
+func appendSummaryLog(path string, record RunSummary) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling summary record: %w", err)
+	}
+	return appendLineLocked(path, string(data))
+}
+
+// appendLineLocked appends line to path while holding an exclusive flock so
+// concurrent runs sharing the file never interleave partial records.
+func appendLineLocked(path, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := lockFileExclusive(f); err != nil {
+		return fmt.Errorf("locking %s: %w", path, err)
+	}
+	defer unlockFile(f)
+
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
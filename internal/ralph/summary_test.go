@@ -0,0 +1,285 @@
+package ralph
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryLogAppendsOneRecordPerRun(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	opts := RunOptions{MaxIterations: 2, Quiet: true, Model: "test/model", SummaryLog: "runs.log"}
+	if _, err := runIterationsWithRunner(cfg, opts, completeRunner()); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	opts.MaxIterations = 1
+	opts.Model = ""
+	if _, err := runIterationsWithRunner(cfg, opts, &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) { return "still working", nil },
+	}); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+
+	f, err := os.Open("runs.log")
+	if err != nil {
+		t.Fatalf("open summary log: %v", err)
+	}
+	defer f.Close()
+
+	var records []RunSummary
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record RunSummary
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("parse line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan summary log: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("records: got %d want %d", len(records), 2)
+	}
+	if records[0].Status != "complete" || records[0].Iterations != 1 || records[0].Model != "test/model" {
+		t.Fatalf("first record: got %+v", records[0])
+	}
+	if records[1].Status != "max_iterations" || records[1].Iterations != 1 || records[1].Model != "" {
+		t.Fatalf("second record: got %+v", records[1])
+	}
+	for _, record := range records {
+		if record.Timestamp == "" || record.Duration == "" {
+			t.Fatalf("expected timestamp and duration, got %+v", record)
+		}
+	}
+}
+
+func TestSummaryLogSkippedForDryRun(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	opts := RunOptions{MaxIterations: 1, DryRun: true, SummaryLog: "runs.log"}
+	captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, completeRunner()); err != nil {
+			t.Fatalf("dry run: %v", err)
+		}
+	})
+
+	if _, err := os.Stat("runs.log"); !os.IsNotExist(err) {
+		t.Fatalf("expected no summary log for dry run, stat err: %v", err)
+	}
+}
+
+func TestNewRunIDIsSeedable(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	a := newRunID(start, rand.New(rand.NewSource(42)))
+	b := newRunID(start, rand.New(rand.NewSource(42)))
+	c := newRunID(start, rand.New(rand.NewSource(43)))
+
+	if a != b {
+		t.Fatalf("same seed: got %q and %q", a, b)
+	}
+	if a == c {
+		t.Fatalf("different seeds produced the same ID %q", a)
+	}
+	if !strings.HasPrefix(a, "20260102T030405Z-") {
+		t.Fatalf("expected timestamp prefix, got %q", a)
+	}
+}
+
+func TestRunIDAppearsAcrossArtifacts(t *testing.T) {
+	withTempCWD(t)
+
+	orig := runIDRand
+	runIDRand = rand.New(rand.NewSource(7))
+	t.Cleanup(func() { runIDRand = orig })
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<ralph_notes>did a thing</ralph_notes>", nil
+		},
+	}
+	opts := RunOptions{MaxIterations: 2, SummaryLog: "runs.log", OutputDir: "out"}
+	stdout := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	var record RunSummary
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse summary log: %v", err)
+	}
+	if record.RunID == "" {
+		t.Fatalf("expected run ID in summary record")
+	}
+
+	notes, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if got := strings.Count(string(notes), "# Run "+record.RunID); got != 1 {
+		t.Fatalf("notes run header count: got %d want %d\n%s", got, 1, notes)
+	}
+	if !strings.Contains(stdout, "Run ID: "+record.RunID) {
+		t.Fatalf("expected run ID in printed summary, got %q", stdout)
+	}
+	for iteration := 1; iteration <= 2; iteration++ {
+		if _, err := os.Stat(iterationArtifactPath("out", record.RunID, iteration, "log")); err != nil {
+			t.Fatalf("expected iteration %d log under the run ID directory: %v", iteration, err)
+		}
+	}
+	if entries, err := os.ReadDir("out"); err != nil || len(entries) != 1 || entries[0].Name() != record.RunID {
+		t.Fatalf("expected only out/%s, got %v (%v)", record.RunID, entries, err)
+	}
+}
+
+func TestMetaAppearsInSummaryAndNotes(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			return "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", nil
+		},
+	}
+	meta := map[string]string{"ticket": "ABC-1", "ci_job": "42"}
+	opts := RunOptions{MaxIterations: 1, SummaryLog: "runs.log", Meta: meta}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "Meta: ci_job=42 ticket=ABC-1\n") {
+		t.Fatalf("expected meta in printed summary, got %q", out)
+	}
+
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	var record RunSummary
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(record.Meta) != 2 || record.Meta["ticket"] != "ABC-1" || record.Meta["ci_job"] != "42" {
+		t.Fatalf("meta in summary log: got %v", record.Meta)
+	}
+
+	notes, err := os.ReadFile(notesFile)
+	if err != nil {
+		t.Fatalf("read notes: %v", err)
+	}
+	if !strings.Contains(string(notes), ")\nMeta: ci_job=42 ticket=ABC-1\n") {
+		t.Fatalf("expected meta in notes header, got %q", notes)
+	}
+}
+
+func TestQuietSummaryPrintsOnlyStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"complete", "<ralph_notes>n</ralph_notes><ralph_status>COMPLETE</ralph_status>", "complete\n"},
+		{"max iterations", "<ralph_notes>n</ralph_notes>", "max_iterations\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempCWD(t)
+
+			cfg := DefaultConfig()
+			writeContextFiles(t, cfg)
+
+			runner := &fakeRunner{
+				runFunc: func(args OpencodeRunArgs) (string, error) {
+					if args.Quiet || args.Verbose {
+						t.Errorf("--quiet-summary must not stream opencode output")
+					}
+					return tt.output, nil
+				},
+			}
+			opts := RunOptions{MaxIterations: 2, MaxPerHour: 10, QuietSummary: true}
+			out := captureOutput(t, &os.Stdout, func() {
+				if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+					t.Fatalf("runIterationsWithRunner: %v", err)
+				}
+			})
+			if out != tt.want {
+				t.Fatalf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxConsecutiveFailures(t *testing.T) {
+	withTempCWD(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	// Fails, recovers once, then keeps failing.
+	fails := []bool{true, false, true, true, true, true}
+	var calls int
+	runner := &fakeRunner{
+		runFunc: func(OpencodeRunArgs) (string, error) {
+			fail := fails[calls]
+			calls++
+			if fail {
+				return "", errors.New("provider unavailable")
+			}
+			return "<ralph_notes>n</ralph_notes>", nil
+		},
+	}
+
+	opts := RunOptions{MaxIterations: 6, MaxConsecutiveFailures: 3, SummaryLog: "runs.log"}
+	out := captureOutput(t, &os.Stdout, func() {
+		if _, err := runIterationsWithRunner(cfg, opts, runner); err != nil {
+			t.Fatalf("runIterationsWithRunner: %v", err)
+		}
+	})
+
+	if calls != 5 {
+		t.Fatalf("expected the run to stop after the third failure in a row (5 calls), got %d", calls)
+	}
+	if !strings.Contains(out, "Failed iterations: 4\n") || !strings.Contains(out, "Status: FAILED") {
+		t.Fatalf("expected failure count and status in summary, got:\n%s", out)
+	}
+	data, err := os.ReadFile("runs.log")
+	if err != nil {
+		t.Fatalf("read summary log: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":"failed"`) || !strings.Contains(string(data), `"failures":4`) {
+		t.Fatalf("expected failed record, got %s", data)
+	}
+}
+
+func TestStatusStyleFailed(t *testing.T) {
+	label, codes := statusStyle("failed")
+	if label != "FAILED" || len(codes) != 2 || codes[0] != ansiRed || codes[1] != ansiBold {
+		t.Fatalf("statusStyle(failed) = %q %q", label, codes)
+	}
+}
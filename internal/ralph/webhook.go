@@ -0,0 +1,79 @@
+package ralph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// httpPoster sends webhook requests; tests substitute a local server's client.
+type httpPoster interface {
+	Post(url, contentType string, body io.Reader) (*http.Response, error)
+}
+
+var webhookClient httpPoster = &http.Client{Timeout: 10 * time.Second}
+
+// webhookRetryDelay is the pause before the single webhook retry.
+const webhookRetryDelay = 2 * time.Second
+
+// WebhookPayload is the JSON body POSTed to --webhook when a run ends with a
+// terminal status.
+type WebhookPayload struct {
+	RunID           string  `json:"run_id,omitempty"`
+	Status          string  `json:"status"`
+	Iterations      int     `json:"iterations"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Tokens is the run's token usage, when opencode reports it.
+	Tokens int               `json:"tokens,omitempty"`
+	Model  string            `json:"model,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+func validateWebhookURL(url string) error {
+	if url != "" && !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("invalid webhook URL %q: only http and https URLs are supported", url)
+	}
+	return nil
+}
+
+// postWebhook POSTs payload to url as JSON, retrying once on a transport
+// error or non-2xx response.
+func postWebhook(client httpPoster, url string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling webhook payload: %w", err)
+	}
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			sleep(webhookRetryDelay)
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("posting webhook to %s: %w", url, lastErr)
+}
+
+// notifyWebhook applies --webhook once a run ends with a terminal status;
+// failures only warn, as the run itself already finished.
+func notifyWebhook(opts RunOptions, payload WebhookPayload) {
+	if opts.Webhook == "" || opts.DryRun || !terminalStatus(payload.Status) {
+		return
+	}
+	if err := postWebhook(webhookClient, opts.Webhook, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
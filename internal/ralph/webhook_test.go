@@ -0,0 +1,98 @@
+package ralph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// webhookServer records the payloads it receives, answering with statuses in
+// turn (200 once they run out).
+func webhookServer(t *testing.T, statuses ...int) (*httptest.Server, *[]WebhookPayload) {
+	t.Helper()
+	var mu sync.Mutex
+	var payloads []WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type: got %q", got)
+		}
+		payloads = append(payloads, payload)
+		status := http.StatusOK
+		if len(payloads) <= len(statuses) {
+			status = statuses[len(payloads)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &payloads
+}
+
+func TestWebhookOnCompletion(t *testing.T) {
+	withTempCWD(t)
+	server, payloads := webhookServer(t)
+
+	cfg := DefaultConfig()
+	writeContextFiles(t, cfg)
+
+	opts := RunOptions{MaxIterations: 3, Quiet: true, Model: "test/model", Webhook: server.URL}
+	if _, err := runIterationsWithRunner(cfg, opts, completeRunner()); err != nil {
+		t.Fatalf("runIterationsWithRunner: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Fatalf("expected one webhook, got %d", len(*payloads))
+	}
+	got := (*payloads)[0]
+	if got.Status != "complete" || got.Iterations != 1 || got.Model != "test/model" || got.RunID == "" {
+		t.Fatalf("payload: got %+v", got)
+	}
+}
+
+func TestWebhookRetriesOnceThenWarns(t *testing.T) {
+	useFakeClock(t)
+
+	server, payloads := webhookServer(t, http.StatusBadGateway)
+	if err := postWebhook(http.DefaultClient, server.URL, WebhookPayload{Status: "complete"}); err != nil {
+		t.Fatalf("expected retry to succeed: %v", err)
+	}
+	if len(*payloads) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(*payloads))
+	}
+
+	failing, _ := webhookServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	stderr := captureOutput(t, &os.Stderr, func() {
+		notifyWebhook(RunOptions{Webhook: failing.URL}, WebhookPayload{Status: "failed"})
+	})
+	if !strings.Contains(stderr, "Warning: posting webhook") || !strings.Contains(stderr, "500") {
+		t.Fatalf("expected warning after the retry fails, got %q", stderr)
+	}
+}
+
+func TestWebhookSkippedForNonTerminalStatus(t *testing.T) {
+	server, payloads := webhookServer(t)
+	notifyWebhook(RunOptions{Webhook: server.URL}, WebhookPayload{Status: "rate_limited"})
+	notifyWebhook(RunOptions{Webhook: server.URL, DryRun: true}, WebhookPayload{Status: "complete"})
+	if len(*payloads) != 0 {
+		t.Fatalf("expected no webhook, got %+v", *payloads)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	withTempCWD(t)
+
+	if err := validateWebhookURL("ftp://example.com"); err == nil {
+		t.Fatalf("expected error for non-http URL")
+	}
+	if err := ConfigSet("webhook_url", "example.com/hook"); err == nil {
+		t.Fatalf("expected ConfigSet to reject a URL without a scheme")
+	}
+}